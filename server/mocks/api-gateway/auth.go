@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Introspector asks the auth-service whether a token is still active, so
+// that tokens revoked by logout are refused although their signature is
+// valid. A revoked token never becomes active again, so inactive answers
+// are remembered until the token expires; active tokens are asked about on
+// every request, which keeps logout effective at once.
+type Introspector struct {
+	URL    string
+	Client *http.Client
+
+	mu       sync.Mutex
+	inactive map[string]time.Time
+}
+
+// Active reports whether the auth-service considers token active. expires
+// is the expiry of the token, after which it is forgotten.
+func (i *Introspector) Active(token string, expires, now time.Time) (bool, error) {
+	i.mu.Lock()
+	for t, exp := range i.inactive {
+		if !now.Before(exp) {
+			delete(i.inactive, t)
+		}
+	}
+	_, revoked := i.inactive[token]
+	i.mu.Unlock()
+	if revoked {
+		return false, nil
+	}
+
+	client := i.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	body, err := json.Marshal(map[string]string{"token": token})
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.Post(i.URL+"/api/v1/auth/introspect", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("introspection returned %s", resp.Status)
+	}
+	var result struct {
+		Active bool `json:"active"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("introspection: %w", err)
+	}
+
+	if !result.Active {
+		i.mu.Lock()
+		if i.inactive == nil {
+			i.inactive = map[string]time.Time{}
+		}
+		i.inactive[token] = expires
+		i.mu.Unlock()
+	}
+	return result.Active, nil
+}
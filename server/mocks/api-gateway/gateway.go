@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// Upstream is a downstream mock serving /api/v1/<Prefix>.
+type Upstream struct {
+	Prefix string
+	URL    string
+	// Public upstreams are reachable without an access token.
+	Public bool
+}
+
+// Gateway routes /api/v1 requests the way Routing.md describes: it resolves
+// the tenant from the host and path prefix, checks the access token, injects
+// the tenant and user headers and proxies to the upstream mock. Tokens are
+// verified locally, then, with an introspector, checked for revocation.
+type Gateway struct {
+	upstreams map[string]*Upstream
+	proxies   map[string]*httputil.ReverseProxy
+	tenants   *TenantResolver
+	verifier  *mockkit.Signer
+	// introspector, when set, refuses tokens the auth-service has revoked.
+	introspector *Introspector
+}
+
+// Headers the gateway owns. Values sent by clients are dropped so they
+// cannot pick another tenant or user.
+var contextHeaders = []string{"X-Tenant-ID", "X-App-Code", "X-Is-Custom-Domain", "X-User-ID", "X-User-Role"}
+
+func NewGateway(upstreams []Upstream, tenants *TenantResolver, verifier *mockkit.Signer, introspector *Introspector) (*Gateway, error) {
+	g := &Gateway{
+		upstreams:    map[string]*Upstream{},
+		proxies:      map[string]*httputil.ReverseProxy{},
+		tenants:      tenants,
+		verifier:     verifier,
+		introspector: introspector,
+	}
+	for i := range upstreams {
+		u := &upstreams[i]
+		target, err := url.Parse(u.URL)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %s: %w", u.Prefix, err)
+		}
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.ModifyResponse = unifyError
+		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+			writeError(w, r, http.StatusBadGateway, "upstream_unavailable", u.Prefix+" service is unavailable")
+		}
+		g.upstreams[u.Prefix] = u
+		g.proxies[u.Prefix] = proxy
+	}
+	return g, nil
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Request-ID") == "" {
+		r.Header.Set("X-Request-ID", mockkit.RandomID(8))
+	}
+	w.Header().Set("X-Request-ID", r.Header.Get("X-Request-ID"))
+	for _, h := range contextHeaders {
+		r.Header.Del(h)
+	}
+
+	host := r.Header.Get("X-Forwarded-Host")
+	if host == "" {
+		host = r.Host
+	}
+	tenant, err := g.tenants.Resolve(host, r.URL.Path)
+	var lookupErr *LookupError
+	switch {
+	case errors.As(err, &lookupErr):
+		writeError(w, r, lookupErr.Status, lookupErr.Code, lookupErr.Message)
+		return
+	case err != nil:
+		log.Printf("tenant lookup for %s%s: %v", host, r.URL.Path, err)
+		writeError(w, r, http.StatusBadGateway, "upstream_unavailable", "tenant service is unavailable")
+		return
+	}
+
+	// A tenant on a path prefix, such as localhost/testco, calls the API
+	// below its prefix: /testco/api/v1/users.
+	path := r.URL.Path
+	if tenant.PathPrefix != "/" {
+		path = strings.TrimPrefix(path, tenant.PathPrefix)
+	}
+	rest, ok := strings.CutPrefix(path, "/api/v1/")
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "not_found", "no API at "+r.URL.Path)
+		return
+	}
+	name, _, _ := strings.Cut(rest, "/")
+	upstream := g.upstreams[name]
+	if upstream == nil {
+		writeError(w, r, http.StatusNotFound, "not_found", "no API at "+r.URL.Path)
+		return
+	}
+
+	r.Header.Set("X-Tenant-ID", tenant.TenantID)
+	r.Header.Set("X-App-Code", tenant.AppCode)
+	r.Header.Set("X-Is-Custom-Domain", strconv.FormatBool(tenant.IsCustomDomain))
+
+	if !upstream.Public {
+		claims, status, code, message := g.authenticate(r, tenant)
+		if claims == nil {
+			writeError(w, r, status, code, message)
+			return
+		}
+		r.Header.Set("X-User-ID", claims.UserID)
+		r.Header.Set("X-User-Role", claims.Role)
+	}
+
+	r.URL.Path = path
+	r.URL.RawPath = ""
+	g.proxies[name].ServeHTTP(w, r)
+}
+
+// authenticate checks the bearer token of r. The token must belong to the
+// tenant the request was routed to.
+func (g *Gateway) authenticate(r *http.Request, tenant *TenantInfo) (claims *mockkit.Claims, status int, code, message string) {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return nil, http.StatusUnauthorized, "unauthorized", "a bearer token is required"
+	}
+	token := strings.TrimSpace(h[7:])
+	now := time.Now()
+	claims, err := g.verifier.Verify(token, now)
+	if err != nil {
+		return nil, http.StatusUnauthorized, "invalid_token", err.Error()
+	}
+	if g.introspector != nil {
+		active, err := g.introspector.Active(token, time.Unix(claims.ExpiresAt, 0), now)
+		if err != nil {
+			log.Printf("token introspection: %v", err)
+			return nil, http.StatusBadGateway, "upstream_unavailable", "auth service is unavailable"
+		}
+		if !active {
+			return nil, http.StatusUnauthorized, "invalid_token", "token has been revoked"
+		}
+	}
+	if claims.TenantID != tenant.TenantID {
+		return nil, http.StatusForbidden, "tenant_mismatch", "the token belongs to another tenant"
+	}
+	return claims, 0, "", ""
+}
+
+// unifyError rewrites upstream error bodies into the gateway envelope. The
+// mocks already answer {"error": {"code", "message"}}; the flat
+// {"error": "code", "message": "..."} shape is for upstreams that are not
+// mocks, such as full services run through docker-compose.override.yml.
+// Any other body becomes the message.
+func unifyError(resp *http.Response) error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	var nested mockkit.ErrorResponse
+	var flat struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	e := mockkit.ErrorBody{Code: codeFor(resp.StatusCode), Message: strings.TrimSpace(string(body))}
+	switch {
+	case json.Unmarshal(body, &nested) == nil && nested.Error.Code != "":
+		e = nested.Error
+	case json.Unmarshal(body, &flat) == nil && flat.Error != "":
+		e.Code, e.Message = flat.Error, flat.Message
+	}
+	if e.Message == "" {
+		e.Message = strings.ToLower(http.StatusText(resp.StatusCode))
+	}
+	e.RequestID = resp.Request.Header.Get("X-Request-ID")
+
+	body, err = json.Marshal(mockkit.ErrorResponse{Error: e})
+	if err != nil {
+		return err
+	}
+	body = append(body, '\n')
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// codeFor is the error code of upstream errors that carry none.
+func codeFor(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusConflict:
+		return "conflict"
+	}
+	if status >= 500 {
+		return "upstream_error"
+	}
+	return "error"
+}
+
+// writeError answers with the error envelope, carrying the request ID.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	mockkit.WriteJSON(w, status, mockkit.ErrorResponse{Error: mockkit.ErrorBody{
+		Code:      code,
+		Message:   message,
+		RequestID: r.Header.Get("X-Request-ID"),
+	}})
+}
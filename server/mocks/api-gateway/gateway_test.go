@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+var testSigner = &mockkit.Signer{Secret: []byte("test-secret")}
+
+func token(t *testing.T, tenant string, ttl time.Duration) string {
+	t.Helper()
+	now := time.Now()
+	tok, err := testSigner.Sign(mockkit.Claims{ID: mockkit.RandomID(8), UserID: "user-1", TenantID: tenant, Role: "admin", IssuedAt: now.Unix(), ExpiresAt: now.Add(ttl).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+// fakeTenants answers route lookups: acme.local is tenant-1, localhost/testco
+// is tenant-2 and anything else has no mapping.
+func fakeTenants(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case q.Get("domain") == "acme.local:8080" || q.Get("domain") == "acme.local":
+			mockkit.WriteJSON(w, http.StatusOK, TenantInfo{TenantID: "tenant-1", AppCode: "PORTAL", PathPrefix: "/"})
+		case q.Get("domain") == "localhost" && strings.HasPrefix(q.Get("path"), "/testco/"):
+			mockkit.WriteJSON(w, http.StatusOK, TenantInfo{TenantID: "tenant-2", AppCode: "PORTAL", PathPrefix: "/testco"})
+		default:
+			mockkit.WriteError(w, http.StatusNotFound, "not_found", "Tenant mapping not found")
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// fakeUpstream echoes the context headers it receives and answers the
+// error shapes unifyError rewrites under /api/v1/users/<shape>.
+func fakeUpstream(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/users/nested":
+			mockkit.WriteError(w, http.StatusConflict, "conflict", "email already in use")
+		case "/api/v1/users/flat":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"bad_email","message":"email is invalid"}`))
+		case "/api/v1/users/plain":
+			http.Error(w, "database is down", http.StatusInternalServerError)
+		case "/api/v1/users/empty":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			mockkit.WriteJSON(w, http.StatusOK, map[string]string{
+				"path":      r.URL.Path,
+				"tenant_id": r.Header.Get("X-Tenant-ID"),
+				"user_id":   r.Header.Get("X-User-ID"),
+				"role":      r.Header.Get("X-User-Role"),
+			})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestGateway(t *testing.T, introspector *Introspector) http.Handler {
+	t.Helper()
+	upstream := fakeUpstream(t)
+	g, err := NewGateway(
+		[]Upstream{
+			{Prefix: "auth", URL: upstream.URL, Public: true},
+			{Prefix: "users", URL: upstream.URL},
+		},
+		&TenantResolver{URL: fakeTenants(t).URL},
+		testSigner,
+		introspector,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func serve(h http.Handler, host, target, token string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Host = host
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return v
+}
+
+func TestGatewayResolvesTenant(t *testing.T) {
+	g := newTestGateway(t, nil)
+	tok1, tok2 := token(t, "tenant-1", time.Hour), token(t, "tenant-2", time.Hour)
+
+	tests := []struct {
+		name       string
+		host       string
+		target     string
+		token      string
+		headers    []string
+		wantStatus int
+		wantTenant string
+		wantPath   string
+	}{
+		{"domain", "acme.local:8080", "/api/v1/users", tok1, nil, http.StatusOK, "tenant-1", "/api/v1/users"},
+		{"forwarded host", "gateway:8080", "/api/v1/users", tok1, []string{"X-Forwarded-Host", "acme.local"}, http.StatusOK, "tenant-1", "/api/v1/users"},
+		{"path prefix is stripped", "localhost", "/testco/api/v1/users/42", tok2, nil, http.StatusOK, "tenant-2", "/api/v1/users/42"},
+		{"client tenant header is replaced", "acme.local", "/api/v1/users", tok1, []string{"X-Tenant-ID", "tenant-2"}, http.StatusOK, "tenant-1", "/api/v1/users"},
+		{"public upstream needs no token", "acme.local", "/api/v1/auth/login", "", nil, http.StatusOK, "tenant-1", "/api/v1/auth/login"},
+		{"unknown host", "other.local", "/api/v1/users", tok1, nil, http.StatusNotFound, "", ""},
+		{"unknown upstream", "acme.local", "/api/v1/billing", tok1, nil, http.StatusNotFound, "", ""},
+		{"not the API", "acme.local", "/index.html", tok1, nil, http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(g, tt.host, tt.target, tt.token, tt.headers...)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if tt.wantTenant == "" {
+				if body := decode[mockkit.ErrorResponse](t, w); body.Error.Code != "not_found" || body.Error.RequestID == "" {
+					t.Errorf("error = %+v, want not_found with a request ID", body.Error)
+				}
+				return
+			}
+			got := decode[map[string]string](t, w)
+			if got["tenant_id"] != tt.wantTenant || got["path"] != tt.wantPath {
+				t.Errorf("upstream saw tenant %q at %q, want %q at %q", got["tenant_id"], got["path"], tt.wantTenant, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestGatewayRejectsTokens(t *testing.T) {
+	var introspections atomic.Int32
+	revoked := token(t, "tenant-1", time.Hour)
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		introspections.Add(1)
+		var in struct{ Token string }
+		json.NewDecoder(r.Body).Decode(&in)
+		mockkit.WriteJSON(w, http.StatusOK, map[string]bool{"active": in.Token != revoked})
+	}))
+	defer auth.Close()
+	g := newTestGateway(t, &Introspector{URL: auth.URL})
+
+	forged := strings.Split(token(t, "tenant-1", time.Hour), ".")
+	forged[2] = strings.Repeat("A", len(forged[2]))
+
+	tests := []struct {
+		name       string
+		auth       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing", "", http.StatusUnauthorized, "unauthorized"},
+		{"not bearer", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, "unauthorized"},
+		{"malformed", "Bearer not-a-token", http.StatusUnauthorized, "invalid_token"},
+		{"bad signature", "Bearer " + strings.Join(forged, "."), http.StatusUnauthorized, "invalid_token"},
+		{"expired", "Bearer " + token(t, "tenant-1", -time.Minute), http.StatusUnauthorized, "invalid_token"},
+		{"revoked", "Bearer " + revoked, http.StatusUnauthorized, "invalid_token"},
+		{"other tenant", "Bearer " + token(t, "tenant-2", time.Hour), http.StatusForbidden, "tenant_mismatch"},
+		{"valid", "bearer " + token(t, "tenant-1", time.Hour), http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(g, "acme.local", "/api/v1/users", "", "Authorization", tt.auth)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				if got := decode[map[string]string](t, w); got["user_id"] != "user-1" || got["role"] != "admin" {
+					t.Errorf("upstream saw user %q with role %q", got["user_id"], got["role"])
+				}
+				return
+			}
+			if got := decode[mockkit.ErrorResponse](t, w).Error.Code; got != tt.wantCode {
+				t.Errorf("error code = %q, want %q", got, tt.wantCode)
+			}
+		})
+	}
+
+	// A revoked token is remembered and not introspected again.
+	before := introspections.Load()
+	serve(g, "acme.local", "/api/v1/users", revoked)
+	if got := introspections.Load(); got != before {
+		t.Errorf("revoked token introspected again: %d calls, want %d", got, before)
+	}
+
+	auth.Close()
+	if w := serve(g, "acme.local", "/api/v1/users", token(t, "tenant-1", time.Hour)); w.Code != http.StatusBadGateway {
+		t.Errorf("without the auth-service status = %d, want 502", w.Code)
+	}
+}
+
+func TestGatewayUnifiesErrors(t *testing.T) {
+	g := newTestGateway(t, nil)
+	tok := token(t, "tenant-1", time.Hour)
+
+	tests := []struct {
+		shape       string
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{"nested", http.StatusConflict, "conflict", "email already in use"},
+		{"flat", http.StatusBadRequest, "bad_email", "email is invalid"},
+		{"plain", http.StatusInternalServerError, "upstream_error", "database is down"},
+		{"empty", http.StatusForbidden, "forbidden", "forbidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shape, func(t *testing.T) {
+			w := serve(g, "acme.local", "/api/v1/users/"+tt.shape, tok, "X-Request-ID", "req-42")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			got := decode[mockkit.ErrorResponse](t, w).Error
+			want := mockkit.ErrorBody{Code: tt.wantCode, Message: tt.wantMessage, RequestID: "req-42"}
+			if got.Code != want.Code || got.Message != want.Message || got.RequestID != want.RequestID {
+				t.Errorf("error = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
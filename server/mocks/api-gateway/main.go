@@ -1,36 +1,63 @@
 package main
 
 import (
-	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
-	"os"
-)
+	"strings"
+	"time"
 
-type HealthResponse struct {
-	Status  string `json:"status"`
-	Service string `json:"service"`
-}
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(HealthResponse{
-		Status:  "healthy",
-		Service: "api-gateway",
-	})
+// serviceURL reads the address of a downstream mock. Addresses without a
+// scheme are taken to be plain HTTP.
+func serviceURL(env, def string) string {
+	url := mockkit.Env(env, def)
+	if !strings.Contains(url, "://") {
+		url = "http://" + url
+	}
+	return url
 }
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	svc := mockkit.New("api-gateway", "8080")
+
+	tenantURL := serviceURL("TENANT_SERVICE_URL", "localhost:8083")
+	authURL := serviceURL("AUTH_SERVICE_URL", "localhost:8081")
+	gateway, err := NewGateway(
+		[]Upstream{
+			{Prefix: "auth", URL: authURL, Public: true},
+			{Prefix: "users", URL: serviceURL("USER_SERVICE_URL", "localhost:8082")},
+			{Prefix: "tenants", URL: tenantURL},
+			{Prefix: "routes", URL: tenantURL},
+			{Prefix: "notifications", URL: serviceURL("NOTIFICATION_SERVICE_URL", "localhost:8084")},
+			{Prefix: "config", URL: serviceURL("SYSTEM_CONFIG_SERVICE_URL", "localhost:8085")},
+		},
+		&TenantResolver{URL: tenantURL},
+		&mockkit.Signer{Secret: []byte(mockkit.Env("JWT_SECRET", "dev-secret-change-in-production"))},
+		&Introspector{URL: authURL},
+	)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	http.HandleFunc("/health", healthHandler)
-	
-	log.Printf("API Gateway mock service starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	// Without the tenant-service no request can be routed.
+	client := &http.Client{Timeout: 2 * time.Second}
+	svc.Ready(func() error {
+		resp, err := client.Get(tenantURL + "/health")
+		if err != nil {
+			return fmt.Errorf("tenant-service: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("tenant-service: %s", resp.Status)
+		}
+		return nil
+	})
+
+	svc.Handle("/", gateway)
+	if err := svc.Run(); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// TenantInfo is the tenant and application a request is routed to, as
+// returned by the tenant-service route lookup.
+type TenantInfo struct {
+	TenantID       string `json:"tenant_id"`
+	AppCode        string `json:"app_code"`
+	IsCustomDomain bool   `json:"is_custom_domain"`
+	PathPrefix     string `json:"path_prefix"`
+}
+
+// LookupError is a failed lookup the gateway passes on to the client, such
+// as 404 "Tenant mapping not found".
+type LookupError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *LookupError) Error() string {
+	return e.Message
+}
+
+// TenantResolver resolves tenants with the tenant-service mock.
+type TenantResolver struct {
+	URL    string
+	Client *http.Client
+}
+
+// Resolve looks up the route serving host and path. A *LookupError means
+// the tenant-service answered; any other error means it could not be asked.
+func (t *TenantResolver) Resolve(host, path string) (*TenantInfo, error) {
+	client := t.Client
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	q := url.Values{"domain": {host}, "path": {path}}
+	resp, err := client.Get(t.URL + "/api/v1/routes/lookup?" + q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body mockkit.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error.Message == "" {
+			return nil, fmt.Errorf("tenant lookup returned %s", resp.Status)
+		}
+		return nil, &LookupError{Status: resp.StatusCode, Code: body.Error.Code, Message: body.Error.Message}
+	}
+	var info TenantInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("tenant lookup: %w", err)
+	}
+	return &info, nil
+}
@@ -1,3 +1,10 @@
 module github.com/vhvplatform/go-auth-service
 
 go 1.21
+
+require (
+	github.com/vhvplatform/go-framework/mocks/mockkit v0.0.0
+	golang.org/x/crypto v0.31.0
+)
+
+replace github.com/vhvplatform/go-framework/mocks/mockkit => ../mockkit
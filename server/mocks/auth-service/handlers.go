@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AuthServer implements the /api/v1/auth endpoints.
+type AuthServer struct {
+	Store      *Store
+	Tokens     *mockkit.Signer
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// TokenResponse is returned by register, login and refresh.
+type TokenResponse struct {
+	Token        string     `json:"token"`
+	RefreshToken string     `json:"refresh_token"`
+	TokenType    string     `json:"token_type"`
+	ExpiresIn    int64      `json:"expires_in"`
+	User         PublicUser `json:"user"`
+}
+
+// IntrospectResponse follows RFC 7662, with the claims of the token.
+type IntrospectResponse struct {
+	Active    bool   `json:"active"`
+	UserID    string `json:"user_id,omitempty"`
+	Email     string `json:"email,omitempty"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	Role      string `json:"role,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+}
+
+func (a *AuthServer) Register(svc *mockkit.Service) {
+	svc.HandleFunc("/api/v1/auth/register", mockkit.Method(http.MethodPost, a.register))
+	svc.HandleFunc("/api/v1/auth/login", mockkit.Method(http.MethodPost, a.login))
+	svc.HandleFunc("/api/v1/auth/refresh", mockkit.Method(http.MethodPost, a.refresh))
+	svc.HandleFunc("/api/v1/auth/logout", mockkit.Method(http.MethodPost, a.logout))
+	svc.HandleFunc("/api/v1/auth/introspect", mockkit.Method(http.MethodPost, a.introspect))
+}
+
+func (a *AuthServer) register(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		Name     string `json:"name"`
+		TenantID string `json:"tenant_id"`
+	}
+	if !mockkit.Decode(w, r, &req) {
+		return
+	}
+	// The gateway sets X-Tenant-ID from the domain the request came in on;
+	// the body may only repeat it, so no one registers into another tenant.
+	if tenant := r.Header.Get("X-Tenant-ID"); tenant != "" {
+		if req.TenantID != "" && req.TenantID != tenant {
+			mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the account is invalid",
+				mockkit.FieldError{Field: "tenant_id", Message: "must match the X-Tenant-ID header"})
+			return
+		}
+		req.TenantID = tenant
+	}
+	switch {
+	case !strings.Contains(req.Email, "@"):
+		mockkit.WriteError(w, http.StatusBadRequest, "invalid_request", "a valid email is required")
+		return
+	case len(req.Password) < 8:
+		mockkit.WriteError(w, http.StatusBadRequest, "invalid_request", "password must be at least 8 characters")
+		return
+	case req.TenantID == "":
+		mockkit.WriteError(w, http.StatusBadRequest, "invalid_request", "tenant_id is required")
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		mockkit.WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	u := &User{
+		ID:           "user-" + mockkit.RandomID(6),
+		Email:        strings.TrimSpace(req.Email),
+		PasswordHash: string(hash),
+		Name:         req.Name,
+		TenantID:     req.TenantID,
+		Role:         "user",
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+	}
+	if err := a.Store.Add(u); errors.Is(err, errEmailTaken) {
+		mockkit.WriteError(w, http.StatusConflict, "email_taken", "an account with this email already exists")
+		return
+	}
+	log.Printf("Registered %s (%s) in tenant %s", u.Email, u.ID, u.TenantID)
+	a.issue(w, http.StatusCreated, u)
+}
+
+func (a *AuthServer) login(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+	if !mockkit.Decode(w, r, &req) {
+		return
+	}
+
+	u := a.Store.ByEmail(req.Email)
+	if u == nil || u.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(req.Password)) != nil {
+		mockkit.WriteError(w, http.StatusUnauthorized, "invalid_credentials", "invalid email or password")
+		return
+	}
+	a.issue(w, http.StatusOK, u)
+}
+
+func (a *AuthServer) refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if !mockkit.Decode(w, r, &req) {
+		return
+	}
+
+	u := a.Store.ByID(a.Store.TakeRefresh(req.RefreshToken, time.Now()))
+	if u == nil {
+		mockkit.WriteError(w, http.StatusUnauthorized, "invalid_token", "refresh token is invalid or expired")
+		return
+	}
+	a.issue(w, http.StatusOK, u)
+}
+
+// logout revokes the bearer access token and the refresh token in the
+// body; either may be left out, but not both.
+func (a *AuthServer) logout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if r.ContentLength != 0 && !mockkit.Decode(w, r, &req) {
+		return
+	}
+	access := bearer(r)
+	if access == "" && req.RefreshToken == "" {
+		mockkit.WriteError(w, http.StatusBadRequest, "invalid_request", "send the access token as a bearer token or a refresh_token")
+		return
+	}
+
+	if access != "" {
+		claims, err := a.Tokens.Verify(access, time.Now())
+		if err != nil {
+			mockkit.WriteError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+			return
+		}
+		a.Store.Revoke(access, time.Unix(claims.ExpiresAt, 0))
+	}
+	if req.RefreshToken != "" {
+		a.Store.TakeRefresh(req.RefreshToken, time.Now())
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// introspect reports whether a token is active. The token is read from a
+// JSON body, a form body (as in RFC 7662) or the Authorization header.
+func (a *AuthServer) introspect(w http.ResponseWriter, r *http.Request) {
+	token := bearer(r)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		mockkit.WriteError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if body = bytes.TrimSpace(body); len(body) > 0 {
+		var req struct {
+			Token string `json:"token"`
+		}
+		if body[0] == '{' {
+			if err := json.Unmarshal(body, &req); err != nil {
+				mockkit.WriteError(w, http.StatusBadRequest, "invalid_request", "invalid JSON body: "+err.Error())
+				return
+			}
+		} else if form, err := url.ParseQuery(string(body)); err == nil {
+			req.Token = form.Get("token")
+		}
+		if req.Token != "" {
+			token = req.Token
+		}
+	}
+	if token == "" {
+		mockkit.WriteError(w, http.StatusBadRequest, "invalid_request", "token is required")
+		return
+	}
+
+	claims, err := a.Tokens.Verify(token, time.Now())
+	if err != nil || a.Store.Revoked(token) {
+		mockkit.WriteJSON(w, http.StatusOK, IntrospectResponse{Active: false})
+		return
+	}
+	mockkit.WriteJSON(w, http.StatusOK, IntrospectResponse{
+		Active:    true,
+		UserID:    claims.UserID,
+		Email:     claims.Email,
+		TenantID:  claims.TenantID,
+		Role:      claims.Role,
+		IssuedAt:  claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+		TokenType: "access",
+	})
+}
+
+// issue responds with a new access and refresh token for u.
+func (a *AuthServer) issue(w http.ResponseWriter, status int, u *User) {
+	now := time.Now()
+	token, err := a.Tokens.Sign(mockkit.Claims{
+		UserID:    u.ID,
+		Email:     u.Email,
+		TenantID:  u.TenantID,
+		Role:      u.Role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(a.AccessTTL).Unix(),
+		ID:        mockkit.RandomID(8),
+	})
+	if err != nil {
+		mockkit.WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	refresh := mockkit.RandomID(32)
+	a.Store.AddRefresh(refresh, u.ID, now.Add(a.RefreshTTL))
+
+	mockkit.WriteJSON(w, status, TokenResponse{
+		Token:        token,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(a.AccessTTL / time.Second),
+		User:         u.Public(),
+	})
+}
+
+func bearer(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+func newAuthServer() *AuthServer {
+	return &AuthServer{
+		Store:      NewStore(),
+		Tokens:     &mockkit.Signer{Secret: []byte("test-secret")},
+		AccessTTL:  time.Hour,
+		RefreshTTL: time.Hour,
+	}
+}
+
+// call runs h with a JSON body and the given headers, given as name, value
+// pairs.
+func call(t *testing.T, h http.HandlerFunc, body string, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return v
+}
+
+func errorCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	return decode[mockkit.ErrorResponse](t, w).Error.Code
+}
+
+func TestRegisterLoginRefreshLogout(t *testing.T) {
+	a := newAuthServer()
+
+	w := call(t, a.register, `{"email":"ann@example.com","password":"secret123","name":"Ann"}`, "X-Tenant-ID", "tenant-1")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register = %d %s", w.Code, w.Body)
+	}
+	if u := decode[TokenResponse](t, w).User; u.TenantID != "tenant-1" || u.Role != "user" {
+		t.Errorf("registered user = %+v", u)
+	}
+	if w := call(t, a.register, `{"email":"ANN@example.com","password":"secret123"}`, "X-Tenant-ID", "tenant-1"); w.Code != http.StatusConflict {
+		t.Errorf("duplicate register = %d, want 409", w.Code)
+	}
+
+	if w := call(t, a.login, `{"email":"ann@example.com","password":"wrong-password"}`); w.Code != http.StatusUnauthorized || errorCode(t, w) != "invalid_credentials" {
+		t.Errorf("login with a wrong password = %d %s", w.Code, w.Body)
+	}
+	w = call(t, a.login, `{"email":"ann@example.com","password":"secret123"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login = %d %s", w.Code, w.Body)
+	}
+	first := decode[TokenResponse](t, w)
+	claims, err := a.Tokens.Verify(first.Token, time.Now())
+	if err != nil || claims.Email != "ann@example.com" || claims.TenantID != "tenant-1" {
+		t.Fatalf("access token claims = %+v, %v", claims, err)
+	}
+
+	// Refresh tokens rotate: each can be used once.
+	w = call(t, a.refresh, `{"refresh_token":"`+first.RefreshToken+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh = %d %s", w.Code, w.Body)
+	}
+	second := decode[TokenResponse](t, w)
+	if second.RefreshToken == first.RefreshToken || second.Token == first.Token {
+		t.Error("refresh returned the same tokens")
+	}
+	if w := call(t, a.refresh, `{"refresh_token":"`+first.RefreshToken+`"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("reused refresh token = %d, want 401", w.Code)
+	}
+
+	w = call(t, a.logout, `{"refresh_token":"`+second.RefreshToken+`"}`, "Authorization", "Bearer "+second.Token)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("logout = %d %s", w.Code, w.Body)
+	}
+	if w := call(t, a.refresh, `{"refresh_token":"`+second.RefreshToken+`"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout = %d, want 401", w.Code)
+	}
+	if w := call(t, a.logout, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("logout without tokens = %d, want 400", w.Code)
+	}
+
+	for _, tt := range []struct {
+		name, token string
+		active      bool
+	}{
+		{"logged out", second.Token, false},
+		{"still valid", first.Token, true},
+		{"garbage", "not.a.token", false},
+	} {
+		got := decode[IntrospectResponse](t, call(t, a.introspect, `{"token":"`+tt.token+`"}`))
+		if got.Active != tt.active {
+			t.Errorf("introspect %s: active = %v, want %v", tt.name, got.Active, tt.active)
+		}
+		if got.Active && got.UserID != claims.UserID {
+			t.Errorf("introspect %s: user_id = %q, want %q", tt.name, got.UserID, claims.UserID)
+		}
+	}
+
+	// RFC 7662 clients send a form body.
+	form := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("token="+second.Token))
+	form.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	a.introspect(rec, form)
+	if decode[IntrospectResponse](t, rec).Active {
+		t.Error("introspect with a form body reported a logged out token active")
+	}
+}
+
+func TestRegisterTenant(t *testing.T) {
+	a := newAuthServer()
+	tests := []struct {
+		name       string
+		body       string
+		header     string
+		wantStatus int
+		wantTenant string
+	}{
+		{"header only", `{"email":"a@example.com","password":"secret123"}`, "tenant-1", http.StatusCreated, "tenant-1"},
+		{"body repeats header", `{"email":"b@example.com","password":"secret123","tenant_id":"tenant-1"}`, "tenant-1", http.StatusCreated, "tenant-1"},
+		{"body only", `{"email":"c@example.com","password":"secret123","tenant_id":"tenant-2"}`, "", http.StatusCreated, "tenant-2"},
+		{"body names another tenant", `{"email":"d@example.com","password":"secret123","tenant_id":"tenant-2"}`, "tenant-1", http.StatusUnprocessableEntity, ""},
+		{"no tenant", `{"email":"e@example.com","password":"secret123"}`, "", http.StatusBadRequest, ""},
+		{"short password", `{"email":"f@example.com","password":"short"}`, "tenant-1", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.header != "" {
+				headers = []string{"X-Tenant-ID", tt.header}
+			}
+			w := call(t, a.register, tt.body, headers...)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if tt.wantTenant != "" {
+				if got := decode[TokenResponse](t, w).User.TenantID; got != tt.wantTenant {
+					t.Errorf("tenant = %q, want %q", got, tt.wantTenant)
+				}
+			}
+			if w.Code == http.StatusUnprocessableEntity {
+				body := decode[mockkit.ErrorResponse](t, w)
+				if body.Error.Code != "validation_failed" || len(body.Error.Details) != 1 || body.Error.Details[0].Field != "tenant_id" {
+					t.Errorf("error = %+v", body.Error)
+				}
+			}
+		})
+	}
+}
+
+// TestAPIScriptRegisters runs scripts/utilities/test-api.sh against register
+// behind a stand-in for the gateway, which resolves localhost to tenant-1.
+func TestAPIScriptRegisters(t *testing.T) {
+	for _, tool := range []string{"bash", "curl"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not installed", tool)
+		}
+	}
+	a := newAuthServer()
+	var status int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/auth/register" {
+			http.NotFound(w, r)
+			return
+		}
+		r.Header.Set("X-Tenant-ID", "tenant-1")
+		rec := httptest.NewRecorder()
+		a.register(rec, r)
+		status = rec.Code
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	defer srv.Close()
+
+	cmd := exec.Command("bash", "../../scripts/utilities/test-api.sh")
+	cmd.Env = append(cmd.Environ(), "API_BASE="+srv.URL)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("test-api.sh: %v\n%s", err, out)
+	}
+	if status != http.StatusCreated {
+		t.Errorf("register from test-api.sh = %d, want 201:\n%s", status, out)
+	}
+}
@@ -1,36 +1,44 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
-	"net/http"
 	"os"
-)
-
-type HealthResponse struct {
-	Status  string `json:"status"`
-	Service string `json:"service"`
-}
+	"time"
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(HealthResponse{
-		Status:  "healthy",
-		Service: "auth-service",
-	})
-}
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8081"
+	svc := mockkit.New("auth-service", "8081")
+
+	usersFile := mockkit.Env("USERS_FILE", "/fixtures/users.json")
+	store := NewStore()
+	if n, err := store.LoadUsers(usersFile); err != nil {
+		log.Printf("No fixture users loaded from %s: %v", usersFile, err)
+	} else {
+		log.Printf("Loaded %d fixture users from %s", n, usersFile)
 	}
 
-	http.HandleFunc("/health", healthHandler)
-	
-	log.Printf("Auth Service mock service starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	auth := &AuthServer{
+		Store:      store,
+		Tokens:     &mockkit.Signer{Secret: []byte(mockkit.Env("JWT_SECRET", "dev-secret-change-in-production"))},
+		AccessTTL:  durationEnv("ACCESS_TOKEN_TTL", time.Hour),
+		RefreshTTL: durationEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+	}
+	auth.Register(svc)
+	if err := svc.Run(); err != nil {
 		log.Fatal(err)
 	}
 }
+
+func durationEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+	return d
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// User is an account as stored in server/fixtures/users.json.
+type User struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	Name         string    `json:"name"`
+	TenantID     string    `json:"tenant_id"`
+	Role         string    `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PublicUser is a User without its password hash.
+type PublicUser struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	TenantID  string    `json:"tenant_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (u *User) Public() PublicUser {
+	return PublicUser{ID: u.ID, Email: u.Email, Name: u.Name, TenantID: u.TenantID, Role: u.Role, CreatedAt: u.CreatedAt}
+}
+
+var errEmailTaken = errors.New("email already registered")
+
+type refreshToken struct {
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// Store keeps users, refresh tokens and revoked access tokens in memory.
+// Users registered at runtime are lost on restart.
+type Store struct {
+	mu      sync.Mutex
+	users   map[string]*User // by lower-cased email
+	refresh map[string]refreshToken
+	revoked map[string]time.Time // token key to expiry
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:   map[string]*User{},
+		refresh: map[string]refreshToken{},
+		revoked: map[string]time.Time{},
+	}
+}
+
+// LoadUsers adds the users of a fixture file and returns how many there were.
+func (s *Store) LoadUsers(path string) (int, error) {
+	var users []*User
+	if err := mockkit.LoadJSON(path, &users); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range users {
+		s.users[strings.ToLower(u.Email)] = u
+	}
+	return len(users), nil
+}
+
+func (s *Store) ByEmail(email string) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.users[strings.ToLower(email)]
+}
+
+func (s *Store) ByID(id string) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+// Add stores a new user, failing when the email is taken.
+func (s *Store) Add(u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.ToLower(u.Email)
+	if _, ok := s.users[key]; ok {
+		return errEmailTaken
+	}
+	s.users[key] = u
+	return nil
+}
+
+func (s *Store) AddRefresh(token, userID string, expires time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh[token] = refreshToken{UserID: userID, ExpiresAt: expires}
+}
+
+// TakeRefresh removes a refresh token and returns its user ID, or "" when
+// the token is unknown or expired. Refresh tokens are single-use.
+func (s *Store) TakeRefresh(token string, now time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rt, ok := s.refresh[token]
+	delete(s.refresh, token)
+	if !ok || now.After(rt.ExpiresAt) {
+		return ""
+	}
+	return rt.UserID
+}
+
+// Revoke marks an access token as logged out until it expires.
+func (s *Store) Revoke(token string, expires time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, exp := range s.revoked {
+		if now.After(exp) {
+			delete(s.revoked, k)
+		}
+	}
+	s.revoked[tokenKey(token)] = expires
+}
+
+func (s *Store) Revoked(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.revoked[tokenKey(token)]
+	return ok
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// tokenKey identifies a token without keeping it, for the revocation list.
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,347 @@
+package mockkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// faultsPath is the admin endpoint that manages fault rules. It is
+	// not a route of the service, so the gateway never proxies it.
+	faultsPath = "/__admin/faults"
+
+	// defaultFaultTTL applies when a rule has no ttl, so that a forgotten
+	// rule does not outlive the test that added it by much.
+	defaultFaultTTL = 5 * time.Minute
+	maxFaultTTL     = 24 * time.Hour
+)
+
+// Duration is a time.Duration written in JSON as a string such as "1.5s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"500ms\"")
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Fault is a rule that degrades the requests it matches. Path matches the
+// request path and the paths below it, so /api/v1/user matches
+// /api/v1/user/1 but not /api/v1/users; Path, Method and Tenant (the
+// X-Tenant-ID header) left empty match every request. Of the rules
+// matching a request, the oldest that fires applies.
+//
+// A matching request is delayed by Latency, then answered with Status or
+// dropped without a response if Drop is set. Rate is the share of matching
+// requests the rule applies to, 1 when left out; a rate of 0 applies to
+// none. Unhealthy rules instead make /health and /ready answer 503 while
+// they last.
+type Fault struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Latency   Duration  `json:"latency,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	Drop      bool      `json:"drop,omitempty"`
+	Unhealthy bool      `json:"unhealthy,omitempty"`
+	Rate      *float64  `json:"rate,omitempty"`
+	TTL       Duration  `json:"ttl,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Hits      int       `json:"hits"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (f *Fault) validate() []FieldError {
+	var problems []FieldError
+	if f.Path != "" && !strings.HasPrefix(f.Path, "/") {
+		problems = append(problems, FieldError{Field: "path", Message: "must start with /"})
+	}
+	if f.Status != 0 && (f.Status < 200 || f.Status > 599) {
+		problems = append(problems, FieldError{Field: "status", Message: "must be from 200 to 599"})
+	}
+	if f.Status != 0 && f.Drop {
+		problems = append(problems, FieldError{Field: "drop", Message: "cannot be combined with status"})
+	}
+	if f.Latency < 0 {
+		problems = append(problems, FieldError{Field: "latency", Message: "must not be negative"})
+	}
+	if f.Rate != nil && (*f.Rate < 0 || *f.Rate > 1) {
+		problems = append(problems, FieldError{Field: "rate", Message: "must be from 0 to 1"})
+	}
+	if f.TTL < 0 || time.Duration(f.TTL) > maxFaultTTL {
+		problems = append(problems, FieldError{Field: "ttl", Message: "must be at most " + maxFaultTTL.String()})
+	}
+	if f.Unhealthy && (f.Latency != 0 || f.Status != 0 || f.Drop || f.Path != "" || f.Method != "" || f.Tenant != "") {
+		problems = append(problems, FieldError{Field: "unhealthy", Message: "applies to /health and /ready only; add a separate rule for the routes"})
+	}
+	if f.Latency == 0 && f.Status == 0 && !f.Drop && !f.Unhealthy {
+		problems = append(problems, FieldError{Field: "latency", Message: "a rule needs latency, status, drop or unhealthy"})
+	}
+	return problems
+}
+
+func (f *Fault) matches(r *http.Request) bool {
+	return underPath(r.URL.Path, f.Path) &&
+		(f.Method == "" || f.Method == r.Method) &&
+		(f.Tenant == "" || f.Tenant == r.Header.Get("X-Tenant-ID"))
+}
+
+// underPath reports whether path is prefix or below it, segment by segment.
+func underPath(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// faults holds the rules of a service.
+type faults struct {
+	mu    sync.Mutex
+	rules []*Fault
+	next  int
+}
+
+// active drops expired rules and returns the rest.
+func (fs *faults) active(now time.Time) []*Fault {
+	kept := fs.rules[:0]
+	for _, f := range fs.rules {
+		if now.Before(f.ExpiresAt) {
+			kept = append(kept, f)
+		}
+	}
+	fs.rules = kept
+	return kept
+}
+
+func (fs *faults) add(f Fault, now time.Time) Fault {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.next++
+	f.ID = "fault-" + strconv.Itoa(fs.next)
+	f.Method = strings.ToUpper(f.Method)
+	if f.Rate == nil {
+		all := 1.0
+		f.Rate = &all
+	}
+	if f.TTL == 0 {
+		f.TTL = Duration(defaultFaultTTL)
+	}
+	f.CreatedAt = now.UTC()
+	f.ExpiresAt = f.CreatedAt.Add(time.Duration(f.TTL))
+	f.Hits = 0
+	fs.rules = append(fs.active(now), &f)
+	return f
+}
+
+func (fs *faults) list(now time.Time) []Fault {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	out := []Fault{}
+	for _, f := range fs.active(now) {
+		out = append(out, *f)
+	}
+	return out
+}
+
+// remove deletes the rule with id, or every rule when id is empty, and
+// returns how many it deleted.
+func (fs *faults) remove(id string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	n := len(fs.rules)
+	kept := fs.rules[:0]
+	for _, f := range fs.rules {
+		if id != "" && f.ID != id {
+			kept = append(kept, f)
+		}
+	}
+	fs.rules = kept
+	return n - len(kept)
+}
+
+// match returns a copy of the rule to apply to r, if any, counting the
+// hit.
+func (fs *faults) match(r *http.Request, now time.Time) *Fault {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, f := range fs.active(now) {
+		if f.Unhealthy || !f.matches(r) {
+			continue
+		}
+		if *f.Rate < 1 && rand.Float64() >= *f.Rate {
+			continue
+		}
+		f.Hits++
+		hit := *f
+		return &hit
+	}
+	return nil
+}
+
+// unhealthy returns the ID of an active unhealthy rule, if any.
+func (fs *faults) unhealthy(now time.Time) string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, f := range fs.active(now) {
+		if f.Unhealthy {
+			f.Hits++
+			return f.ID
+		}
+	}
+	return ""
+}
+
+// inject applies the rule matching r, if any. It returns false when it
+// answered the request itself.
+func (fs *faults) inject(w http.ResponseWriter, r *http.Request) bool {
+	f := fs.match(r, time.Now())
+	if f == nil {
+		return true
+	}
+	if rec, ok := w.(*recorder); ok {
+		rec.fault = f.ID
+	}
+	if f.Latency > 0 {
+		t := time.NewTimer(time.Duration(f.Latency))
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			return false
+		}
+	}
+	switch {
+	case f.Drop:
+		drop(w)
+		return false
+	case f.Status != 0:
+		WriteError(w, f.Status, "injected_fault", fmt.Sprintf("%s injected by mock rule %s", http.StatusText(f.Status), f.ID))
+		return false
+	}
+	return true
+}
+
+// drop closes the connection without answering. Where the connection
+// cannot be taken over, as with HTTP/2, the handler aborts instead.
+func drop(w http.ResponseWriter) {
+	if rec, ok := w.(*recorder); ok {
+		rec.status = 0
+	}
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	conn.Close()
+}
+
+// ServeHTTP is the admin API:
+//
+//	GET    /__admin/faults       list the active rules
+//	POST   /__admin/faults       add a rule
+//	DELETE /__admin/faults       delete every rule
+//	GET    /__admin/faults/{id}  get a rule
+//	DELETE /__admin/faults/{id}  delete a rule
+func (fs *faults) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, faultsPath), "/")
+	if id != "" {
+		fs.serveRule(w, r, id)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, http.StatusOK, map[string]any{"data": fs.list(time.Now())})
+	case http.MethodPost:
+		var f Fault
+		if !Decode(w, r, &f) {
+			return
+		}
+		if problems := f.validate(); len(problems) > 0 {
+			WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the fault is invalid", problems...)
+			return
+		}
+		f = fs.add(f, time.Now())
+		log.Printf("Added %s: %s", f.ID, f.describe())
+		w.Header().Set("Location", faultsPath+"/"+f.ID)
+		WriteJSON(w, http.StatusCreated, f)
+	case http.MethodDelete:
+		if n := fs.remove(""); n > 0 {
+			log.Printf("Removed %d fault(s)", n)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		MethodNotAllowed(w, r, "GET, POST, DELETE")
+	}
+}
+
+func (fs *faults) serveRule(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		for _, f := range fs.list(time.Now()) {
+			if f.ID == id {
+				WriteJSON(w, http.StatusOK, f)
+				return
+			}
+		}
+		WriteError(w, http.StatusNotFound, "not_found", "fault not found")
+	case http.MethodDelete:
+		if fs.remove(id) == 0 {
+			WriteError(w, http.StatusNotFound, "not_found", "fault not found")
+			return
+		}
+		log.Printf("Removed %s", id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		MethodNotAllowed(w, r, "GET, DELETE")
+	}
+}
+
+// describe summarises a rule for the log.
+func (f *Fault) describe() string {
+	var effects []string
+	if f.Latency > 0 {
+		effects = append(effects, "latency "+time.Duration(f.Latency).String())
+	}
+	if f.Status != 0 {
+		effects = append(effects, "status "+strconv.Itoa(f.Status))
+	}
+	if f.Drop {
+		effects = append(effects, "drop")
+	}
+	if f.Unhealthy {
+		effects = append(effects, "unhealthy")
+	}
+	scope := firstNonEmpty(f.Method, "*") + " " + firstNonEmpty(f.Path, "/") + "*"
+	if f.Unhealthy {
+		scope = "/health and /ready"
+	}
+	if f.Tenant != "" {
+		scope += " tenant=" + f.Tenant
+	}
+	return fmt.Sprintf("%s on %s at rate %g for %s", strings.Join(effects, ", "), scope, *f.Rate, time.Duration(f.TTL))
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,322 @@
+package mockkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newFaultService returns a Service with one route, /api/v1/users/, that
+// answers 200.
+func newFaultService() *Service {
+	svc := New("example-service", "0")
+	svc.HandleFunc("/api/v1/users/", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"ok": "yes"})
+	})
+	return svc
+}
+
+func rate(v float64) *float64 {
+	return &v
+}
+
+func TestUnderPath(t *testing.T) {
+	tests := []struct {
+		path, prefix string
+		want         bool
+	}{
+		{"/api/v1/users", "", true},
+		{"/api/v1/users", "/", true},
+		{"/api/v1/users", "/api/v1/users", true},
+		{"/api/v1/users/1", "/api/v1/users", true},
+		{"/api/v1/users/1", "/api/v1/users/", true},
+		{"/api/v1/users", "/api/v1/user", false},
+		{"/api/v1/user", "/api/v1/users", false},
+		{"/api/v1", "/api/v1/users", false},
+	}
+	for _, tt := range tests {
+		if got := underPath(tt.path, tt.prefix); got != tt.want {
+			t.Errorf("underPath(%q, %q) = %v, want %v", tt.path, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestFaultMatching(t *testing.T) {
+	svc := newFaultService()
+	h := svc.Handler()
+	svc.faults.add(Fault{Path: "/api/v1/users", Method: "post", Tenant: "tenant-1", Status: http.StatusServiceUnavailable}, time.Now())
+
+	tests := []struct {
+		method, target, tenant string
+		want                   int
+	}{
+		{http.MethodPost, "/api/v1/users/1", "tenant-1", http.StatusServiceUnavailable},
+		{http.MethodGet, "/api/v1/users/1", "tenant-1", http.StatusOK},
+		{http.MethodPost, "/api/v1/users/1", "tenant-2", http.StatusOK},
+		{http.MethodPost, "/api/v1/users/1", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := serve(h, tt.method, tt.target, tt.tenant, "")
+		if w.Code != tt.want {
+			t.Errorf("%s %s tenant=%q = %d, want %d", tt.method, tt.target, tt.tenant, w.Code, tt.want)
+		}
+		if w.Code == http.StatusServiceUnavailable {
+			var body ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Error.Code != "injected_fault" {
+				t.Errorf("injected error = %s, %v", w.Body, err)
+			}
+		}
+	}
+	if f := svc.faults.list(time.Now())[0]; f.Hits != 1 {
+		t.Errorf("hits = %d, want 1", f.Hits)
+	}
+}
+
+func TestFaultRate(t *testing.T) {
+	const requests = 1000
+	for _, tt := range []struct {
+		name     string
+		rate     *float64
+		min, max int
+	}{
+		{"left out", nil, requests, requests},
+		{"zero", rate(0), 0, 0},
+		{"half", rate(0.5), 1, requests - 1},
+		{"one", rate(1), requests, requests},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newFaultService()
+			h := svc.Handler()
+			svc.faults.add(Fault{Status: http.StatusInternalServerError, Rate: tt.rate}, time.Now())
+
+			failed := 0
+			for i := 0; i < requests; i++ {
+				if serve(h, http.MethodGet, "/api/v1/users/1", "", "").Code == http.StatusInternalServerError {
+					failed++
+				}
+			}
+			if failed < tt.min || failed > tt.max {
+				t.Errorf("%d of %d requests failed, want %d to %d", failed, requests, tt.min, tt.max)
+			}
+			if f := svc.faults.list(time.Now())[0]; f.Hits != failed {
+				t.Errorf("hits = %d, want %d", f.Hits, failed)
+			}
+		})
+	}
+}
+
+func TestFaultExpires(t *testing.T) {
+	fs := &faults{}
+	now := time.Now()
+	short := fs.add(Fault{Status: http.StatusBadGateway, TTL: Duration(time.Minute)}, now)
+	long := fs.add(Fault{Status: http.StatusBadGateway}, now)
+	if time.Duration(long.TTL) != defaultFaultTTL {
+		t.Errorf("default ttl = %s, want %s", time.Duration(long.TTL), defaultFaultTTL)
+	}
+
+	var ids []string
+	for _, f := range fs.list(now.Add(time.Minute - time.Second)) {
+		ids = append(ids, f.ID)
+	}
+	if want := []string{short.ID, long.ID}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("rules before the ttl = %v, want %v", ids, want)
+	}
+
+	// active drops a rule once its ttl has passed, at the exact expiry too.
+	kept := fs.active(short.ExpiresAt)
+	if len(kept) != 1 || kept[0].ID != long.ID || len(fs.rules) != 1 {
+		t.Errorf("rules at the expiry of %s = %+v", short.ID, kept)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	if f := fs.match(r, long.ExpiresAt); f != nil {
+		t.Errorf("expired rule %s still matches", f.ID)
+	}
+	if len(fs.rules) != 0 {
+		t.Errorf("expired rules are kept: %d", len(fs.rules))
+	}
+}
+
+func TestFaultDrop(t *testing.T) {
+	svc := newFaultService()
+	srv := httptest.NewServer(svc.Handler())
+	defer srv.Close()
+	svc.faults.add(Fault{Path: "/api/v1/users", Drop: true}, time.Now())
+
+	resp, err := http.Get(srv.URL + "/api/v1/users/1")
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("dropped request got a response: %s", resp.Status)
+	}
+	// The connection is gone, not the server.
+	resp, err = http.Get(srv.URL + "/health")
+	if err != nil {
+		t.Fatalf("GET /health after a drop: %v", err)
+	}
+	resp.Body.Close()
+
+	// Without a connection to take over the handler aborts.
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("recover() = %v, want http.ErrAbortHandler", r)
+		}
+	}()
+	serve(svc.Handler(), http.MethodGet, "/api/v1/users/1", "", "")
+}
+
+func TestFaultUnhealthy(t *testing.T) {
+	svc := newFaultService()
+	h := svc.Handler()
+	f := svc.faults.add(Fault{Unhealthy: true, TTL: Duration(time.Minute)}, time.Now())
+
+	for path, status := range map[string]string{"/health": "unhealthy", "/ready": "not_ready"} {
+		w := serve(h, http.MethodGet, path, "", "")
+		var body HealthResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != http.StatusServiceUnavailable || body.Status != status || body.Error != "made unhealthy by mock rule "+f.ID {
+			t.Errorf("%s = %d %+v, want 503 %s", path, w.Code, body, status)
+		}
+	}
+	// The routes keep working.
+	if w := serve(h, http.MethodGet, "/api/v1/users/1", "", ""); w.Code != http.StatusOK {
+		t.Errorf("route with an unhealthy rule = %d, want 200", w.Code)
+	}
+
+	svc.faults.remove(f.ID)
+	if w := serve(h, http.MethodGet, "/health", "", ""); w.Code != http.StatusOK {
+		t.Errorf("/health after removing the rule = %d, want 200", w.Code)
+	}
+}
+
+func TestFaultValidation(t *testing.T) {
+	h := newFaultService().Handler()
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"no effect", `{"path":"/api"}`, []string{"latency"}},
+		{"relative path", `{"path":"api","status":500}`, []string{"path"}},
+		{"status out of range", `{"status":99}`, []string{"status"}},
+		{"drop with status", `{"status":500,"drop":true}`, []string{"drop"}},
+		{"negative latency", `{"latency":"-1s"}`, []string{"latency"}},
+		{"rate above one", `{"status":500,"rate":1.5}`, []string{"rate"}},
+		{"rate below zero", `{"status":500,"rate":-0.1}`, []string{"rate"}},
+		{"ttl too long", `{"status":500,"ttl":"48h"}`, []string{"ttl"}},
+		{"unhealthy with a scope", `{"unhealthy":true,"path":"/api"}`, []string{"unhealthy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(h, http.MethodPost, faultsPath, "", tt.body)
+			var body ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			var fields []string
+			for _, d := range body.Error.Details {
+				fields = append(fields, d.Field)
+			}
+			if w.Code != http.StatusUnprocessableEntity || !reflect.DeepEqual(fields, tt.want) {
+				t.Errorf("POST %s = %d with fields %v, want 422 with %v", tt.body, w.Code, fields, tt.want)
+			}
+		})
+	}
+
+	if w := serve(h, http.MethodPost, faultsPath, "", `{"latency":500}`); w.Code != http.StatusBadRequest {
+		t.Errorf("POST with a numeric latency = %d, want 400", w.Code)
+	}
+}
+
+func TestFaultAdminAPI(t *testing.T) {
+	svc := newFaultService()
+	h := svc.Handler()
+
+	list := func() []Fault {
+		t.Helper()
+		w := serve(h, http.MethodGet, faultsPath, "", "")
+		var body struct{ Data []Fault }
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", faultsPath, w.Code, w.Body)
+		}
+		return body.Data
+	}
+	if got := list(); len(got) != 0 {
+		t.Fatalf("rules of a new service = %+v", got)
+	}
+
+	var ids []string
+	for _, body := range []string{`{"path":"/api/v1/users","status":503}`, `{"latency":"50ms","method":"get"}`} {
+		w := serve(h, http.MethodPost, faultsPath, "", body)
+		var f Fault
+		if err := json.Unmarshal(w.Body.Bytes(), &f); err != nil || w.Code != http.StatusCreated {
+			t.Fatalf("POST %s = %d %s", body, w.Code, w.Body)
+		}
+		if got := w.Header().Get("Location"); got != faultsPath+"/"+f.ID {
+			t.Errorf("Location = %q, want %s/%s", got, faultsPath, f.ID)
+		}
+		ids = append(ids, f.ID)
+	}
+	if got := list(); len(got) != 2 || got[1].Method != http.MethodGet || *got[1].Rate != 1 {
+		t.Errorf("rules = %+v", got)
+	}
+
+	w := serve(h, http.MethodGet, faultsPath+"/"+ids[0], "", "")
+	var f Fault
+	if err := json.Unmarshal(w.Body.Bytes(), &f); err != nil || w.Code != http.StatusOK || f.Status != http.StatusServiceUnavailable {
+		t.Errorf("GET %s = %d %s", ids[0], w.Code, w.Body)
+	}
+
+	if w := serve(h, http.MethodDelete, faultsPath+"/"+ids[0], "", ""); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE %s = %d, want 204", ids[0], w.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if w := serve(h, method, faultsPath+"/"+ids[0], "", ""); w.Code != http.StatusNotFound {
+			t.Errorf("%s a deleted rule = %d, want 404", method, w.Code)
+		}
+	}
+	if got := list(); len(got) != 1 || got[0].ID != ids[1] {
+		t.Errorf("rules after deleting %s = %+v", ids[0], got)
+	}
+
+	if w := serve(h, http.MethodDelete, faultsPath, "", ""); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE %s = %d, want 204", faultsPath, w.Code)
+	}
+	if got := list(); len(got) != 0 {
+		t.Errorf("rules after deleting all = %+v", got)
+	}
+
+	for _, tt := range []struct{ method, target, allow string }{
+		{http.MethodPut, faultsPath, "GET, POST, DELETE"},
+		{http.MethodPost, faultsPath + "/" + ids[1], "GET, DELETE"},
+	} {
+		w := serve(h, tt.method, tt.target, "", "")
+		if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s = %d Allow %q, want 405 Allow %q", tt.method, tt.target, w.Code, w.Header().Get("Allow"), tt.allow)
+		}
+	}
+}
+
+func TestFaultLatencyStopsWithRequest(t *testing.T) {
+	svc := newFaultService()
+	h := svc.Handler()
+	svc.faults.add(Fault{Latency: Duration(time.Minute)}, time.Now())
+
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+	ctx, cancel := context.WithTimeout(r.Context(), 20*time.Millisecond)
+	defer cancel()
+	w := httptest.NewRecorder()
+	start := time.Now()
+	h.ServeHTTP(w, r.WithContext(ctx))
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the delay outlived the request: %s", elapsed)
+	}
+	if w.Body.Len() != 0 || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("cancelled request was answered: %d %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/vhvplatform/go-framework/mocks/mockkit
+
+go 1.21
@@ -0,0 +1,71 @@
+package mockkit
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ErrorResponse is the envelope of every error the mocks return:
+//
+//	{"error": {"code": "not_found", "message": "user not found"}}
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody is the error inside an ErrorResponse. Code is a stable,
+// machine-readable identifier such as validation_failed.
+type ErrorBody struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+	// RequestID is set by the api-gateway, from X-Request-ID.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// FieldError describes an invalid request field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// WriteJSON answers with status and v encoded as JSON.
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteError answers with status and the error envelope. details name the
+// invalid fields of a validation_failed error.
+func WriteError(w http.ResponseWriter, status int, code, message string, details ...FieldError) {
+	WriteJSON(w, status, ErrorResponse{Error: ErrorBody{Code: code, Message: message, Details: details}})
+}
+
+// Decode reads the JSON body of r into v, rejecting unknown fields. On
+// failure it writes a 400 and returns false.
+func Decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_json", "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// MethodNotAllowed answers 405, listing the allowed methods in Allow.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request, allow string) {
+	w.Header().Set("Allow", allow)
+	WriteError(w, http.StatusMethodNotAllowed, "method_not_allowed", r.Method+" is not allowed")
+}
+
+// Method restricts h to one HTTP method.
+func Method(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			MethodNotAllowed(w, r, method)
+			return
+		}
+		h(w, r)
+	}
+}
@@ -0,0 +1,56 @@
+package mockkit
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignerVerify(t *testing.T) {
+	signer := &Signer{Secret: []byte("secret")}
+	now := time.Unix(1_700_000_000, 0)
+	token, err := signer.Sign(Claims{UserID: "user-1", TenantID: "tenant-1", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	parts := strings.Split(token, ".")
+
+	claims, err := signer.Verify(token, now)
+	if err != nil || claims.UserID != "user-1" || claims.TenantID != "tenant-1" {
+		t.Fatalf("Verify() = %+v, %v", claims, err)
+	}
+
+	noneHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"user_id":"user-2","tenant_id":"tenant-1"}`))
+	tests := []struct {
+		name    string
+		token   string
+		signer  *Signer
+		now     time.Time
+		want    error
+		wantMsg string
+	}{
+		{"other secret", token, &Signer{Secret: []byte("other")}, now, ErrSignature, ""},
+		{"changed claims", parts[0] + "." + forged + "." + parts[2], signer, now, ErrSignature, ""},
+		{"alg none", noneHeader + "." + parts[1] + ".", signer, now, nil, "unsupported algorithm none"},
+		{"expired", token, signer, now.Add(time.Hour), ErrExpired, ""},
+		{"two parts", parts[0] + "." + parts[1], signer, now, ErrMalformed, ""},
+		{"bad header", "!!." + parts[1] + "." + parts[2], signer, now, ErrMalformed, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.signer.Verify(tt.token, tt.now)
+			if claims != nil {
+				t.Errorf("Verify() claims = %+v, want none", claims)
+			}
+			switch {
+			case tt.want != nil && !errors.Is(err, tt.want):
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			case tt.wantMsg != "" && (err == nil || err.Error() != tt.wantMsg):
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package mockkit
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// buckets are the upper bounds, in seconds, of the request duration
+// histogram.
+var buckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type series struct {
+	method, route string
+	status        int
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// metrics serves http_requests_total and http_request_duration_seconds in
+// the Prometheus text format.
+type metrics struct {
+	mu        sync.Mutex
+	requests  map[series]uint64
+	durations map[series]*histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{requests: map[series]uint64{}, durations: map[series]*histogram{}}
+}
+
+// observe records a request. Requests no route served, the 404s of the
+// mux, share the route label "unmatched".
+func (m *metrics) observe(method, route string, status int, elapsed time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	k := series{method, route, status}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[k]++
+	h := m.durations[k]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(buckets))}
+		m.durations[k] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, le := range buckets {
+		if seconds <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]series, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP http_requests_total Requests served, by method, route and status.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(w, "http_requests_total{%s} %d\n", k.labels(), m.requests[k])
+	}
+	fmt.Fprintln(w, "# HELP http_request_duration_seconds Time to serve requests, by method, route and status.")
+	fmt.Fprintln(w, "# TYPE http_request_duration_seconds histogram")
+	for _, k := range keys {
+		h := m.durations[k]
+		for i, le := range buckets {
+			fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=%q} %d\n", k.labels(), strconv.FormatFloat(le, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", k.labels(), h.count)
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{%s} %g\n", k.labels(), h.sum)
+		fmt.Fprintf(w, "http_request_duration_seconds_count{%s} %d\n", k.labels(), h.count)
+	}
+}
+
+func (k series) labels() string {
+	return fmt.Sprintf("method=%q,route=%q,status=\"%d\"", k.method, k.route, k.status)
+}
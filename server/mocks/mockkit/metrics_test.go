@@ -0,0 +1,65 @@
+package mockkit
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	svc := New("example-service", "0")
+	svc.HandleFunc("/api/v1/items/", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"id": strings.TrimPrefix(r.URL.Path, "/api/v1/items/")})
+	})
+	h := svc.Handler()
+
+	serve(h, http.MethodGet, "/api/v1/items/1", "", "")
+	serve(h, http.MethodGet, "/api/v1/items/2", "", "")
+	serve(h, http.MethodGet, "/nowhere", "", "")
+
+	w := serve(h, http.MethodGet, "/metrics", "", "")
+	if got := w.Header().Get("Content-Type"); got != "text/plain; version=0.0.4" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE http_requests_total counter\n",
+		"# TYPE http_request_duration_seconds histogram\n",
+		// Requests are labelled with the pattern that served them, not the
+		// path, so that IDs don't create a series each.
+		`http_requests_total{method="GET",route="/api/v1/items/",status="200"} 2` + "\n",
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1` + "\n",
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/items/",status="200",le="+Inf"} 2` + "\n",
+		`http_request_duration_seconds_count{method="GET",route="/api/v1/items/",status="200"} 2` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics lacks %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `route="/api/v1/items/1"`) {
+		t.Errorf("/metrics labels a request with its path:\n%s", body)
+	}
+	// The scrape itself is counted from the next one on.
+	if w := serve(h, http.MethodGet, "/metrics", "", ""); !strings.Contains(w.Body.String(), `http_requests_total{method="GET",route="/metrics",status="200"} 1`) {
+		t.Errorf("/metrics does not count its own requests:\n%s", w.Body)
+	}
+}
+
+func TestMetricsBuckets(t *testing.T) {
+	m := newMetrics()
+	m.observe(http.MethodPost, "/api/v1/items", http.StatusCreated, 30*time.Millisecond)
+	m.observe(http.MethodPost, "/api/v1/items", http.StatusCreated, 3*time.Second)
+
+	w := serve(m, http.MethodGet, "/metrics", "", "")
+	labels := `method="POST",route="/api/v1/items",status="201"`
+	for le, want := range map[string]string{"0.025": "0", "0.05": "1", "2.5": "1", "5": "2", "+Inf": "2"} {
+		line := "http_request_duration_seconds_bucket{" + labels + `,le="` + le + `"} ` + want + "\n"
+		if !strings.Contains(w.Body.String(), line) {
+			t.Errorf("/metrics lacks %q", line)
+		}
+	}
+	if line := "http_request_duration_seconds_sum{" + labels + "} 3.03\n"; !strings.Contains(w.Body.String(), line) {
+		t.Errorf("/metrics lacks %q:\n%s", line, w.Body)
+	}
+}
@@ -0,0 +1,508 @@
+package mockkit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// recordingsPath is the admin endpoint reporting on recording and
+	// replay.
+	recordingsPath = "/__admin/recordings"
+
+	// maxRecordedBody bounds the bodies written to a recording; larger
+	// exchanges are proxied but not recorded.
+	maxRecordedBody = 10 << 20
+)
+
+// matchFields are the parts of a request replay can match on.
+var matchFields = []string{"method", "path", "query", "body", "tenant"}
+
+// hopHeaders are not recorded: they describe one connection, not the
+// exchange. Date is set afresh on replay.
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Transfer-Encoding", "Upgrade", "Te", "Trailer", "Content-Length", "Date"}
+
+// redactedHeaders hold credentials, which are not written to disk.
+var redactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie"}
+
+// Recording is one request and response, stored as a JSON file.
+type Recording struct {
+	Request struct {
+		Method  string      `json:"method"`
+		Path    string      `json:"path"`
+		Query   string      `json:"query,omitempty"`
+		Headers http.Header `json:"headers,omitempty"`
+		Body    *Body       `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status  int         `json:"status"`
+		Headers http.Header `json:"headers,omitempty"`
+		Body    *Body       `json:"body,omitempty"`
+	} `json:"response"`
+	RecordedAt time.Time `json:"recorded_at"`
+	DurationMS int64     `json:"duration_ms"`
+
+	// file is the name the recording was loaded from.
+	file string
+}
+
+// Body is a recorded body: JSON as it is, so recordings can be read and
+// edited, other text as a string, and anything else base64-encoded.
+type Body struct {
+	JSON   json.RawMessage `json:"json,omitempty"`
+	Text   string          `json:"text,omitempty"`
+	Base64 string          `json:"base64,omitempty"`
+}
+
+func newBody(data []byte) *Body {
+	switch {
+	case len(data) == 0:
+		return nil
+	case json.Valid(data):
+		var compact bytes.Buffer
+		json.Compact(&compact, data)
+		return &Body{JSON: compact.Bytes()}
+	case utf8.Valid(data):
+		return &Body{Text: string(data)}
+	}
+	return &Body{Base64: base64.StdEncoding.EncodeToString(data)}
+}
+
+func (b *Body) bytes() []byte {
+	switch {
+	case b == nil:
+		return nil
+	case b.JSON != nil:
+		// The file may hold the JSON indented; serve it as it was sent.
+		var compact bytes.Buffer
+		if json.Compact(&compact, b.JSON) != nil {
+			return b.JSON
+		}
+		return compact.Bytes()
+	case b.Base64 != "":
+		data, _ := base64.StdEncoding.DecodeString(b.Base64)
+		return data
+	}
+	return []byte(b.Text)
+}
+
+// bodyHash hashes a body for matching. JSON is hashed in a canonical form,
+// so that key order and spacing do not matter.
+func bodyHash(data []byte) string {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return ""
+	}
+	var v any
+	if json.Unmarshal(data, &v) == nil {
+		data, _ = json.Marshal(v)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// cleanHeaders returns the headers of h worth recording, with the values
+// of credentials replaced.
+func cleanHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range hopHeaders {
+		out.Del(name)
+	}
+	for _, name := range redactedHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, "REDACTED")
+		}
+	}
+	return out
+}
+
+// Recorder proxies requests to an upstream and writes every exchange to a
+// file in Dir.
+type Recorder struct {
+	Upstream *url.URL
+	Dir      string
+
+	mu    sync.Mutex
+	seq   int
+	count int
+	proxy *httputil.ReverseProxy
+}
+
+// NewRecorder returns a Recorder for upstream, an absolute URL, creating
+// dir if needed.
+func NewRecorder(upstream, dir string) (*Recorder, error) {
+	u, err := url.Parse(upstream)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("UPSTREAM_URL %q must be an absolute URL such as https://dev.example.com", upstream)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	rec := &Recorder{Upstream: u, Dir: dir}
+	// Number new recordings after those already in Dir.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		prefix, _, _ := strings.Cut(e.Name(), "-")
+		if n, err := strconv.Atoi(prefix); err == nil && n > rec.seq {
+			rec.seq = n
+		}
+	}
+	rec.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(u)
+			pr.SetXForwarded()
+			// Uncompressed responses make readable recordings.
+			pr.Out.Header.Del("Accept-Encoding")
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("Upstream %s failed: %v", u.Host, err)
+			WriteError(w, http.StatusBadGateway, "upstream_unavailable", "the upstream did not answer")
+		},
+	}
+	return rec, nil
+}
+
+// ServeHTTP proxies r to the upstream and records the exchange.
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reqBody, err := io.ReadAll(io.LimitReader(r.Body, maxRecordedBody+1))
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "could not read the body: "+err.Error())
+		return
+	}
+	recordable := len(reqBody) <= maxRecordedBody
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(reqBody), r.Body))
+
+	var recording Recording
+	recording.Request.Method = r.Method
+	recording.Request.Path = r.URL.Path
+	recording.Request.Query = r.URL.Query().Encode()
+	recording.Request.Headers = cleanHeaders(r.Header)
+	recording.Request.Body = newBody(reqBody)
+	start := time.Now()
+
+	proxy := *rec.proxy
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		// Event streams never end, so they are passed through unrecorded.
+		if !recordable || strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			log.Printf("Not recording %s %s", r.Method, r.URL.Path)
+			return nil
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxRecordedBody+1))
+		if err != nil {
+			return err
+		}
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		if len(body) > maxRecordedBody {
+			log.Printf("Not recording %s %s: the response is over %d bytes", r.Method, r.URL.Path, maxRecordedBody)
+			return nil
+		}
+		recording.Response.Status = resp.StatusCode
+		recording.Response.Headers = cleanHeaders(resp.Header)
+		recording.Response.Body = newBody(body)
+		recording.RecordedAt = start.UTC()
+		recording.DurationMS = time.Since(start).Milliseconds()
+		if err := rec.save(&recording); err != nil {
+			log.Printf("Recording %s %s failed: %v", r.Method, r.URL.Path, err)
+		}
+		return nil
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+func (rec *Recorder) save(recording *Recording) error {
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	rec.mu.Lock()
+	rec.seq++
+	seq := rec.seq
+	rec.count++
+	rec.mu.Unlock()
+
+	slug := strings.Trim(unsafeName.ReplaceAllString(recording.Request.Path, "-"), "-")
+	if len(slug) > 60 {
+		slug = slug[:60]
+	}
+	name := fmt.Sprintf("%04d-%s-%s.json", seq, strings.ToLower(recording.Request.Method), slug)
+	return os.WriteFile(filepath.Join(rec.Dir, name), append(data, '\n'), 0o644)
+}
+
+func (rec *Recorder) report(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w, r, "GET")
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	WriteJSON(w, http.StatusOK, map[string]any{
+		"mode":     "record",
+		"upstream": rec.Upstream.String(),
+		"dir":      rec.Dir,
+		"recorded": rec.count,
+	})
+}
+
+// Replayer answers requests with the recordings whose request matches on
+// the Match fields. Several recordings of the same request are served in
+// file name order, the last one repeating, so that a recorded sequence
+// such as create-then-get plays back as it happened.
+type Replayer struct {
+	Dir   string
+	Match []string
+
+	mu        sync.Mutex
+	byKey     map[string][]*Recording
+	next      map[string]int
+	total     int
+	served    int
+	unmatched map[string]*Unmatched
+}
+
+// Unmatched is a request replay had no recording for.
+type Unmatched struct {
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	Tenant     string    `json:"tenant,omitempty"`
+	BodySHA256 string    `json:"body_sha256,omitempty"`
+	Count      int       `json:"count"`
+	FirstSeen  time.Time `json:"first_seen"`
+}
+
+// ParseMatch reads a comma-separated list of match fields.
+func ParseMatch(s string) ([]string, error) {
+	var fields []string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if f == "" {
+			continue
+		}
+		known := false
+		for _, m := range matchFields {
+			known = known || m == f
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown match field %q (use %s)", f, strings.Join(matchFields, ", "))
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no match fields given (use %s)", strings.Join(matchFields, ", "))
+	}
+	return fields, nil
+}
+
+// NewReplayer loads every recording in dir.
+func NewReplayer(dir string, match []string) (*Replayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	rp := &Replayer{Dir: dir, Match: match, byKey: map[string][]*Recording{}, next: map[string]int{}, unmatched: map[string]*Unmatched{}}
+	for _, file := range files {
+		var recording Recording
+		if err := LoadJSON(file, &recording); err != nil {
+			return nil, err
+		}
+		recording.file = filepath.Base(file)
+		req := recording.Request
+		key := rp.key(req.Method, req.Path, req.Query, req.Headers.Get("X-Tenant-ID"), bodyHash(req.Body.bytes()))
+		rp.byKey[key] = append(rp.byKey[key], &recording)
+		rp.total++
+	}
+	return rp, nil
+}
+
+// key joins the fields of a request that replay matches on.
+func (rp *Replayer) key(method, path, rawQuery, tenant, hash string) string {
+	var parts []string
+	for _, f := range rp.Match {
+		switch f {
+		case "method":
+			parts = append(parts, method)
+		case "path":
+			parts = append(parts, path)
+		case "query":
+			q, _ := url.ParseQuery(rawQuery)
+			parts = append(parts, q.Encode())
+		case "body":
+			parts = append(parts, hash)
+		case "tenant":
+			parts = append(parts, tenant)
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// ServeHTTP answers r with its recording, or 404 no_recording.
+func (rp *Replayer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", "could not read the body: "+err.Error())
+		return
+	}
+	tenant, hash := r.Header.Get("X-Tenant-ID"), bodyHash(body)
+	key := rp.key(r.Method, r.URL.Path, r.URL.RawQuery, tenant, hash)
+
+	rp.mu.Lock()
+	var recording *Recording
+	if list := rp.byKey[key]; len(list) > 0 {
+		i := rp.next[key]
+		if i < len(list)-1 {
+			rp.next[key] = i + 1
+		}
+		recording = list[min(i, len(list)-1)]
+		rp.served++
+	} else {
+		u := rp.unmatched[key]
+		if u == nil {
+			u = &Unmatched{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Tenant: tenant, BodySHA256: hash, FirstSeen: time.Now().UTC()}
+			rp.unmatched[key] = u
+		}
+		u.Count++
+	}
+	rp.mu.Unlock()
+
+	if recording == nil {
+		log.Printf("Unmatched %s %s: no recording matches on %s", r.Method, r.URL.RequestURI(), strings.Join(rp.Match, ", "))
+		WriteError(w, http.StatusNotFound, "no_recording", "no recording matches "+r.Method+" "+r.URL.RequestURI())
+		return
+	}
+	for name, values := range recording.Response.Headers {
+		w.Header()[name] = values
+	}
+	w.Header().Set("X-Mock-Recording", recording.file)
+	w.WriteHeader(recording.Response.Status)
+	w.Write(recording.Response.Body.bytes())
+}
+
+// Report lists the unmatched requests, most frequent first.
+func (rp *Replayer) Report() []Unmatched {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	out := make([]Unmatched, 0, len(rp.unmatched))
+	for _, u := range rp.unmatched {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].FirstSeen.Before(out[j].FirstSeen)
+	})
+	return out
+}
+
+// report serves the replay statistics; DELETE starts every sequence over
+// and forgets the unmatched requests.
+func (rp *Replayer) report(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		unmatched := rp.Report()
+		rp.mu.Lock()
+		defer rp.mu.Unlock()
+		WriteJSON(w, http.StatusOK, map[string]any{
+			"mode":       "replay",
+			"dir":        rp.Dir,
+			"match":      rp.Match,
+			"recordings": rp.total,
+			"served":     rp.served,
+			"unmatched":  unmatched,
+		})
+	case http.MethodDelete:
+		rp.mu.Lock()
+		defer rp.mu.Unlock()
+		rp.next = map[string]int{}
+		rp.unmatched = map[string]*Unmatched{}
+		rp.served = 0
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		MethodNotAllowed(w, r, "GET, DELETE")
+	}
+}
+
+// logReport logs the unmatched requests, for when the mock stops.
+func (rp *Replayer) logReport() {
+	unmatched := rp.Report()
+	if len(unmatched) == 0 {
+		log.Printf("Replay: every request matched a recording")
+		return
+	}
+	log.Printf("Replay: %d request(s) matched no recording:", len(unmatched))
+	for _, u := range unmatched {
+		target := u.Path
+		if u.Query != "" {
+			target += "?" + u.Query
+		}
+		log.Printf("  %dx %s %s", u.Count, u.Method, target)
+	}
+}
+
+// setMode applies MOCK_MODE. In record mode every request but the built-in
+// endpoints is proxied to UPSTREAM_URL and recorded in RECORDINGS_DIR; in
+// replay mode the recordings answer instead, matched on the REPLAY_MATCH
+// fields. Either way the routes of the mock and its readiness checks are
+// dropped, and fault rules still apply. The Replayer is returned so that
+// Run can report the unmatched requests on shutdown.
+func (s *Service) setMode() (*Replayer, error) {
+	mode := Env("MOCK_MODE", "")
+	dir := Env("RECORDINGS_DIR", filepath.Join("/recordings", s.Name))
+	switch mode {
+	case "":
+		return nil, nil
+	case "record":
+		rec, err := NewRecorder(os.Getenv("UPSTREAM_URL"), dir)
+		if err != nil {
+			return nil, fmt.Errorf("MOCK_MODE=record: %w", err)
+		}
+		s.reset()
+		s.checks = nil
+		s.Handle("/", rec)
+		s.handle(recordingsPath, http.HandlerFunc(rec.report))
+		log.Printf("Recording requests to %s in %s", rec.Upstream, dir)
+		return nil, nil
+	case "replay":
+		match, err := ParseMatch(Env("REPLAY_MATCH", strings.Join(matchFields, ",")))
+		if err != nil {
+			return nil, fmt.Errorf("REPLAY_MATCH: %w", err)
+		}
+		rp, err := NewReplayer(dir, match)
+		if err != nil {
+			return nil, fmt.Errorf("MOCK_MODE=replay: %w", err)
+		}
+		s.reset()
+		s.checks = nil
+		s.Handle("/", rp)
+		s.handle(recordingsPath, http.HandlerFunc(rp.report))
+		log.Printf("Replaying %d recordings from %s, matching on %s", rp.total, dir, strings.Join(match, ", "))
+		return rp, nil
+	}
+	return nil, fmt.Errorf("MOCK_MODE %q is not supported (use record or replay)", mode)
+}
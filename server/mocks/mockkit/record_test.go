@@ -0,0 +1,242 @@
+package mockkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// serve runs one request through h and returns the response.
+func serve(h http.Handler, method, target, tenant, body string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if tenant != "" {
+		r.Header.Set("X-Tenant-ID", tenant)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// record sends requests through a Recorder to an upstream that answers
+// every request with a count of the requests so far, and returns the
+// directory of the recordings.
+func record(t *testing.T, requests func(rec *Recorder)) string {
+	t.Helper()
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret; HttpOnly")
+		w.Header().Set("X-Upstream", "yes")
+		if r.URL.Path == "/missing" {
+			WriteError(w, http.StatusNotFound, "not_found", "no such thing")
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]any{"call": n, "path": r.URL.Path, "tenant": r.Header.Get("X-Tenant-ID"), "echo": string(body)})
+	}))
+	defer upstream.Close()
+
+	dir := filepath.Join(t.TempDir(), "recordings")
+	rec, err := NewRecorder(upstream.URL, dir)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	requests(rec)
+	return dir
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := record(t, func(rec *Recorder) {
+		w := serve(rec, http.MethodPost, "/api/v1/users?b=2&a=1", "tenant-1", `{"name":"Ann","email":"ann@example.com"}`,
+			"Authorization", "Bearer token", "Cookie", "session=secret")
+		if w.Code != http.StatusOK || w.Header().Get("X-Upstream") != "yes" {
+			t.Fatalf("recorded request = %d %v", w.Code, w.Header())
+		}
+		serve(rec, http.MethodGet, "/missing", "", "")
+		serve(rec, http.MethodGet, "/binary", "", "\x00\xff")
+	})
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 3 || filepath.Base(files[0]) != "0001-post-api-v1-users.json" {
+		t.Fatalf("recordings = %v", files)
+	}
+	var recording Recording
+	if err := LoadJSON(files[0], &recording); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []http.Header{recording.Request.Headers, recording.Response.Headers} {
+		for _, name := range []string{"Authorization", "Cookie", "Set-Cookie"} {
+			if v := h.Get(name); v != "" && v != "REDACTED" {
+				t.Errorf("%s recorded as %q", name, v)
+			}
+		}
+	}
+	if recording.Response.Headers.Get("Set-Cookie") != "REDACTED" || recording.Request.Headers.Get("Authorization") != "REDACTED" {
+		t.Errorf("credentials not redacted: request %v, response %v", recording.Request.Headers, recording.Response.Headers)
+	}
+	if recording.Request.Body == nil || recording.Request.Body.JSON == nil {
+		t.Errorf("JSON request body recorded as %+v", recording.Request.Body)
+	}
+	raw, _ := os.ReadFile(files[2])
+	if !strings.Contains(string(raw), `"base64"`) {
+		t.Errorf("binary body not base64-encoded: %s", raw)
+	}
+
+	// New recordings are numbered after those in the directory.
+	rec, err := NewRecorder("http://127.0.0.1:1", dir)
+	if err != nil || rec.seq != 3 {
+		t.Errorf("NewRecorder() on existing recordings: seq = %d, %v", rec.seq, err)
+	}
+
+	rp, err := NewReplayer(dir, matchFields)
+	if err != nil {
+		t.Fatalf("NewReplayer() error = %v", err)
+	}
+	// Query order and JSON key order do not matter.
+	w := serve(rp, http.MethodPost, "/api/v1/users?a=1&b=2", "tenant-1", `{"email": "ann@example.com", "name": "Ann"}`)
+	if w.Code != http.StatusOK || w.Header().Get("X-Mock-Recording") != "0001-post-api-v1-users.json" {
+		t.Fatalf("replay = %d %v %s", w.Code, w.Header(), w.Body)
+	}
+	var got map[string]any
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if got["tenant"] != "tenant-1" || got["call"] != float64(1) {
+		t.Errorf("replayed body = %v", got)
+	}
+	if w := serve(rp, http.MethodGet, "/missing", "", ""); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "no such thing") {
+		t.Errorf("replayed error = %d %s", w.Code, w.Body)
+	}
+	if w := serve(rp, http.MethodGet, "/binary", "", "\x00\xff"); w.Body.String() == "" {
+		t.Error("replayed binary response is empty")
+	}
+}
+
+func TestReplayMatchFields(t *testing.T) {
+	dir := record(t, func(rec *Recorder) {
+		serve(rec, http.MethodPost, "/api/v1/users?page=1", "tenant-1", `{"name":"Ann"}`)
+	})
+
+	tests := []struct {
+		match  string
+		method string
+		target string
+		tenant string
+		body   string
+		want   int
+	}{
+		{"method,path,query,body,tenant", "POST", "/api/v1/users?page=1", "tenant-1", `{"name":"Ann"}`, http.StatusOK},
+		{"method,path,query,body,tenant", "POST", "/api/v1/users?page=1", "tenant-2", `{"name":"Ann"}`, http.StatusNotFound},
+		{"method,path,query,body", "POST", "/api/v1/users?page=1", "tenant-2", `{"name":"Ann"}`, http.StatusOK},
+		{"method,path,query,body", "POST", "/api/v1/users?page=1", "tenant-1", `{"name":"Bob"}`, http.StatusNotFound},
+		{"method,path,query", "POST", "/api/v1/users?page=1", "tenant-1", `{"name":"Bob"}`, http.StatusOK},
+		{"method,path,query", "POST", "/api/v1/users?page=2", "tenant-1", `{"name":"Ann"}`, http.StatusNotFound},
+		{"method,path", "POST", "/api/v1/users?page=2", "", "", http.StatusOK},
+		{"method,path", "PUT", "/api/v1/users?page=1", "tenant-1", `{"name":"Ann"}`, http.StatusNotFound},
+		{"path", "DELETE", "/api/v1/users", "", "", http.StatusOK},
+		{"path", "POST", "/api/v1/users/1", "tenant-1", `{"name":"Ann"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.match+" "+tt.method+" "+tt.target+" "+tt.tenant+" "+tt.body, func(t *testing.T) {
+			match, err := ParseMatch(tt.match)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rp, err := NewReplayer(dir, match)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if w := serve(rp, tt.method, tt.target, tt.tenant, tt.body); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+
+	for _, s := range []string{"", " , ", "method,headers"} {
+		if _, err := ParseMatch(s); err == nil {
+			t.Errorf("ParseMatch(%q) succeeded", s)
+		}
+	}
+	if got, _ := ParseMatch(" Method , PATH "); strings.Join(got, ",") != "method,path" {
+		t.Errorf("ParseMatch() = %v", got)
+	}
+}
+
+func TestReplaySequence(t *testing.T) {
+	dir := record(t, func(rec *Recorder) {
+		for i := 0; i < 3; i++ {
+			serve(rec, http.MethodGet, "/api/v1/outbox", "", "")
+		}
+	})
+	rp, err := NewReplayer(dir, matchFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := func() string {
+		var out []string
+		for i := 0; i < 4; i++ {
+			var body map[string]any
+			json.Unmarshal(serve(rp, http.MethodGet, "/api/v1/outbox", "", "").Body.Bytes(), &body)
+			out = append(out, fmt.Sprint(body["call"]))
+		}
+		return strings.Join(out, ",")
+	}
+	// The last recording repeats once the sequence is played.
+	if got := calls(); got != "1,2,3,3" {
+		t.Errorf("replayed calls = %s, want 1,2,3,3", got)
+	}
+
+	// DELETE on the admin endpoint starts the sequence over.
+	if w := serve(http.HandlerFunc(rp.report), http.MethodDelete, recordingsPath, "", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("reset = %d", w.Code)
+	}
+	if got := calls(); got != "1,2,3,3" {
+		t.Errorf("replayed calls after reset = %s, want 1,2,3,3", got)
+	}
+}
+
+func TestReplayNoRecording(t *testing.T) {
+	rp, err := NewReplayer(t.TempDir(), matchFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		serve(rp, http.MethodGet, "/api/v1/users?limit=5", "tenant-1", "")
+	}
+	w := serve(rp, http.MethodPost, "/api/v1/users", "", `{"name":"Ann"}`)
+
+	var body ErrorResponse
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusNotFound || body.Error.Code != "no_recording" || body.Error.Message != "no recording matches POST /api/v1/users" {
+		t.Errorf("unmatched request = %d %+v", w.Code, body.Error)
+	}
+
+	report := rp.Report()
+	if len(report) != 2 {
+		t.Fatalf("Report() = %+v, want 2 entries", report)
+	}
+	if u := report[0]; u.Count != 2 || u.Path != "/api/v1/users" || u.Query != "limit=5" || u.Tenant != "tenant-1" {
+		t.Errorf("most frequent unmatched = %+v", u)
+	}
+	if u := report[1]; u.Count != 1 || u.BodySHA256 == "" {
+		t.Errorf("unmatched POST = %+v", u)
+	}
+
+	var stats struct {
+		Served    int         `json:"served"`
+		Unmatched []Unmatched `json:"unmatched"`
+	}
+	json.Unmarshal(serve(http.HandlerFunc(rp.report), http.MethodGet, recordingsPath, "", "").Body.Bytes(), &stats)
+	if stats.Served != 0 || len(stats.Unmatched) != 2 {
+		t.Errorf("admin report = %+v", stats)
+	}
+}
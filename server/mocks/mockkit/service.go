@@ -0,0 +1,247 @@
+// Package mockkit runs the mock services under server/mocks. A mock
+// creates a Service, registers its routes and calls Run, which adds
+// /health, /ready, /metrics and the /__admin/faults fault injection API,
+// logs every request and shuts down gracefully on SIGTERM. MOCK_MODE turns
+// any mock into a recording proxy or a replay of its recordings:
+//
+//	func main() {
+//		svc := mockkit.New("example-service", "8086")
+//		svc.HandleFunc("/api/v1/examples", mockkit.Method(http.MethodGet, list))
+//		if err := svc.Run(); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+package mockkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds how long Run waits for requests in flight.
+const shutdownTimeout = 10 * time.Second
+
+// HealthResponse is the body of /health and /ready.
+type HealthResponse struct {
+	Status  string `json:"status"`
+	Service string `json:"service"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Service is the HTTP server of one mock.
+type Service struct {
+	// Name is the compose service name, reported by /health.
+	Name string
+	// Port is used when the PORT variable is not set.
+	Port string
+
+	mux     *http.ServeMux
+	metrics *metrics
+	faults  *faults
+	checks  []func() error
+}
+
+// New returns a Service with the built-in endpoints registered. name is
+// the compose service name and defaultPort the port used without PORT.
+func New(name, defaultPort string) *Service {
+	s := &Service{Name: name, Port: defaultPort, metrics: newMetrics(), faults: &faults{}}
+	s.reset()
+	return s
+}
+
+// reset starts a mux holding only the built-in endpoints.
+func (s *Service) reset() {
+	s.mux = http.NewServeMux()
+	s.handle("/health", http.HandlerFunc(s.health))
+	s.handle("/ready", http.HandlerFunc(s.ready))
+	s.handle("/metrics", s.metrics)
+	s.handle(faultsPath, s.faults)
+	s.handle(faultsPath+"/", s.faults)
+}
+
+// Handle registers h for pattern, as http.ServeMux does. Requests are
+// counted in /metrics under the pattern, and the fault rules added through
+// /__admin/faults apply to them.
+func (s *Service) Handle(pattern string, h http.Handler) {
+	s.handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.faults.inject(w, r) {
+			h.ServeHTTP(w, r)
+		}
+	}))
+}
+
+// handle registers h for pattern without fault injection.
+func (s *Service) handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rec, ok := w.(*recorder); ok {
+			rec.route = pattern
+		}
+		h.ServeHTTP(w, r)
+	}))
+}
+
+// HandleFunc registers the handler function h for pattern, as Handle does.
+func (s *Service) HandleFunc(pattern string, h func(http.ResponseWriter, *http.Request)) {
+	s.Handle(pattern, http.HandlerFunc(h))
+}
+
+// Handler returns the handler Run serves, with the built-in endpoints, so
+// that tests can drive a mock through httptest.
+func (s *Service) Handler() http.Handler {
+	return s.observe(s.mux)
+}
+
+// Ready adds a check /ready runs. The mock is ready when every check
+// returns nil.
+func (s *Service) Ready(check func() error) {
+	s.checks = append(s.checks, check)
+}
+
+// Run serves on PORT until SIGINT or SIGTERM, then cancels the requests in
+// flight and waits for them to finish. MOCK_MODE=record or replay replaces
+// the routes of the mock with a recording proxy or a replay of the
+// recordings; see record.go.
+func (s *Service) Run() error {
+	port := Env("PORT", s.Port)
+	replayer, err := s.setMode()
+	if err != nil {
+		return err
+	}
+	if replayer != nil {
+		defer replayer.logReport()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{
+		Addr:        ":" + port,
+		Handler:     s.Handler(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("%s mock service starting on port %s", s.Name, port)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	log.Printf("%s shutting down", s.Name)
+	shutdown, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdown); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Service) health(w http.ResponseWriter, r *http.Request) {
+	if id := s.faults.unhealthy(time.Now()); id != "" {
+		WriteJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: "unhealthy", Service: s.Name, Error: "made unhealthy by mock rule " + id})
+		return
+	}
+	WriteJSON(w, http.StatusOK, HealthResponse{Status: "healthy", Service: s.Name})
+}
+
+func (s *Service) ready(w http.ResponseWriter, r *http.Request) {
+	if id := s.faults.unhealthy(time.Now()); id != "" {
+		WriteJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: "not_ready", Service: s.Name, Error: "made unhealthy by mock rule " + id})
+		return
+	}
+	for _, check := range s.checks {
+		if err := check(); err != nil {
+			WriteJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: "not_ready", Service: s.Name, Error: err.Error()})
+			return
+		}
+	}
+	WriteJSON(w, http.StatusOK, HealthResponse{Status: "ready", Service: s.Name})
+}
+
+// observe logs and measures every request. The log line carries the
+// tenant when the request has one, as requests through the gateway do.
+func (s *Service) observe(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		path := r.URL.Path
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+
+		s.metrics.observe(r.Method, rec.route, rec.status, elapsed)
+		if rec.route == "/health" || rec.route == "/ready" || rec.route == "/metrics" {
+			return
+		}
+		line := fmt.Sprintf("%s %s %d %s", r.Method, path, rec.status, elapsed.Round(time.Millisecond))
+		if tenant := r.Header.Get("X-Tenant-ID"); tenant != "" {
+			line += " tenant=" + tenant
+		}
+		if rec.fault != "" {
+			line += " fault=" + rec.fault
+		}
+		log.Print(line)
+	})
+}
+
+// recorder captures the status of a response and the route that served
+// it.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	route  string
+	// fault is the rule applied to the request, if any.
+	fault string
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap gives http.ResponseController the underlying writer.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush lets streaming handlers, such as server-sent events, flush
+// through the recorder.
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Env returns the value of the environment variable name, or def when it
+// is unset or empty.
+func Env(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+// LoadJSON reads the JSON fixture at path into v.
+func LoadJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
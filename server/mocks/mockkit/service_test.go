@@ -0,0 +1,197 @@
+package mockkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func decodeHealth(t *testing.T, w *httptest.ResponseRecorder) HealthResponse {
+	t.Helper()
+	var body HealthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return body
+}
+
+func TestHealthAndReadyChecks(t *testing.T) {
+	svc := New("example-service", "0")
+	var fixturesErr, brokerErr error
+	svc.Ready(func() error { return fixturesErr })
+	svc.Ready(func() error { return brokerErr })
+	h := svc.Handler()
+
+	tests := []struct {
+		name                string
+		fixtures, broker    error
+		wantStatus          int
+		wantReady, wantBody string
+	}{
+		{"every check passes", nil, nil, http.StatusOK, "ready", ""},
+		{"second check fails", nil, errors.New("broker unreachable"), http.StatusServiceUnavailable, "not_ready", "broker unreachable"},
+		{"first failure wins", errors.New("no fixtures"), errors.New("broker unreachable"), http.StatusServiceUnavailable, "not_ready", "no fixtures"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fixturesErr, brokerErr = tt.fixtures, tt.broker
+
+			w := serve(h, http.MethodGet, "/ready", "", "")
+			body := decodeHealth(t, w)
+			if w.Code != tt.wantStatus || body.Status != tt.wantReady || body.Error != tt.wantBody || body.Service != "example-service" {
+				t.Errorf("/ready = %d %+v, want %d %s %q", w.Code, body, tt.wantStatus, tt.wantReady, tt.wantBody)
+			}
+
+			// Readiness checks never make the service unhealthy.
+			w = serve(h, http.MethodGet, "/health", "", "")
+			if body := decodeHealth(t, w); w.Code != http.StatusOK || body.Status != "healthy" {
+				t.Errorf("/health = %d %+v, want 200 healthy", w.Code, body)
+			}
+		})
+	}
+}
+
+func TestHandleAndResetRouting(t *testing.T) {
+	svc := New("example-service", "0")
+	svc.HandleFunc("/api/v1/items", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, map[string]string{"ok": "yes"})
+	})
+	h := svc.Handler()
+
+	// Fault rules apply to the routes of the mock, not to the built-in
+	// endpoints registered with handle.
+	svc.faults.add(Fault{Status: http.StatusBadGateway}, time.Now())
+	if w := serve(h, http.MethodGet, "/api/v1/items", "", ""); w.Code != http.StatusBadGateway {
+		t.Errorf("route with a fault = %d, want 502", w.Code)
+	}
+	for _, path := range []string{"/health", "/ready", "/metrics", faultsPath} {
+		if w := serve(h, http.MethodGet, path, "", ""); w.Code != http.StatusOK {
+			t.Errorf("%s with a fault = %d, want 200", path, w.Code)
+		}
+	}
+	svc.faults.remove("")
+
+	// reset drops the routes of the mock and keeps the built-in endpoints.
+	svc.reset()
+	h = svc.Handler()
+	if w := serve(h, http.MethodGet, "/api/v1/items", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("route after reset = %d, want 404", w.Code)
+	}
+	for _, path := range []string{"/health", "/ready", "/metrics", faultsPath} {
+		if w := serve(h, http.MethodGet, path, "", ""); w.Code != http.StatusOK {
+			t.Errorf("%s after reset = %d, want 200", path, w.Code)
+		}
+	}
+	if w := serve(h, http.MethodGet, faultsPath+"/fault-1", "", ""); !strings.Contains(w.Body.String(), "fault not found") {
+		t.Errorf("%s/fault-1 after reset is not served by the fault API: %s", faultsPath, w.Body)
+	}
+}
+
+func TestRecorderCapturesStatus(t *testing.T) {
+	svc := New("example-service", "0")
+	svc.HandleFunc("/created", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	svc.HandleFunc("/implicit", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	svc.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: 1\n\n"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush() through the recorder: %v", err)
+		}
+	})
+	h := svc.Handler()
+
+	serve(h, http.MethodPost, "/created", "", "")
+	serve(h, http.MethodGet, "/implicit", "", "")
+	if w := serve(h, http.MethodGet, "/stream", "", ""); !w.Flushed {
+		t.Error("the stream was not flushed")
+	}
+
+	for route, status := range map[string]int{"/created": http.StatusCreated, "/implicit": http.StatusOK, "/stream": http.StatusOK} {
+		var k series
+		for s := range svc.metrics.requests {
+			if s.route == route {
+				k = s
+			}
+		}
+		if k.status != status {
+			t.Errorf("%s recorded with status %d, want %d", route, k.status, status)
+		}
+	}
+}
+
+func TestRunShutsDownGracefully(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	_, port, _ := net.SplitHostPort(addr)
+	t.Setenv("PORT", port)
+	t.Setenv("MOCK_MODE", "")
+
+	started := make(chan struct{})
+	finished := make(chan error, 1)
+	svc := New("example-service", "0")
+	svc.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		finished <- r.Context().Err()
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- svc.Run() }()
+
+	// Wait for the server, then start a request that is still in flight
+	// when the signal arrives.
+	go func() {
+		for i := 0; i < 100; i++ {
+			resp, err := http.Get("http://" + addr + "/slow")
+			if err == nil {
+				resp.Body.Close()
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no request reached the server on %s", addr)
+	}
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := self.Signal(syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-finished:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("request context error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request in flight was not cancelled")
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(shutdownTimeout):
+		t.Fatal("Run() did not return after SIGTERM")
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+const (
+	defaultWait = 10 * time.Second
+	maxWait     = 2 * time.Minute
+)
+
+// NotificationServer implements the send endpoints and the outbox API.
+type NotificationServer struct {
+	Outbox *Outbox
+	// From is the sender of emails that name none.
+	From string
+}
+
+// SendResponse acknowledges a queued notification.
+type SendResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+type emailRequest struct {
+	From    string   `json:"from"`
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	HTML    string   `json:"html"`
+}
+
+type smsRequest struct {
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+type pushRequest struct {
+	To    string            `json:"to"`
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data"`
+}
+
+func (s *NotificationServer) Register(svc *mockkit.Service) {
+	svc.HandleFunc("/api/v1/notifications/email", mockkit.Method(http.MethodPost, s.email))
+	svc.HandleFunc("/api/v1/notifications/sms", mockkit.Method(http.MethodPost, s.sms))
+	svc.HandleFunc("/api/v1/notifications/push", mockkit.Method(http.MethodPost, s.push))
+	svc.HandleFunc("/api/v1/outbox", s.outbox)
+	svc.HandleFunc("/api/v1/outbox/wait", s.wait)
+	svc.HandleFunc("/api/v1/outbox/", s.message)
+}
+
+func (s *NotificationServer) email(w http.ResponseWriter, r *http.Request) {
+	var req emailRequest
+	if !mockkit.Decode(w, r, &req) {
+		return
+	}
+	var problems []mockkit.FieldError
+	if len(req.To) == 0 {
+		problems = append(problems, mockkit.FieldError{Field: "to", Message: "needs at least one recipient"})
+	}
+	for _, to := range req.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			problems = append(problems, mockkit.FieldError{Field: "to", Message: to + " is not a valid email address"})
+		}
+	}
+	if strings.TrimSpace(req.Subject) == "" {
+		problems = append(problems, mockkit.FieldError{Field: "subject", Message: "is required"})
+	}
+	if req.Body == "" && req.HTML == "" {
+		problems = append(problems, mockkit.FieldError{Field: "body", Message: "body or html is required"})
+	}
+	if len(problems) > 0 {
+		mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the email is invalid", problems...)
+		return
+	}
+	s.accept(w, r, Message{
+		Channel: ChannelEmail,
+		From:    firstNonEmpty(req.From, s.From),
+		To:      req.To,
+		Subject: req.Subject,
+		Body:    req.Body,
+		HTML:    req.HTML,
+	})
+}
+
+func (s *NotificationServer) sms(w http.ResponseWriter, r *http.Request) {
+	var req smsRequest
+	if !mockkit.Decode(w, r, &req) {
+		return
+	}
+	var problems []mockkit.FieldError
+	if !validPhone(req.To) {
+		problems = append(problems, mockkit.FieldError{Field: "to", Message: "must be a phone number in E.164 format, e.g. +84901234567"})
+	}
+	if req.Body == "" {
+		problems = append(problems, mockkit.FieldError{Field: "body", Message: "is required"})
+	}
+	if len(problems) > 0 {
+		mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the SMS is invalid", problems...)
+		return
+	}
+	s.accept(w, r, Message{Channel: ChannelSMS, To: []string{req.To}, Body: req.Body})
+}
+
+func (s *NotificationServer) push(w http.ResponseWriter, r *http.Request) {
+	var req pushRequest
+	if !mockkit.Decode(w, r, &req) {
+		return
+	}
+	var problems []mockkit.FieldError
+	if req.To == "" {
+		problems = append(problems, mockkit.FieldError{Field: "to", Message: "a device token or user ID is required"})
+	}
+	if req.Title == "" && req.Body == "" {
+		problems = append(problems, mockkit.FieldError{Field: "body", Message: "title or body is required"})
+	}
+	if len(problems) > 0 {
+		mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the push notification is invalid", problems...)
+		return
+	}
+	s.accept(w, r, Message{Channel: ChannelPush, To: []string{req.To}, Subject: req.Title, Body: req.Body, Data: req.Data})
+}
+
+// accept puts m in the outbox. Nothing is delivered.
+func (s *NotificationServer) accept(w http.ResponseWriter, r *http.Request, m Message) {
+	m.Source = "api"
+	m.TenantID = r.Header.Get("X-Tenant-ID")
+	saved := s.Outbox.Add(m)
+	log.Printf("Captured %s %s to %s", saved.Channel, saved.ID, strings.Join(saved.To, ", "))
+	mockkit.WriteJSON(w, http.StatusAccepted, SendResponse{ID: saved.ID, Status: "queued"})
+}
+
+func (s *NotificationServer) outbox(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		mockkit.WriteJSON(w, http.StatusOK, map[string]any{"data": s.Outbox.List(filter(r))})
+	case http.MethodDelete:
+		n := s.Outbox.Clear()
+		log.Printf("Cleared %d messages from the outbox", n)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		mockkit.MethodNotAllowed(w, r, "GET, DELETE")
+	}
+}
+
+func (s *NotificationServer) message(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		mockkit.MethodNotAllowed(w, r, "GET")
+		return
+	}
+	m, err := s.Outbox.Get(strings.TrimPrefix(r.URL.Path, "/api/v1/outbox/"))
+	if err != nil {
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", err.Error())
+		return
+	}
+	mockkit.WriteJSON(w, http.StatusOK, m)
+}
+
+// wait answers with the first message matching the query as soon as there
+// is one, or 408 once ?timeout= (default 10s) has passed.
+func (s *NotificationServer) wait(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		mockkit.MethodNotAllowed(w, r, "GET")
+		return
+	}
+	timeout := defaultWait
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxWait {
+			mockkit.WriteError(w, http.StatusBadRequest, "validation_failed", "invalid query parameters", []mockkit.FieldError{{Field: "timeout", Message: "must be a duration up to " + maxWait.String() + ", e.g. 30s"}}...)
+			return
+		}
+		timeout = d
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	m, err := s.Outbox.Wait(ctx, filter(r))
+	if errors.Is(err, context.DeadlineExceeded) {
+		mockkit.WriteError(w, http.StatusRequestTimeout, "timeout", "no matching message within "+timeout.String())
+		return
+	}
+	if err != nil {
+		// The client went away.
+		return
+	}
+	mockkit.WriteJSON(w, http.StatusOK, m)
+}
+
+// filter reads a Filter from the channel, tenant_id, to, subject and body
+// query parameters.
+func filter(r *http.Request) Filter {
+	q := r.URL.Query()
+	return Filter{
+		Channel:  q.Get("channel"),
+		TenantID: q.Get("tenant_id"),
+		To:       q.Get("to"),
+		Subject:  q.Get("subject"),
+		Body:     q.Get("body"),
+	}
+}
+
+func validPhone(s string) bool {
+	if len(s) < 8 || len(s) > 16 || s[0] != '+' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
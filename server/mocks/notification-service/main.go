@@ -1,36 +1,32 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
-	"net/http"
 	"os"
-)
-
-type HealthResponse struct {
-	Status  string `json:"status"`
-	Service string `json:"service"`
-}
+	"strconv"
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(HealthResponse{
-		Status:  "healthy",
-		Service: "notification-service",
-	})
-}
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8084"
+	svc := mockkit.New("notification-service", "8084")
+
+	limit, err := strconv.Atoi(mockkit.Env("OUTBOX_LIMIT", "1000"))
+	if err != nil {
+		log.Fatalf("Invalid OUTBOX_LIMIT: %v", err)
 	}
+	outbox := NewOutbox(limit)
+
+	smtpPort := mockkit.Env("SMTP_LISTEN_PORT", "1025")
+	hostname, _ := os.Hostname()
+	sink := &SMTPSink{Outbox: outbox, Hostname: hostname}
+	go func() {
+		log.Printf("SMTP sink listening on port %s", smtpPort)
+		log.Fatal(sink.ListenAndServe(":" + smtpPort))
+	}()
 
-	http.HandleFunc("/health", healthHandler)
-	
-	log.Printf("Notification Service mock service starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	(&NotificationServer{Outbox: outbox, From: mockkit.Env("SMTP_FROM", "noreply@example.com")}).Register(svc)
+	if err := svc.Run(); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// Channels a notification can be sent on.
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+)
+
+// Message is a captured notification. Messages sent through the API and
+// mail received by the SMTP sink end up in the same outbox.
+type Message struct {
+	ID        string            `json:"id"`
+	Channel   string            `json:"channel"`
+	Source    string            `json:"source"`
+	TenantID  string            `json:"tenant_id,omitempty"`
+	From      string            `json:"from,omitempty"`
+	To        []string          `json:"to"`
+	Subject   string            `json:"subject,omitempty"`
+	Body      string            `json:"body"`
+	HTML      string            `json:"html,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Filter selects messages. Empty fields match anything; To matches any
+// recipient, ignoring case, and Subject and Body match substrings.
+type Filter struct {
+	Channel  string
+	TenantID string
+	To       string
+	Subject  string
+	Body     string
+}
+
+func (f Filter) Match(m *Message) bool {
+	if f.Channel != "" && f.Channel != m.Channel {
+		return false
+	}
+	if f.TenantID != "" && f.TenantID != m.TenantID {
+		return false
+	}
+	if f.Subject != "" && !strings.Contains(m.Subject, f.Subject) {
+		return false
+	}
+	if f.Body != "" && !strings.Contains(m.Body, f.Body) && !strings.Contains(m.HTML, f.Body) {
+		return false
+	}
+	if f.To == "" {
+		return true
+	}
+	for _, to := range m.To {
+		if strings.EqualFold(to, f.To) {
+			return true
+		}
+	}
+	return false
+}
+
+var errNotFound = errors.New("message not found")
+
+// Outbox keeps the latest Limit messages in memory, oldest first.
+type Outbox struct {
+	Limit int
+
+	mu       sync.Mutex
+	messages []*Message
+	// added is closed and replaced whenever a message is added, to wake
+	// up the waiters.
+	added chan struct{}
+}
+
+func NewOutbox(limit int) *Outbox {
+	return &Outbox{Limit: limit, added: make(chan struct{})}
+}
+
+// Add stores m, setting its ID and time.
+func (o *Outbox) Add(m Message) *Message {
+	m.ID = "msg-" + mockkit.RandomID(6)
+	m.CreatedAt = time.Now().UTC()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, &m)
+	if o.Limit > 0 && len(o.messages) > o.Limit {
+		o.messages = o.messages[len(o.messages)-o.Limit:]
+	}
+	close(o.added)
+	o.added = make(chan struct{})
+	return &m
+}
+
+func (o *Outbox) List(f Filter) []*Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := []*Message{}
+	for _, m := range o.messages {
+		if f.Match(m) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (o *Outbox) Get(id string) (*Message, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, m := range o.messages {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+// Clear empties the outbox and returns how many messages it held.
+func (o *Outbox) Clear() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	n := len(o.messages)
+	o.messages = nil
+	return n
+}
+
+// Wait returns the oldest message matching f, waiting for one to arrive
+// until ctx is done.
+func (o *Outbox) Wait(ctx context.Context, f Filter) (*Message, error) {
+	for {
+		o.mu.Lock()
+		for _, m := range o.messages {
+			if f.Match(m) {
+				o.mu.Unlock()
+				return m, nil
+			}
+		}
+		added := o.added
+		o.mu.Unlock()
+
+		select {
+		case <-added:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
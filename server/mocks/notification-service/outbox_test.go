@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+func TestOutboxWaitWakesOnAdd(t *testing.T) {
+	o := NewOutbox(10)
+	o.Add(Message{Channel: ChannelSMS, To: []string{"+84900000000"}, Body: "code 1234"})
+
+	got := make(chan *Message)
+	go func() {
+		m, err := o.Wait(context.Background(), Filter{Channel: ChannelEmail, To: "ANN@example.com"})
+		if err != nil {
+			t.Error(err)
+		}
+		got <- m
+	}()
+
+	// A message that does not match leaves the waiter waiting.
+	time.Sleep(10 * time.Millisecond)
+	o.Add(Message{Channel: ChannelEmail, To: []string{"bob@example.com"}, Subject: "not this one"})
+	select {
+	case m := <-got:
+		t.Fatalf("Wait() returned %q for another recipient", m.Subject)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	o.Add(Message{Channel: ChannelEmail, To: []string{"ann@example.com"}, Subject: "welcome"})
+	select {
+	case m := <-got:
+		if m.Subject != "welcome" {
+			t.Errorf("Wait() = %q, want welcome", m.Subject)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait() did not wake up on Add")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := o.Wait(ctx, Filter{Subject: "never"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWaitEndpoint(t *testing.T) {
+	o := NewOutbox(10)
+	svc := mockkit.New("notification-service", "0")
+	(&NotificationServer{Outbox: o}).Register(svc)
+	h := svc.Handler()
+
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+
+	if w := get("/api/v1/outbox/wait?subject=reset&timeout=20ms"); w.Code != http.StatusRequestTimeout {
+		t.Errorf("wait without a message = %d %s, want 408", w.Code, w.Body)
+	}
+	for _, timeout := range []string{"soon", "0s", "-1s", "3m"} {
+		if w := get("/api/v1/outbox/wait?timeout=" + timeout); w.Code != http.StatusBadRequest {
+			t.Errorf("timeout=%s = %d, want 400", timeout, w.Code)
+		}
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- get("/api/v1/outbox/wait?subject=reset&timeout=5s") }()
+	time.Sleep(10 * time.Millisecond)
+	o.Add(Message{Channel: ChannelEmail, To: []string{"ann@example.com"}, Subject: "Password reset"})
+	select {
+	case w := <-done:
+		if w.Code != http.StatusOK {
+			t.Errorf("wait = %d %s, want 200", w.Code, w.Body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait did not answer after the message arrived")
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// maxMessageSize bounds the DATA of one mail.
+const maxMessageSize = 10 << 20
+
+// SMTPSink is an SMTP server that accepts every mail and puts it in the
+// outbox instead of delivering it. It accepts any AUTH credentials and
+// offers no STARTTLS.
+type SMTPSink struct {
+	Outbox   *Outbox
+	Hostname string
+}
+
+func (s *SMTPSink) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serve(conn)
+	}
+}
+
+type smtpSession struct {
+	mail bool
+	from string
+	to   []string
+}
+
+func (s *SMTPSink) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	reply := func(format string, args ...any) {
+		fmt.Fprintf(w, format+"\r\n", args...)
+		w.Flush()
+	}
+
+	reply("220 %s ESMTP notification-service mock", s.Hostname)
+	var sess smtpSession
+	for {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			reply("250 %s", s.Hostname)
+		case "EHLO":
+			fmt.Fprintf(w, "250-%s\r\n250-SIZE %d\r\n250-8BITMIME\r\n250 AUTH PLAIN LOGIN\r\n", s.Hostname, maxMessageSize)
+			w.Flush()
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			switch strings.ToUpper(mech) {
+			case "PLAIN":
+				if initial == "" {
+					reply("334 ")
+					r.ReadString('\n')
+				}
+			case "LOGIN":
+				reply("334 VXNlcm5hbWU6")
+				r.ReadString('\n')
+				reply("334 UGFzc3dvcmQ6")
+				r.ReadString('\n')
+			default:
+				reply("504 unrecognized authentication type")
+				continue
+			}
+			reply("235 authentication succeeded")
+		case "MAIL":
+			sess = smtpSession{mail: true, from: address(arg, "FROM:")}
+			reply("250 OK")
+		case "RCPT":
+			if !sess.mail {
+				reply("503 need MAIL first")
+				continue
+			}
+			sess.to = append(sess.to, address(arg, "TO:"))
+			reply("250 OK")
+		case "DATA":
+			if len(sess.to) == 0 {
+				reply("503 need RCPT first")
+				continue
+			}
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := readData(r)
+			if err != nil {
+				reply("552 %v", err)
+				return
+			}
+			m := parseMail(data)
+			m.From = firstNonEmpty(m.From, sess.from)
+			m.To = sess.to
+			saved := s.Outbox.Add(m)
+			log.Printf("Captured email %s to %s: %q", saved.ID, strings.Join(saved.To, ", "), saved.Subject)
+			reply("250 OK queued as %s", saved.ID)
+			sess = smtpSession{}
+		case "RSET":
+			sess = smtpSession{}
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "VRFY":
+			reply("252 cannot verify user")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// address extracts the address of a MAIL FROM or RCPT TO argument.
+func address(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	arg = strings.TrimSpace(arg)
+	if i := strings.Index(arg, ">"); strings.HasPrefix(arg, "<") && i > 0 {
+		arg = arg[1:i]
+	}
+	return arg
+}
+
+// readData reads a DATA section up to the lone dot, undoing dot-stuffing.
+func readData(r *bufio.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimRight(line, "\r\n") == "." {
+			return buf.Bytes(), nil
+		}
+		line = strings.TrimPrefix(line, ".")
+		if buf.Len()+len(line) > maxMessageSize {
+			return nil, fmt.Errorf("message exceeds %d bytes", maxMessageSize)
+		}
+		buf.WriteString(line)
+	}
+}
+
+// parseMail reads the sender, subject and text and HTML bodies of a mail.
+// Mail that does not parse is kept whole as the body.
+func parseMail(data []byte) Message {
+	m := Message{Channel: ChannelEmail, Source: "smtp"}
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		m.Body = string(data)
+		return m
+	}
+	dec := new(mime.WordDecoder)
+	m.Subject, err = dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		m.Subject = msg.Header.Get("Subject")
+	}
+	if from, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		m.From = from.Address
+	}
+	readPart(&m, msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	return m
+}
+
+// readPart fills the text and HTML bodies of m from a MIME part,
+// descending into multipart parts.
+func readPart(m *Message, contentType, encoding string, body io.Reader) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err != nil {
+				return
+			}
+			readPart(m, p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p)
+		}
+	}
+
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	b, _ := io.ReadAll(body)
+	switch {
+	case mediaType == "text/plain" && m.Body == "":
+		m.Body = string(b)
+	case mediaType == "text/html" && m.HTML == "":
+		m.HTML = string(b)
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
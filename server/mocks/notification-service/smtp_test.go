@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestSMTPSession(t *testing.T) {
+	outbox := NewOutbox(10)
+	server, client := net.Pipe()
+	go (&SMTPSink{Outbox: outbox, Hostname: "mock.local"}).serve(server)
+	c := textproto.NewConn(client)
+	defer c.Close()
+
+	expect := func(code int, format string, args ...any) {
+		t.Helper()
+		if format != "" {
+			if err := c.PrintfLine(format, args...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, msg, err := c.ReadResponse(code); err != nil {
+			t.Fatalf("%q: %v %s", format, err, msg)
+		}
+	}
+	expect(220, "")
+	expect(250, "EHLO client.local")
+	expect(334, "AUTH LOGIN")
+	expect(334, "dXNlcg==")
+	expect(235, "cGFzcw==")
+	expect(503, "RCPT TO:<ann@example.com>")
+	expect(250, "MAIL FROM:<bounce@example.com> SIZE=1000")
+	expect(503, "DATA")
+	expect(250, "RCPT TO:<ann@example.com>")
+	expect(250, "rcpt to: bob@example.com")
+	expect(354, "DATA")
+
+	// The text part is quoted-printable and holds dot-stuffed lines.
+	mail := strings.Join([]string{
+		"From: Platform <noreply@example.com>",
+		"Subject: =?UTF-8?Q?Ch=C3=A0o?= Ann",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/alternative; boundary="b1"`,
+		"",
+		"--b1",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"Hello Ann,=",
+		" welcome =E2=9C=93",
+		"..",
+		"..signature",
+		"--b1",
+		"Content-Type: text/html; charset=utf-8",
+		"Content-Transfer-Encoding: base64",
+		"",
+		"PHA+SGVsbG8gQW5uPC9wPg==",
+		"--b1--",
+		".",
+	}, "\r\n")
+	if err := c.PrintfLine("%s", mail); err != nil {
+		t.Fatal(err)
+	}
+	expect(250, "")
+	expect(221, "QUIT")
+
+	msgs := outbox.List(Filter{})
+	if len(msgs) != 1 {
+		t.Fatalf("outbox holds %d messages, want 1", len(msgs))
+	}
+	m := msgs[0]
+	if m.From != "noreply@example.com" || strings.Join(m.To, ",") != "ann@example.com,bob@example.com" {
+		t.Errorf("from %q to %v", m.From, m.To)
+	}
+	if m.Subject != "Chào Ann" {
+		t.Errorf("subject = %q", m.Subject)
+	}
+	if want := "Hello Ann, welcome ✓\r\n.\r\n.signature"; m.Body != want {
+		t.Errorf("body = %q, want %q", m.Body, want)
+	}
+	if m.HTML != "<p>Hello Ann</p>" {
+		t.Errorf("html = %q", m.HTML)
+	}
+	if m.Source != "smtp" || m.Channel != ChannelEmail {
+		t.Errorf("source %q channel %q", m.Source, m.Channel)
+	}
+}
+
+func TestReadPart(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		body        string
+		wantBody    string
+		wantHTML    string
+	}{
+		{"plain", "text/plain", "", "hello", "hello", ""},
+		{"no content type", "", "", "hello", "hello", ""},
+		{"quoted-printable", "text/plain; charset=utf-8", "Quoted-Printable", "caf=C3=A9 =3D soft=\r\nbreak", "café = softbreak", ""},
+		{"html only", "text/html", "base64", "PGI+aGk8L2I+", "", "<b>hi</b>"},
+		{
+			"nested multipart keeps the first text part",
+			`multipart/mixed; boundary="outer"`, "",
+			"--outer\r\nContent-Type: multipart/alternative; boundary=inner\r\n\r\n" +
+				"--inner\r\nContent-Type: text/plain\r\n\r\nfirst\r\n" +
+				"--inner\r\nContent-Type: text/html\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n<p>a=3Db</p>\r\n" +
+				"--inner--\r\n" +
+				"--outer\r\nContent-Type: text/plain\r\n\r\nsecond\r\n" +
+				"--outer--\r\n",
+			"first", "<p>a=b</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Message
+			readPart(&m, tt.contentType, tt.encoding, strings.NewReader(tt.body))
+			if m.Body != tt.wantBody || m.HTML != tt.wantHTML {
+				t.Errorf("body %q html %q, want %q and %q", m.Body, m.HTML, tt.wantBody, tt.wantHTML)
+			}
+		})
+	}
+}
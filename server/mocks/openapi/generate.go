@@ -0,0 +1,221 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxDepth stops generation of recursive schemas.
+const maxDepth = 6
+
+// Preference is what a client asked for with a Prefer header such as
+// "Prefer: code=404, example=expired".
+type Preference struct {
+	Code    string
+	Example string
+}
+
+func ParsePreference(header string) Preference {
+	var p Preference
+	for _, part := range strings.FieldsFunc(header, func(r rune) bool { return r == ',' || r == ';' }) {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "code":
+			p.Code = value
+		case "example":
+			p.Example = value
+		}
+	}
+	return p
+}
+
+// SelectResponse picks the response of an operation: the preferred status
+// if the spec has it, else the lowest 2xx, else the default response.
+func SelectResponse(op *Operation, prefer string) (int, string, bool) {
+	if prefer != "" {
+		if _, ok := op.Responses[prefer]; ok {
+			return statusOf(prefer), prefer, true
+		}
+		return 0, "", false
+	}
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			return statusOf(code), code, true
+		}
+	}
+	if _, ok := op.Responses["default"]; ok {
+		return 200, "default", true
+	}
+	if len(codes) > 0 {
+		return statusOf(codes[0]), codes[0], true
+	}
+	return 204, "", true
+}
+
+// statusOf turns a response key into a status, "2XX" meaning 200.
+func statusOf(code string) int {
+	if n, err := strconv.Atoi(code); err == nil {
+		return n
+	}
+	if len(code) == 3 && code[0] >= '1' && code[0] <= '5' {
+		return int(code[0]-'0') * 100
+	}
+	return 200
+}
+
+// ResponseBody returns the body of a response: the named example, the
+// media type's example, its first named example, the schema's example, or
+// a value generated from the schema, in that order. ok is false when the
+// named example does not exist.
+func ResponseBody(spec *Spec, media *MediaType, example string) (any, bool) {
+	if example != "" {
+		e := spec.example(media.Examples[example])
+		if e == nil {
+			return nil, false
+		}
+		return e.Value, true
+	}
+	if media.Example != nil {
+		return media.Example, true
+	}
+	if len(media.Examples) > 0 {
+		names := make([]string, 0, len(media.Examples))
+		for name := range media.Examples {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if e := spec.example(media.Examples[names[0]]); e != nil {
+			return e.Value, true
+		}
+	}
+	return Generate(spec, media.Schema, 0), true
+}
+
+// Generate builds a value that satisfies schema, preferring the examples
+// and defaults the spec gives.
+func Generate(spec *Spec, schema *Schema, depth int) any {
+	schema = spec.schema(schema)
+	if schema == nil || depth > maxDepth {
+		return nil
+	}
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if schema.Default != nil {
+		return schema.Default
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	if len(schema.AllOf) > 0 {
+		merged := map[string]any{}
+		for _, sub := range schema.AllOf {
+			if obj, ok := Generate(spec, sub, depth+1).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		for k, v := range generateObject(spec, schema, depth) {
+			merged[k] = v
+		}
+		return merged
+	}
+	if len(schema.OneOf) > 0 {
+		return Generate(spec, schema.OneOf[0], depth+1)
+	}
+	if len(schema.AnyOf) > 0 {
+		return Generate(spec, schema.AnyOf[0], depth+1)
+	}
+
+	types := schema.types()
+	if len(types) == 0 {
+		if schema.nullable() {
+			return nil
+		}
+		return map[string]any{}
+	}
+	switch types[0] {
+	case "object":
+		return generateObject(spec, schema, depth)
+	case "array":
+		n := 1
+		if schema.MinItems != nil && *schema.MinItems > n {
+			n = *schema.MinItems
+		}
+		if schema.MaxItems != nil && *schema.MaxItems < n {
+			n = *schema.MaxItems
+		}
+		items := make([]any, 0, n)
+		for i := 0; i < n && depth < maxDepth; i++ {
+			items = append(items, Generate(spec, schema.Items, depth+1))
+		}
+		return items
+	case "string":
+		return generateString(schema)
+	case "integer":
+		if schema.Minimum != nil {
+			return int(*schema.Minimum)
+		}
+		return 0
+	case "number":
+		if schema.Minimum != nil {
+			return *schema.Minimum
+		}
+		return 0
+	case "boolean":
+		return true
+	}
+	return nil
+}
+
+// generateObject fills every property of schema that a response carries.
+func generateObject(spec *Spec, schema *Schema, depth int) map[string]any {
+	obj := map[string]any{}
+	for name, prop := range schema.Properties {
+		if p := spec.schema(prop); p != nil && p.WriteOnly {
+			continue
+		}
+		obj[name] = Generate(spec, prop, depth+1)
+	}
+	return obj
+}
+
+func generateString(schema *Schema) string {
+	var s string
+	switch schema.Format {
+	case "email":
+		s = "user@example.com"
+	case "uuid":
+		s = "00000000-0000-4000-8000-000000000000"
+	case "date-time":
+		s = "2024-01-01T00:00:00Z"
+	case "date":
+		s = "2024-01-01"
+	case "uri", "url":
+		s = "https://example.com"
+	case "hostname":
+		s = "example.com"
+	default:
+		s = "string"
+	}
+	if schema.MinLength != nil {
+		for len(s) < *schema.MinLength {
+			s += "x"
+		}
+	}
+	if schema.MaxLength != nil && len(s) > *schema.MaxLength {
+		s = s[:*schema.MaxLength]
+	}
+	return s
+}
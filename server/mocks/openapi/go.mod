@@ -0,0 +1,10 @@
+module github.com/vhvplatform/go-framework/mocks/openapi
+
+go 1.21
+
+require (
+	github.com/vhvplatform/go-framework/mocks/mockkit v0.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace github.com/vhvplatform/go-framework/mocks/mockkit => ../mockkit
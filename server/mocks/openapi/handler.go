@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// Handler serves the operations of a spec: it validates each request,
+// then answers with an override if one matches, else with a response
+// built from the spec.
+type Handler struct {
+	Spec      *Spec
+	Router    *Router
+	Overrides *Overrides
+	Validate  bool
+}
+
+func NewHandler(spec *Spec, overridesDir string, validate bool) *Handler {
+	return &Handler{
+		Spec:      spec,
+		Router:    NewRouter(spec),
+		Overrides: &Overrides{Dir: overridesDir},
+		Validate:  validate,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt, pathParams, allowed := h.Router.Match(r.Method, r.URL.Path)
+	if rt == nil {
+		if len(allowed) > 0 {
+			mockkit.MethodNotAllowed(w, r, strings.Join(allowed, ", "))
+			return
+		}
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", "no operation matches "+r.Method+" "+r.URL.Path)
+		return
+	}
+
+	if h.Validate {
+		if _, problems := ValidateRequest(h.Spec, rt, pathParams, r); len(problems) > 0 {
+			mockkit.WriteError(w, http.StatusBadRequest, "validation_failed", "request does not match the API specification", problems...)
+			return
+		}
+	}
+
+	override, err := h.Overrides.Find(rt.op.OperationID, r, pathParams)
+	if err != nil {
+		log.Printf("Ignoring override: %v", err)
+	}
+	if override != nil {
+		override.Write(w)
+		return
+	}
+
+	prefer := ParsePreference(r.Header.Get("Prefer"))
+	status, key, ok := SelectResponse(rt.op, prefer.Code)
+	if !ok {
+		mockkit.WriteError(w, http.StatusBadRequest, "unknown_response",
+			"operation "+rt.op.OperationID+" has no "+prefer.Code+" response")
+		return
+	}
+	resp := h.Spec.response(rt.op.Responses[key])
+	if resp == nil || len(resp.Content) == 0 {
+		w.WriteHeader(status)
+		return
+	}
+	contentType := "application/json"
+	media := jsonMedia(resp.Content)
+	if media == nil {
+		for ct, m := range resp.Content {
+			contentType, media = ct, m
+			break
+		}
+	}
+	body, ok := ResponseBody(h.Spec, media, prefer.Example)
+	if !ok {
+		mockkit.WriteError(w, http.StatusBadRequest, "unknown_example",
+			"the "+key+" response of "+rt.op.OperationID+" has no example "+prefer.Example)
+		return
+	}
+	if !isJSON(contentType) {
+		if s, ok := body.(string); ok {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(status)
+			w.Write([]byte(s))
+			return
+		}
+		contentType = "application/json"
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		mockkit.WriteError(w, http.StatusInternalServerError, "internal_error", "could not encode the example: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+func main() {
+	svc := mockkit.New(mockkit.Env("SERVICE_NAME", "openapi-mock"), "8080")
+
+	path := mockkit.Env("OPENAPI_SPEC", "/specs/openapi.yaml")
+	spec, err := LoadSpec(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	validate, err := strconv.ParseBool(mockkit.Env("VALIDATE_REQUESTS", "true"))
+	if err != nil {
+		log.Fatalf("VALIDATE_REQUESTS: %v", err)
+	}
+
+	h := NewHandler(spec, mockkit.Env("OVERRIDES_DIR", ""), validate)
+	log.Printf("Loaded %d operations from %s (%s %s)", len(h.Router.Routes()), path, spec.Info.Title, spec.Info.Version)
+	for _, r := range h.Router.Routes() {
+		log.Printf("  %s", r)
+	}
+
+	svc.Handle("/", h)
+	if err := svc.Run(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Override is a canned response for an operation, read from
+// <OVERRIDES_DIR>/<operationId>.json. The file holds one override or a
+// list of them; the first whose When matches the request wins.
+type Override struct {
+	When    *Condition        `json:"when,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Condition matches path parameters, query parameters and headers
+// against patterns as path.Match reads them, so "acme.localhost*" also
+// matches the domain with a port. An empty condition matches every
+// request.
+type Condition struct {
+	Path    map[string]string `json:"path,omitempty"`
+	Query   map[string]string `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (c *Condition) Match(r *http.Request, pathParams map[string]string) bool {
+	if c == nil {
+		return true
+	}
+	for k, v := range c.Path {
+		if !matches(v, pathParams[k]) {
+			return false
+		}
+	}
+	query := r.URL.Query()
+	for k, v := range c.Query {
+		if !matches(v, query.Get(k)) {
+			return false
+		}
+	}
+	for k, v := range c.Headers {
+		if !matches(v, r.Header.Get(k)) {
+			return false
+		}
+	}
+	return true
+}
+
+func matches(pattern, value string) bool {
+	ok, err := path.Match(pattern, value)
+	return ok || err != nil && pattern == value
+}
+
+// Overrides reads override files from a directory. Files are read on
+// every request, so a test can change them while the mock runs.
+type Overrides struct {
+	Dir string
+}
+
+// Find returns the override for a request, or nil when there is none.
+func (o *Overrides) Find(operationID string, r *http.Request, pathParams map[string]string) (*Override, error) {
+	if o.Dir == "" || operationID == "" || strings.ContainsAny(operationID, `/\`) {
+		return nil, nil
+	}
+	file := filepath.Join(o.Dir, operationID+".json")
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Override
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(data, &list)
+	} else {
+		var one Override
+		err = json.Unmarshal(data, &one)
+		list = []Override{one}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", file, err)
+	}
+	for i := range list {
+		if list[i].When.Match(r, pathParams) {
+			return &list[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (o *Override) Write(w http.ResponseWriter) {
+	for k, v := range o.Headers {
+		w.Header().Set(k, v)
+	}
+	status := o.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if len(o.Body) == 0 {
+		w.WriteHeader(status)
+		return
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	var body bytes.Buffer
+	if json.Compact(&body, o.Body) != nil {
+		body.Reset()
+		body.Write(o.Body)
+	}
+	body.WriteByte('\n')
+	w.WriteHeader(status)
+	w.Write(body.Bytes())
+}
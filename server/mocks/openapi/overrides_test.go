@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverridesFind(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"getUser.json": `[
+			{"when": {"path": {"id": "missing-*"}}, "status": 404, "body": {"error": {"code": "not_found", "message": "user not found"}}},
+			{"when": {"query": {"expand": "roles"}, "headers": {"X-Tenant-ID": "tenant-1"}}, "status": 200, "body": {"id": "with-roles"}},
+			{"when": {"headers": {"Host": "acme.localhost*"}}, "status": 200, "body": {"id": "acme"}}
+		]`,
+		"listUsers.json":  `{"status": 503, "headers": {"Retry-After": "1"}}`,
+		"createUser.json": `{"status": `,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o := &Overrides{Dir: dir}
+
+	tests := []struct {
+		name       string
+		op         string
+		target     string
+		headers    map[string]string
+		params     map[string]string
+		wantStatus int // 0 for no override
+		wantErr    bool
+	}{
+		{"single override", "listUsers", "/users", nil, nil, 503, false},
+		{"path pattern", "getUser", "/users/missing-1", nil, map[string]string{"id": "missing-1"}, 404, false},
+		{"query and header", "getUser", "/users/1?expand=roles", map[string]string{"X-Tenant-ID": "tenant-1"}, map[string]string{"id": "1"}, 200, false},
+		{"header does not match", "getUser", "/users/1?expand=roles", map[string]string{"X-Tenant-ID": "tenant-2"}, map[string]string{"id": "1"}, 0, false},
+		{"no file", "deleteUser", "/users/1", nil, nil, 0, false},
+		{"no operation ID", "", "/users", nil, nil, 0, false},
+		{"path traversal", "../getUser", "/users", nil, nil, 0, false},
+		{"broken file", "createUser", "/users", nil, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			got, err := o.Find(tt.op, r, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Find() error = %v, want error %v", err, tt.wantErr)
+			}
+			status := 0
+			if got != nil {
+				status = got.Status
+			}
+			if status != tt.wantStatus {
+				t.Errorf("Find() status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+
+	// Patterns are matched against the Host header with its port.
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set("Host", "acme.localhost:8080")
+	if got, _ := o.Find("getUser", r, map[string]string{"id": "1"}); got == nil || string(got.Body) != `{"id": "acme"}` {
+		t.Errorf("Find() by host = %+v", got)
+	}
+
+	// Files are read on every request.
+	if err := os.WriteFile(filepath.Join(dir, "listUsers.json"), []byte(`{"status": 429}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := o.Find("listUsers", httptest.NewRequest(http.MethodGet, "/users", nil), nil); got == nil || got.Status != 429 {
+		t.Errorf("Find() after the file changed = %+v, want 429", got)
+	}
+}
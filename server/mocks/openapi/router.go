@@ -0,0 +1,140 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// route is an operation of the spec with its path template split into
+// segments, "{id}" segments matching any value.
+type route struct {
+	method   string
+	template string
+	segments []string
+	op       *Operation
+	params   []*Parameter
+}
+
+// literals counts the fixed segments, so that /users/me is tried before
+// /users/{id}.
+func (r *route) literals() int {
+	n := 0
+	for _, s := range r.segments {
+		if !isParam(s) {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *route) match(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, s := range r.segments {
+		if isParam(s) {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[s[1:len(s)-1]] = segments[i]
+		} else if s != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// Router finds the operation serving a request.
+type Router struct {
+	base   string
+	routes []*route
+}
+
+func NewRouter(spec *Spec) *Router {
+	r := &Router{base: spec.BasePath()}
+	for template, item := range spec.Paths {
+		for method, op := range item.operations() {
+			rt := &route{
+				method:   method,
+				template: template,
+				segments: split(template),
+				op:       op,
+			}
+			// Operation parameters override the path item's of the same
+			// name and location.
+			seen := map[string]bool{}
+			for _, p := range op.Parameters {
+				p = spec.parameter(p)
+				seen[p.In+":"+p.Name] = true
+				rt.params = append(rt.params, p)
+			}
+			for _, p := range item.Parameters {
+				if p = spec.parameter(p); !seen[p.In+":"+p.Name] {
+					rt.params = append(rt.params, p)
+				}
+			}
+			r.routes = append(r.routes, rt)
+		}
+	}
+	sort.Slice(r.routes, func(i, j int) bool {
+		a, b := r.routes[i], r.routes[j]
+		if a.literals() != b.literals() {
+			return a.literals() > b.literals()
+		}
+		if a.template != b.template {
+			return a.template < b.template
+		}
+		return a.method < b.method
+	})
+	return r
+}
+
+// Match returns the route and path parameters of a request. When the path
+// matches but the method does not, it returns the allowed methods instead.
+func (r *Router) Match(method, path string) (*route, map[string]string, []string) {
+	rest, ok := strings.CutPrefix(path, r.base)
+	if !ok || rest != "" && !strings.HasPrefix(rest, "/") {
+		return nil, nil, nil
+	}
+	segments := split(rest)
+	var allowed []string
+	var found *route
+	var foundParams map[string]string
+	for _, rt := range r.routes {
+		params, ok := rt.match(segments)
+		if !ok {
+			continue
+		}
+		if rt.method == method {
+			if found == nil {
+				found, foundParams = rt, params
+			}
+			continue
+		}
+		allowed = append(allowed, rt.method)
+	}
+	if found != nil {
+		return found, foundParams, nil
+	}
+	sort.Strings(allowed)
+	return nil, nil, allowed
+}
+
+func split(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// Routes lists the operations as "METHOD template", for the startup log.
+func (r *Router) Routes() []string {
+	var out []string
+	for _, rt := range r.routes {
+		out = append(out, rt.method+" "+r.base+rt.template)
+	}
+	sort.Strings(out)
+	return out
+}
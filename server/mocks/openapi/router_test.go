@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRouterMatch(t *testing.T) {
+	router := NewRouter(parseSpec(t, testSpec))
+
+	tests := []struct {
+		method      string
+		path        string
+		wantOp      string
+		wantParams  map[string]string
+		wantAllowed []string
+	}{
+		{"GET", "/api/v1/users", "listUsers", map[string]string{}, nil},
+		{"GET", "/api/v1/users/", "listUsers", map[string]string{}, nil},
+		{"GET", "/api/v1/users/me", "getCurrentUser", map[string]string{}, nil},
+		{"GET", "/api/v1/users/42", "getUser", map[string]string{"id": "42"}, nil},
+		{"PUT", "/api/v1/users/42/roles/admin", "addRole", map[string]string{"id": "42", "role": "admin"}, nil},
+		// /users/me only has GET, but {id} matches it for other methods.
+		{"DELETE", "/api/v1/users/me", "deleteUser", map[string]string{"id": "me"}, nil},
+		{"PUT", "/api/v1/users/42", "", nil, []string{"DELETE", "GET", "PATCH"}},
+		{"DELETE", "/api/v1/users", "", nil, []string{"GET", "POST"}},
+		{"GET", "/api/v1/users/42/roles", "", nil, nil},
+		// Parameters never match an empty segment.
+		{"PUT", "/api/v1/users//roles/admin", "", nil, nil},
+		{"GET", "/api/v1/usersx", "", nil, nil},
+		{"GET", "/users", "", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rt, params, allowed := router.Match(tt.method, tt.path)
+			var op string
+			if rt != nil {
+				op = rt.op.OperationID
+			}
+			if op != tt.wantOp {
+				t.Errorf("operation = %q, want %q", op, tt.wantOp)
+			}
+			if !reflect.DeepEqual(params, tt.wantParams) {
+				t.Errorf("params = %v, want %v", params, tt.wantParams)
+			}
+			if !reflect.DeepEqual(allowed, tt.wantAllowed) {
+				t.Errorf("allowed = %v, want %v", allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the part of an OpenAPI 3 document the mock runtime uses. JSON
+// documents load too, JSON being YAML.
+type Spec struct {
+	OpenAPI string `yaml:"openapi"`
+	Info    struct {
+		Title   string `yaml:"title"`
+		Version string `yaml:"version"`
+	} `yaml:"info"`
+	Servers    []struct{ URL string } `yaml:"servers"`
+	Paths      map[string]*PathItem   `yaml:"paths"`
+	Components struct {
+		Schemas       map[string]*Schema      `yaml:"schemas"`
+		Parameters    map[string]*Parameter   `yaml:"parameters"`
+		RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+		Responses     map[string]*Response    `yaml:"responses"`
+		Examples      map[string]*Example     `yaml:"examples"`
+	} `yaml:"components"`
+}
+
+type PathItem struct {
+	Parameters []*Parameter `yaml:"parameters"`
+	Get        *Operation   `yaml:"get"`
+	Put        *Operation   `yaml:"put"`
+	Post       *Operation   `yaml:"post"`
+	Delete     *Operation   `yaml:"delete"`
+	Patch      *Operation   `yaml:"patch"`
+	Head       *Operation   `yaml:"head"`
+	Options    *Operation   `yaml:"options"`
+}
+
+func (p *PathItem) operations() map[string]*Operation {
+	ops := map[string]*Operation{}
+	for method, op := range map[string]*Operation{
+		"GET": p.Get, "PUT": p.Put, "POST": p.Post, "DELETE": p.Delete,
+		"PATCH": p.Patch, "HEAD": p.Head, "OPTIONS": p.Options,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+type Operation struct {
+	OperationID string               `yaml:"operationId"`
+	Summary     string               `yaml:"summary"`
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+}
+
+type Parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *Schema `yaml:"schema"`
+}
+
+type RequestBody struct {
+	Ref      string                `yaml:"$ref"`
+	Required bool                  `yaml:"required"`
+	Content  map[string]*MediaType `yaml:"content"`
+}
+
+type Response struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Content     map[string]*MediaType `yaml:"content"`
+}
+
+type MediaType struct {
+	Schema   *Schema             `yaml:"schema"`
+	Example  any                 `yaml:"example"`
+	Examples map[string]*Example `yaml:"examples"`
+}
+
+type Example struct {
+	Ref   string `yaml:"$ref"`
+	Value any    `yaml:"value"`
+}
+
+// Schema is a JSON schema as OpenAPI 3.0 and 3.1 write them. Type is a
+// string, or in 3.1 a list such as [string, "null"].
+type Schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 any                `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Enum                 []any              `yaml:"enum"`
+	Default              any                `yaml:"default"`
+	Example              any                `yaml:"example"`
+	Nullable             bool               `yaml:"nullable"`
+	ReadOnly             bool               `yaml:"readOnly"`
+	WriteOnly            bool               `yaml:"writeOnly"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	Required             []string           `yaml:"required"`
+	AdditionalProperties any                `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	AllOf                []*Schema          `yaml:"allOf"`
+	OneOf                []*Schema          `yaml:"oneOf"`
+	AnyOf                []*Schema          `yaml:"anyOf"`
+	Pattern              string             `yaml:"pattern"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	MinItems             *int               `yaml:"minItems"`
+	MaxItems             *int               `yaml:"maxItems"`
+}
+
+// types returns the types the schema allows, without "null".
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var out []string
+		for _, v := range t {
+			if name, ok := v.(string); ok && name != "null" {
+				out = append(out, name)
+			}
+		}
+		return out
+	}
+	if len(s.Properties) > 0 {
+		return []string{"object"}
+	}
+	if s.Items != nil {
+		return []string{"array"}
+	}
+	return nil
+}
+
+func (s *Schema) nullable() bool {
+	if s.Nullable {
+		return true
+	}
+	if t, ok := s.Type.([]any); ok {
+		for _, v := range t {
+			if v == "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LoadSpec reads an OpenAPI document and checks its references.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%s: only OpenAPI 3 documents are supported, got openapi %q", path, spec.OpenAPI)
+	}
+	if err := spec.checkRefs(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// BasePath is the path of the first server URL, such as /api/v1.
+func (s *Spec) BasePath() string {
+	if len(s.Servers) == 0 {
+		return ""
+	}
+	u, err := url.Parse(s.Servers[0].URL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// refName returns the component name of a local reference such as
+// #/components/schemas/User.
+func refName(ref, kind string) (string, error) {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported reference %q: only %s... references are supported", ref, prefix)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
+
+// schema follows the references of s, which may be nil.
+func (s *Spec) schema(schema *Schema) *Schema {
+	for i := 0; schema != nil && schema.Ref != "" && i < 32; i++ {
+		name, _ := refName(schema.Ref, "schemas")
+		schema = s.Components.Schemas[name]
+	}
+	return schema
+}
+
+func (s *Spec) parameter(p *Parameter) *Parameter {
+	if p.Ref == "" {
+		return p
+	}
+	name, _ := refName(p.Ref, "parameters")
+	return s.Components.Parameters[name]
+}
+
+func (s *Spec) requestBody(b *RequestBody) *RequestBody {
+	if b == nil || b.Ref == "" {
+		return b
+	}
+	name, _ := refName(b.Ref, "requestBodies")
+	return s.Components.RequestBodies[name]
+}
+
+func (s *Spec) response(r *Response) *Response {
+	if r == nil || r.Ref == "" {
+		return r
+	}
+	name, _ := refName(r.Ref, "responses")
+	return s.Components.Responses[name]
+}
+
+func (s *Spec) example(e *Example) *Example {
+	if e == nil || e.Ref == "" {
+		return e
+	}
+	name, _ := refName(e.Ref, "examples")
+	return s.Components.Examples[name]
+}
+
+// checkRefs reports the first reference that does not resolve, so that
+// the lookups above can ignore errors.
+func (s *Spec) checkRefs() error {
+	var problems []string
+	check := func(ref, kind string, exists func(string) bool) {
+		if ref == "" {
+			return
+		}
+		name, err := refName(ref, kind)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else if !exists(name) {
+			problems = append(problems, "unresolved reference "+ref)
+		}
+	}
+	seen := map[*Schema]bool{}
+	var walk func(*Schema)
+	walk = func(schema *Schema) {
+		if schema == nil || seen[schema] {
+			return
+		}
+		seen[schema] = true
+		check(schema.Ref, "schemas", func(n string) bool { return s.Components.Schemas[n] != nil })
+		for _, p := range schema.Properties {
+			walk(p)
+		}
+		walk(schema.Items)
+		for _, list := range [][]*Schema{schema.AllOf, schema.OneOf, schema.AnyOf} {
+			for _, sub := range list {
+				walk(sub)
+			}
+		}
+		if sub, ok := schema.AdditionalProperties.(map[string]any); ok && sub["$ref"] != nil {
+			ref, _ := sub["$ref"].(string)
+			check(ref, "schemas", func(n string) bool { return s.Components.Schemas[n] != nil })
+		}
+	}
+	content := func(media map[string]*MediaType) {
+		for _, m := range media {
+			walk(m.Schema)
+			for _, e := range m.Examples {
+				check(e.Ref, "examples", func(n string) bool { return s.Components.Examples[n] != nil })
+			}
+		}
+	}
+	params := func(list []*Parameter) {
+		for _, p := range list {
+			check(p.Ref, "parameters", func(n string) bool { return s.Components.Parameters[n] != nil })
+			walk(p.Schema)
+		}
+	}
+
+	for _, schema := range s.Components.Schemas {
+		walk(schema)
+	}
+	for _, p := range s.Components.Parameters {
+		walk(p.Schema)
+	}
+	for _, b := range s.Components.RequestBodies {
+		content(b.Content)
+	}
+	for _, r := range s.Components.Responses {
+		content(r.Content)
+	}
+	for _, item := range s.Paths {
+		params(item.Parameters)
+		for _, op := range item.operations() {
+			params(op.Parameters)
+			if b := op.RequestBody; b != nil {
+				check(b.Ref, "requestBodies", func(n string) bool { return s.Components.RequestBodies[n] != nil })
+				content(b.Content)
+			}
+			for _, r := range op.Responses {
+				check(r.Ref, "responses", func(n string) bool { return s.Components.Responses[n] != nil })
+				content(r.Content)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
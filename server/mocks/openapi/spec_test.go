@@ -0,0 +1,153 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// testSpec is a small document covering what the router and the
+// validator handle: literal and parameter segments, path item parameters,
+// references and request bodies.
+const testSpec = `
+openapi: 3.0.3
+info: {title: Test, version: "1"}
+servers:
+  - url: http://localhost:8082/api/v1
+paths:
+  /users:
+    get:
+      operationId: listUsers
+      parameters:
+        - {name: limit, in: query, schema: {type: integer, minimum: 1, maximum: 100}}
+        - {name: role, in: query, schema: {type: string, enum: [admin, user]}}
+        - {name: ids, in: query, schema: {type: array, items: {type: integer}}}
+      responses: {"200": {description: ok}}
+    post:
+      operationId: createUser
+      parameters:
+        - $ref: '#/components/parameters/TenantHeader'
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/NewUser'}
+      responses: {"201": {description: created}}
+  /users/me:
+    get:
+      operationId: getCurrentUser
+      responses: {"200": {description: ok}}
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string, format: uuid}}
+    get:
+      operationId: getUser
+      responses: {"200": {description: ok}}
+    delete:
+      operationId: deleteUser
+      responses: {"204": {description: deleted}}
+    patch:
+      operationId: updateUser
+      requestBody:
+        content:
+          application/merge-patch+json:
+            schema: {$ref: '#/components/schemas/NewUser'}
+      responses: {"200": {description: ok}}
+  /users/{id}/roles/{role}:
+    put:
+      operationId: addRole
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: string}}
+        - {name: role, in: path, required: true, schema: {type: string}}
+      responses: {"204": {description: added}}
+components:
+  parameters:
+    TenantHeader: {name: X-Tenant-ID, in: header, required: true, schema: {type: string, minLength: 1}}
+  schemas:
+    NewUser:
+      type: object
+      required: [id, email, name]
+      additionalProperties: false
+      properties:
+        id: {type: string, readOnly: true}
+        email: {type: string, format: email}
+        name: {type: string, minLength: 1, maxLength: 20}
+        age: {type: [integer, "null"], minimum: 0}
+        tags: {type: array, maxItems: 2, items: {type: string}}
+        address: {$ref: '#/components/schemas/Address'}
+    Address:
+      type: object
+      required: [city]
+      properties:
+        city: {type: string}
+`
+
+func parseSpec(t *testing.T, doc string) *Spec {
+	t.Helper()
+	var spec Spec
+	if err := yaml.Unmarshal([]byte(doc), &spec); err != nil {
+		t.Fatalf("parse spec: %v", err)
+	}
+	return &spec
+}
+
+func TestCheckRefs(t *testing.T) {
+	if err := parseSpec(t, testSpec).checkRefs(); err != nil {
+		t.Fatalf("checkRefs() of the test spec error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"missing schema", `
+components:
+  schemas:
+    User: {properties: {address: {$ref: '#/components/schemas/Address'}}}`, "unresolved reference #/components/schemas/Address"},
+		{"missing schema in items", `
+components:
+  schemas:
+    List: {type: array, items: {$ref: '#/components/schemas/Item'}}`, "unresolved reference #/components/schemas/Item"},
+		{"missing schema in additionalProperties", `
+components:
+  schemas:
+    Map: {type: object, additionalProperties: {$ref: '#/components/schemas/Value'}}`, "unresolved reference #/components/schemas/Value"},
+		{"missing parameter", `
+paths:
+  /users:
+    get:
+      parameters: [{$ref: '#/components/parameters/Limit'}]`, "unresolved reference #/components/parameters/Limit"},
+		{"missing response", `
+paths:
+  /users:
+    get:
+      responses: {"404": {$ref: '#/components/responses/NotFound'}}`, "unresolved reference #/components/responses/NotFound"},
+		{"missing example", `
+paths:
+  /users:
+    post:
+      requestBody:
+        content:
+          application/json:
+            examples: {ann: {$ref: '#/components/examples/Ann'}}`, "unresolved reference #/components/examples/Ann"},
+		{"remote reference", `
+components:
+  schemas:
+    User: {$ref: 'common.yaml#/components/schemas/User'}`, `unsupported reference "common.yaml#/components/schemas/User"`},
+		{"several problems are sorted", `
+components:
+  schemas:
+    A: {$ref: '#/components/schemas/Z'}
+    B: {$ref: '#/components/schemas/Y'}`, "unresolved reference #/components/schemas/Y; unresolved reference #/components/schemas/Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseSpec(t, "openapi: 3.0.3\n"+strings.TrimPrefix(tt.doc, "\n")).checkRefs()
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("checkRefs() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
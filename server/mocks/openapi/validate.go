@@ -0,0 +1,356 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// maxBody bounds the request bodies the mock reads.
+const maxBody = 1 << 20
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// validator checks values against the schemas of one spec, collecting a
+// problem per invalid field.
+type validator struct {
+	spec     *Spec
+	problems []mockkit.FieldError
+}
+
+func (v *validator) fail(field, format string, args ...any) {
+	v.problems = append(v.problems, mockkit.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateRequest checks the parameters and body of r against the
+// operation and returns the parsed JSON body, if any.
+func ValidateRequest(spec *Spec, rt *route, pathParams map[string]string, r *http.Request) (any, []mockkit.FieldError) {
+	v := &validator{spec: spec}
+	query := r.URL.Query()
+	for _, p := range rt.params {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw, present = pathParams[p.Name]
+		case "query":
+			raw, present = query.Get(p.Name), query.Has(p.Name)
+		case "header":
+			raw = r.Header.Get(p.Name)
+			present = raw != ""
+		case "cookie":
+			if c, err := r.Cookie(p.Name); err == nil {
+				raw, present = c.Value, true
+			}
+		}
+		field := p.In + "." + p.Name
+		if !present {
+			if p.Required {
+				v.fail(field, "is required")
+			}
+			continue
+		}
+		if schema := spec.schema(p.Schema); schema != nil {
+			v.value(field, parseParam(spec, raw, schema), schema, true)
+		}
+	}
+
+	var body any
+	rb := spec.requestBody(rt.op.RequestBody)
+	if rb == nil {
+		return nil, v.problems
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBody))
+	if err != nil {
+		v.fail("body", "could not be read: %v", err)
+		return nil, v.problems
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		if rb.Required {
+			v.fail("body", "is required")
+		}
+		return nil, v.problems
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "" {
+		mediaType = "application/json"
+	}
+	media := rb.Content[mediaType]
+	if media == nil && isJSON(mediaType) {
+		media = jsonMedia(rb.Content)
+	}
+	if media == nil {
+		v.fail("body", "content type %s is not accepted", mediaType)
+		return nil, v.problems
+	}
+	if !isJSON(mediaType) {
+		return nil, v.problems
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		v.fail("body", "is not valid JSON: %v", err)
+		return nil, v.problems
+	}
+	if schema := spec.schema(media.Schema); schema != nil {
+		v.value("body", body, schema, true)
+	}
+	return body, v.problems
+}
+
+// parseParam converts a parameter to the type of its schema, leaving it a
+// string when it does not parse so that validation reports it.
+func parseParam(spec *Spec, raw string, schema *Schema) any {
+	for _, t := range schema.types() {
+		switch t {
+		case "integer", "number":
+			if f, err := strconv.ParseFloat(raw, 64); err == nil {
+				return f
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(raw); err == nil {
+				return b
+			}
+		case "array":
+			var out []any
+			for _, part := range strings.Split(raw, ",") {
+				if items := spec.schema(schema.Items); items != nil {
+					out = append(out, parseParam(spec, part, items))
+				} else {
+					out = append(out, part)
+				}
+			}
+			return out
+		}
+	}
+	return raw
+}
+
+// value checks one value. request is set for request bodies, where
+// readOnly properties need not be sent.
+func (v *validator) value(field string, value any, schema *Schema, request bool) {
+	schema = v.spec.schema(schema)
+	if schema == nil {
+		return
+	}
+	if value == nil {
+		if !schema.nullable() && len(schema.types()) > 0 {
+			v.fail(field, "must not be null")
+		}
+		return
+	}
+
+	for _, sub := range schema.AllOf {
+		v.value(field, value, sub, request)
+	}
+	if len(schema.OneOf) > 0 && !v.anyMatch(field, value, schema.OneOf, request) {
+		v.fail(field, "does not match any of the allowed schemas")
+	}
+	if len(schema.AnyOf) > 0 && !v.anyMatch(field, value, schema.AnyOf, request) {
+		v.fail(field, "does not match any of the allowed schemas")
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(value, schema.Enum) {
+		v.fail(field, "must be one of %s", enumList(schema.Enum))
+		return
+	}
+
+	types := schema.types()
+	if len(types) == 0 {
+		return
+	}
+	for _, t := range types {
+		if hasType(value, t) {
+			v.typed(field, value, t, schema, request)
+			return
+		}
+	}
+	v.fail(field, "must be of type %s", strings.Join(types, " or "))
+}
+
+func (v *validator) anyMatch(field string, value any, schemas []*Schema, request bool) bool {
+	for _, sub := range schemas {
+		try := &validator{spec: v.spec}
+		try.value(field, value, sub, request)
+		if len(try.problems) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) typed(field string, value any, t string, schema *Schema, request bool) {
+	switch t {
+	case "string":
+		s := value.(string)
+		n := len([]rune(s))
+		if schema.MinLength != nil && n < *schema.MinLength {
+			if *schema.MinLength == 1 {
+				v.fail(field, "must not be empty")
+			} else {
+				v.fail(field, "must be at least %d characters", *schema.MinLength)
+			}
+		}
+		if schema.MaxLength != nil && n > *schema.MaxLength {
+			v.fail(field, "must be at most %d characters", *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(s) {
+				v.fail(field, "must match %s", schema.Pattern)
+			}
+		}
+		if msg := checkFormat(schema.Format, s); msg != "" {
+			v.fail(field, "%s", msg)
+		}
+	case "integer", "number":
+		f := value.(float64)
+		if schema.Minimum != nil && f < *schema.Minimum {
+			v.fail(field, "must be at least %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			v.fail(field, "must be at most %v", *schema.Maximum)
+		}
+	case "array":
+		items := value.([]any)
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			v.fail(field, "must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			v.fail(field, "must have at most %d items", *schema.MaxItems)
+		}
+		for i, item := range items {
+			v.value(fmt.Sprintf("%s[%d]", field, i), item, schema.Items, request)
+		}
+	case "object":
+		obj := value.(map[string]any)
+		for _, name := range schema.Required {
+			prop := v.spec.schema(schema.Properties[name])
+			if _, ok := obj[name]; !ok && !(request && prop != nil && prop.ReadOnly) {
+				v.fail(field+"."+name, "is required")
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := schema.Properties[name]
+			if !ok {
+				if schema.AdditionalProperties == false {
+					v.fail(field+"."+name, "is not allowed")
+				}
+				continue
+			}
+			v.value(field+"."+name, obj[name], prop, request)
+		}
+	}
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	return true
+}
+
+// checkFormat checks the formats tests commonly rely on; others pass.
+func checkFormat(format, s string) string {
+	switch format {
+	case "email":
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return "must be a valid email address"
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(s) {
+			return "must be a UUID"
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return "must be an RFC 3339 date-time"
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return "must be a date like 2024-01-31"
+		}
+	}
+	return ""
+}
+
+func inEnum(value any, enum []any) bool {
+	for _, e := range enum {
+		if fmt.Sprint(normalize(e)) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []any) string {
+	var parts []string
+	for _, e := range enum {
+		parts = append(parts, fmt.Sprint(e))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// normalize converts YAML numbers to the float64 of decoded JSON.
+func normalize(v any) any {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	}
+	return v
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// jsonMedia returns the JSON content of a body or response, if any.
+func jsonMedia(content map[string]*MediaType) *MediaType {
+	if m := content["application/json"]; m != nil {
+		return m
+	}
+	keys := make([]string, 0, len(content))
+	for k := range content {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if isJSON(k) {
+			return content[k]
+		}
+	}
+	return nil
+}
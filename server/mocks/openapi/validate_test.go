@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	spec := parseSpec(t, testSpec)
+	router := NewRouter(spec)
+	const uuid = "0b8e5a52-4c1e-4a6e-9d5e-7a3f2c1d0e9f"
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		tenant      string
+		want        []string // "field: message"
+	}{
+		{"valid query", "GET", "/api/v1/users?limit=10&role=admin&ids=1,2", "", "", "", nil},
+		{"query out of range", "GET", "/api/v1/users?limit=0", "", "", "", []string{"query.limit: must be at least 1"}},
+		{"query not a number", "GET", "/api/v1/users?limit=ten", "", "", "", []string{"query.limit: must be of type integer"}},
+		{"query not in enum", "GET", "/api/v1/users?role=owner", "", "", "", []string{"query.role: must be one of admin, user"}},
+		{"array items", "GET", "/api/v1/users?ids=1,x", "", "", "", []string{"query.ids[1]: must be of type integer"}},
+		{"path format", "GET", "/api/v1/users/42", "", "", "", []string{"path.id: must be a UUID"}},
+		{"valid path", "GET", "/api/v1/users/" + uuid, "", "", "", nil},
+		{"valid body", "POST", "/api/v1/users", "application/json", `{"email":"ann@example.com","name":"Ann","age":null,"tags":["a"],"address":{"city":"Hanoi"}}`, "tenant-1", nil},
+		{"missing header and body", "POST", "/api/v1/users", "", "", "", []string{"header.X-Tenant-ID: is required", "body: is required"}},
+		{"invalid JSON", "POST", "/api/v1/users", "application/json", `{"email":`, "tenant-1", []string{"body: is not valid JSON: unexpected end of JSON input"}},
+		{"wrong content type", "POST", "/api/v1/users", "text/plain", "hello", "tenant-1", []string{"body: content type text/plain is not accepted"}},
+		{
+			"invalid fields", "POST", "/api/v1/users", "application/json; charset=utf-8",
+			`{"email":"ann","name":"","age":-1.5,"tags":["a","b","c"],"address":{},"admin":true}`, "tenant-1",
+			[]string{
+				"body.address.city: is required",
+				"body.admin: is not allowed",
+				"body.age: must be of type integer",
+				"body.email: must be a valid email address",
+				"body.name: must not be empty",
+				"body.tags: must have at most 2 items",
+			},
+		},
+		{"readOnly is not required but null is checked", "POST", "/api/v1/users", "application/json", `{"email":"ann@example.com","name":null}`, "tenant-1", []string{"body.name: must not be null"}},
+		{"optional body may be empty", "PATCH", "/api/v1/users/" + uuid, "", "", "", nil},
+		{"+json media type", "PATCH", "/api/v1/users/" + uuid, "application/json", `{"email":"ann@example.com","name":"A very long name indeed"}`, "", []string{"body.name: must be at most 20 characters"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.tenant != "" {
+				r.Header.Set("X-Tenant-ID", tt.tenant)
+			}
+			rt, params, _ := router.Match(tt.method, r.URL.Path)
+			if rt == nil {
+				t.Fatalf("no route for %s %s", tt.method, tt.target)
+			}
+			_, problems := ValidateRequest(spec, rt, params, r)
+			var got []string
+			for _, p := range problems {
+				got = append(got, p.Field+": "+p.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRequestReturnsBody(t *testing.T) {
+	spec := parseSpec(t, testSpec)
+	rt, params, _ := NewRouter(spec).Match(http.MethodPost, "/api/v1/users")
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/users", strings.NewReader(`{"email":"ann@example.com","name":"Ann"}`))
+	r.Header.Set("X-Tenant-ID", "tenant-1")
+	body, problems := ValidateRequest(spec, rt, params, r)
+	if len(problems) > 0 {
+		t.Fatalf("problems = %v", problems)
+	}
+	if got, _ := body.(map[string]any); got["name"] != "Ann" {
+		t.Errorf("body = %v", body)
+	}
+}
@@ -1,3 +1,7 @@
 module github.com/vhvplatform/go-system-config-service
 
 go 1.21
+
+require github.com/vhvplatform/go-framework/mocks/mockkit v0.0.0
+
+replace github.com/vhvplatform/go-framework/mocks/mockkit => ../mockkit
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+const (
+	defaultWatch = 30 * time.Second
+	maxWatch     = 5 * time.Minute
+	// heartbeat keeps idle event streams from timing out in proxies.
+	heartbeat = 15 * time.Second
+)
+
+var keyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// ConfigServer implements /api/v1/config.
+type ConfigServer struct {
+	Store *Store
+}
+
+// ConfigResponse is the effective configuration of a tenant's app.
+type ConfigResponse struct {
+	TenantID string           `json:"tenant_id,omitempty"`
+	AppCode  string           `json:"app_code,omitempty"`
+	Revision int64            `json:"revision"`
+	Values   map[string]Value `json:"values"`
+}
+
+// WatchResponse carries the changes since the revision a watcher passed.
+// Passing Revision back waits for the next ones.
+type WatchResponse struct {
+	Revision int64    `json:"revision"`
+	Changes  []Change `json:"changes"`
+}
+
+type settingInput struct {
+	Type            string          `json:"type"`
+	Value           json.RawMessage `json:"value"`
+	ExpectedVersion int             `json:"expected_version"`
+}
+
+func (s *ConfigServer) Register(svc *mockkit.Service) {
+	svc.HandleFunc("/api/v1/config", mockkit.Method(http.MethodGet, s.config))
+	svc.HandleFunc("/api/v1/config/values/", mockkit.Method(http.MethodGet, s.value))
+	svc.HandleFunc("/api/v1/config/settings", mockkit.Method(http.MethodGet, s.settings))
+	svc.HandleFunc("/api/v1/config/settings/", s.setting)
+	svc.HandleFunc("/api/v1/config/history", mockkit.Method(http.MethodGet, s.history))
+	svc.HandleFunc("/api/v1/config/watch", mockkit.Method(http.MethodGet, s.watch))
+}
+
+// view returns the tenant and app whose configuration a request reads:
+// the X-Tenant-ID and X-App-Code headers the gateway sets, or the
+// tenant_id and app_code query parameters when called directly.
+func view(r *http.Request) (tenantID, appCode string) {
+	q := r.URL.Query()
+	tenantID, appCode = r.Header.Get("X-Tenant-ID"), r.Header.Get("X-App-Code")
+	if tenantID == "" {
+		tenantID, appCode = q.Get("tenant_id"), q.Get("app_code")
+	}
+	return tenantID, appCode
+}
+
+func (s *ConfigServer) config(w http.ResponseWriter, r *http.Request) {
+	tenantID, appCode := view(r)
+	values, revision := s.Store.Effective(tenantID, appCode)
+	mockkit.WriteJSON(w, http.StatusOK, ConfigResponse{TenantID: tenantID, AppCode: appCode, Revision: revision, Values: values})
+}
+
+func (s *ConfigServer) value(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/api/v1/config/values/")
+	values, _ := s.Store.Effective(view(r))
+	v, ok := values[key]
+	if !ok {
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", errNotFound.Error())
+		return
+	}
+	mockkit.WriteJSON(w, http.StatusOK, v)
+}
+
+func (s *ConfigServer) settings(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	mockkit.WriteJSON(w, http.StatusOK, map[string]any{"data": s.Store.Settings(q.Get("layer"), q.Get("tenant_id"), q.Get("app_code"))})
+}
+
+// setting reads, sets and deletes one setting. The layer is picked with
+// the tenant_id and app_code query parameters; without them it is global.
+func (s *ConfigServer) setting(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/api/v1/config/settings/")
+	q := r.URL.Query()
+	tenantID, appCode := q.Get("tenant_id"), q.Get("app_code")
+
+	var problems []mockkit.FieldError
+	if !keyPattern.MatchString(key) {
+		problems = append(problems, mockkit.FieldError{Field: "key", Message: "must be lower case letters, digits, _, - and ., e.g. feature.signup.enabled"})
+	}
+	if appCode != "" && tenantID == "" {
+		problems = append(problems, mockkit.FieldError{Field: "app_code", Message: "app settings need a tenant_id"})
+	}
+	if len(problems) > 0 {
+		mockkit.WriteError(w, http.StatusBadRequest, "validation_failed", "invalid setting address", problems...)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		for _, setting := range s.Store.Settings("", tenantID, appCode) {
+			if setting.Key == key && setting.TenantID == tenantID && setting.AppCode == appCode {
+				mockkit.WriteJSON(w, http.StatusOK, setting)
+				return
+			}
+		}
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", errNotFound.Error())
+	case http.MethodPut:
+		var in settingInput
+		if !mockkit.Decode(w, r, &in) {
+			return
+		}
+		if err := ValidateValue(in.Type, in.Value); err != nil {
+			mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the setting is invalid", []mockkit.FieldError{{Field: "value", Message: err.Error()}}...)
+			return
+		}
+		setting, err := s.Store.Set(Setting{
+			Key:       key,
+			TenantID:  tenantID,
+			AppCode:   appCode,
+			Type:      in.Type,
+			Value:     in.Value,
+			UpdatedBy: r.Header.Get("X-User-ID"),
+		}, in.ExpectedVersion)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		mockkit.WriteJSON(w, http.StatusOK, setting)
+	case http.MethodDelete:
+		if err := s.Store.Delete(key, tenantID, appCode, r.Header.Get("X-User-ID")); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		mockkit.MethodNotAllowed(w, r, "GET, PUT, DELETE")
+	}
+}
+
+func (s *ConfigServer) history(w http.ResponseWriter, r *http.Request) {
+	since, err := revisionParam(r)
+	if err != nil {
+		mockkit.WriteError(w, http.StatusBadRequest, "validation_failed", "invalid query parameters", []mockkit.FieldError{{Field: "since", Message: err.Error()}}...)
+		return
+	}
+	mockkit.WriteJSON(w, http.StatusOK, map[string]any{"data": s.Store.History(r.URL.Query().Get("key"), since)})
+}
+
+// watch long-polls for the changes after ?since= that affect the view, or
+// streams them as server-sent events when the client accepts
+// text/event-stream. Without since it waits for the next change.
+func (s *ConfigServer) watch(w http.ResponseWriter, r *http.Request) {
+	since, err := revisionParam(r)
+	if err != nil {
+		mockkit.WriteError(w, http.StatusBadRequest, "validation_failed", "invalid query parameters", []mockkit.FieldError{{Field: "since", Message: err.Error()}}...)
+		return
+	}
+	if since < 0 {
+		since = s.Store.Revision()
+	}
+	tenantID, appCode := view(r)
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		s.stream(w, r, since, tenantID, appCode)
+		return
+	}
+
+	timeout := defaultWatch
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > maxWatch {
+			mockkit.WriteError(w, http.StatusBadRequest, "validation_failed", "invalid query parameters", []mockkit.FieldError{{Field: "timeout", Message: "must be a duration up to " + maxWatch.String() + ", e.g. 30s"}}...)
+			return
+		}
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	changes, revision := s.Store.Watch(ctx, since, tenantID, appCode)
+	mockkit.WriteJSON(w, http.StatusOK, WatchResponse{Revision: revision, Changes: changes})
+}
+
+// stream sends each change as a "change" event whose ID is its revision,
+// so a reconnecting EventSource resumes with Last-Event-ID.
+func (s *ConfigServer) stream(w http.ResponseWriter, r *http.Request, since int64, tenantID, appCode string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		mockkit.WriteError(w, http.StatusInternalServerError, "internal_error", "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, ": watching from revision %d\n\n", since)
+	flusher.Flush()
+
+	for {
+		ctx, cancel := context.WithTimeout(r.Context(), heartbeat)
+		changes, _ := s.Store.Watch(ctx, since, tenantID, appCode)
+		cancel()
+		if r.Context().Err() != nil {
+			return
+		}
+		if len(changes) == 0 {
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		for _, c := range changes {
+			data, _ := json.Marshal(c)
+			fmt.Fprintf(w, "id: %d\nevent: change\ndata: %s\n\n", c.Revision, data)
+			since = c.Revision
+		}
+		flusher.Flush()
+	}
+}
+
+// revisionParam reads ?since=, or the Last-Event-ID header of a
+// reconnecting event stream. It is -1 when neither is set.
+func revisionParam(r *http.Request) (int64, error) {
+	v := r.URL.Query().Get("since")
+	if v == "" {
+		v = r.Header.Get("Last-Event-ID")
+	}
+	if v == "" {
+		return -1, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("must be a revision number")
+	}
+	return n, nil
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errNotFound):
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, errVersionConflict):
+		mockkit.WriteError(w, http.StatusConflict, "version_conflict", err.Error(), []mockkit.FieldError{{Field: "expected_version", Message: "is not the current version"}}...)
+	default:
+		mockkit.WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
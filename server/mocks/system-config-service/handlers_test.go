@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *Store) {
+	t.Helper()
+	store := NewStore()
+	svc := mockkit.New("system-config-service", "0")
+	(&ConfigServer{Store: store}).Register(svc)
+	srv := httptest.NewServer(svc.Handler())
+	t.Cleanup(srv.Close)
+	return srv, store
+}
+
+func put(t *testing.T, srv *httptest.Server, target, body string) *http.Response {
+	t.Helper()
+	r, err := http.NewRequest(http.MethodPut, srv.URL+target, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestPutExpectedVersion(t *testing.T) {
+	srv, _ := newTestServer(t)
+	const target = "/api/v1/config/settings/theme?tenant_id=tenant-1"
+
+	if resp := put(t, srv, target, `{"type":"string","value":"dark"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT = %s", resp.Status)
+	}
+	if resp := put(t, srv, target, `{"type":"string","value":"blue","expected_version":1}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT with the current version = %s", resp.Status)
+	}
+
+	r, _ := http.NewRequest(http.MethodPut, srv.URL+target, strings.NewReader(`{"type":"string","value":"red","expected_version":1}`))
+	r.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body mockkit.ErrorResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusConflict || body.Error.Code != "version_conflict" ||
+		len(body.Error.Details) != 1 || body.Error.Details[0].Field != "expected_version" {
+		t.Errorf("PUT with a stale version = %s %+v", resp.Status, body.Error)
+	}
+
+	if resp := put(t, srv, target, `{"type":"number","value":"dark"}`); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("PUT of a mistyped value = %s, want 422", resp.Status)
+	}
+}
+
+func TestWatchLongPoll(t *testing.T) {
+	srv, store := newTestServer(t)
+	set(t, store, "theme", "", "", `"light"`)
+
+	get := func(target string) (WatchResponse, int) {
+		resp, err := http.Get(srv.URL + target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body WatchResponse
+		json.NewDecoder(resp.Body).Decode(&body)
+		return body, resp.StatusCode
+	}
+
+	if body, _ := get("/api/v1/config/watch?since=0"); len(body.Changes) != 1 || body.Revision != 1 {
+		t.Errorf("watch since 0 = %+v", body)
+	}
+	if body, _ := get("/api/v1/config/watch?since=1&timeout=20ms"); len(body.Changes) != 0 || body.Revision != 1 {
+		t.Errorf("watch without changes = %+v, want none at revision 1", body)
+	}
+	if _, status := get("/api/v1/config/watch?since=x"); status != http.StatusBadRequest {
+		t.Errorf("watch since=x = %d, want 400", status)
+	}
+
+	done := make(chan WatchResponse)
+	go func() {
+		body, _ := get("/api/v1/config/watch?since=1&tenant_id=tenant-1&timeout=5s")
+		done <- body
+	}()
+	time.Sleep(20 * time.Millisecond)
+	set(t, store, "theme", "tenant-1", "", `"dark"`)
+	select {
+	case body := <-done:
+		if len(body.Changes) != 1 || body.Changes[0].Layer != LayerTenant || body.Revision != 2 {
+			t.Errorf("watch = %+v, want the tenant change at revision 2", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch did not answer after a change")
+	}
+}
+
+func TestWatchStreamResumes(t *testing.T) {
+	srv, store := newTestServer(t)
+	for _, v := range []string{`"a"`, `"b"`, `"c"`} {
+		set(t, store, "theme", "", "", v)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/v1/config/watch", nil)
+	r.Header.Set("Accept", "text/event-stream")
+	r.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	events := make(chan string)
+	go func() {
+		defer close(events)
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			if id, ok := strings.CutPrefix(sc.Text(), "id: "); ok {
+				events <- id
+			}
+		}
+	}()
+	next := func() string {
+		t.Helper()
+		select {
+		case id := <-events:
+			return id
+		case <-time.After(2 * time.Second):
+			t.Fatal("no event")
+			return ""
+		}
+	}
+
+	// The stream resumes after the last event seen, then follows changes.
+	if a, b := next(), next(); a != "2" || b != "3" {
+		t.Errorf("resumed events = %s, %s, want 2, 3", a, b)
+	}
+	set(t, store, "theme", "", "", `"d"`)
+	if id := next(); id != "4" {
+		t.Errorf("next event = %s, want 4", id)
+	}
+}
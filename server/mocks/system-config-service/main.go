@@ -1,36 +1,24 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
-	"net/http"
-	"os"
-)
-
-type HealthResponse struct {
-	Status  string `json:"status"`
-	Service string `json:"service"`
-}
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(HealthResponse{
-		Status:  "healthy",
-		Service: "system-config-service",
-	})
-}
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8085"
+	svc := mockkit.New("system-config-service", "8085")
+
+	store := NewStore()
+	configFile := mockkit.Env("CONFIG_FILE", "/fixtures/config.json")
+	if n, err := store.Load(configFile); err != nil {
+		log.Printf("No settings loaded from %s: %v", configFile, err)
+	} else {
+		log.Printf("Loaded %d settings from %s", n, configFile)
 	}
 
-	http.HandleFunc("/health", healthHandler)
-	
-	log.Printf("System Config Service mock service starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	(&ConfigServer{Store: store}).Register(svc)
+	if err := svc.Run(); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// Value types of a setting.
+const (
+	TypeString = "string"
+	TypeNumber = "number"
+	TypeBool   = "bool"
+	TypeJSON   = "json"
+)
+
+// Layers, from the least to the most specific. A tenant setting overrides
+// the global one and an app setting overrides both for that tenant's app.
+const (
+	LayerGlobal = "global"
+	LayerTenant = "tenant"
+	LayerApp    = "app"
+)
+
+// Setting is a configuration value on one layer: global when TenantID is
+// empty, the tenant's when AppCode is empty, else the tenant's app's.
+type Setting struct {
+	Key       string          `json:"key"`
+	TenantID  string          `json:"tenant_id,omitempty"`
+	AppCode   string          `json:"app_code,omitempty"`
+	Type      string          `json:"type"`
+	Value     json.RawMessage `json:"value"`
+	Version   int             `json:"version"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	UpdatedBy string          `json:"updated_by,omitempty"`
+}
+
+func (s *Setting) Layer() string {
+	switch {
+	case s.TenantID == "":
+		return LayerGlobal
+	case s.AppCode == "":
+		return LayerTenant
+	default:
+		return LayerApp
+	}
+}
+
+// Change is an entry of the change log. Revision numbers every change of
+// the store; Version counts the changes of one setting.
+type Change struct {
+	Revision  int64           `json:"revision"`
+	Action    string          `json:"action"`
+	Key       string          `json:"key"`
+	Layer     string          `json:"layer"`
+	TenantID  string          `json:"tenant_id,omitempty"`
+	AppCode   string          `json:"app_code,omitempty"`
+	Type      string          `json:"type,omitempty"`
+	OldValue  json.RawMessage `json:"old_value,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Version   int             `json:"version"`
+	Time      time.Time       `json:"time"`
+	UpdatedBy string          `json:"updated_by,omitempty"`
+}
+
+// Affects reports whether the change can alter the configuration seen by
+// the tenant's app. An empty app sees the tenant layer only, an empty
+// tenant the global layer only.
+func (c *Change) Affects(tenantID, appCode string) bool {
+	if c.TenantID == "" {
+		return true
+	}
+	if c.TenantID != tenantID {
+		return false
+	}
+	return c.AppCode == "" || c.AppCode == appCode
+}
+
+// Value is the effective value of a key, with the layer it comes from.
+type Value struct {
+	Type    string          `json:"type"`
+	Value   json.RawMessage `json:"value"`
+	Layer   string          `json:"layer"`
+	Version int             `json:"version"`
+}
+
+var (
+	errNotFound        = errors.New("setting not found")
+	errVersionConflict = errors.New("setting was changed by someone else")
+)
+
+type settingKey struct {
+	key, tenantID, appCode string
+}
+
+// Store keeps the settings and their change log in memory.
+type Store struct {
+	mu       sync.Mutex
+	settings map[settingKey]*Setting
+	// versions holds the last version of every key, deleted ones
+	// included, so that a recreated setting continues the count.
+	versions map[settingKey]int
+	changes  []Change
+	revision int64
+	// changed is closed and replaced on every change, to wake up watchers.
+	changed chan struct{}
+}
+
+func NewStore() *Store {
+	return &Store{settings: map[settingKey]*Setting{}, versions: map[settingKey]int{}, changed: make(chan struct{})}
+}
+
+// Load sets the settings of a JSON file, as changes by "fixtures".
+func (s *Store) Load(path string) (int, error) {
+	var settings []Setting
+	if err := mockkit.LoadJSON(path, &settings); err != nil {
+		return 0, err
+	}
+	for _, setting := range settings {
+		if err := ValidateValue(setting.Type, setting.Value); err != nil {
+			return 0, fmt.Errorf("%s: %s: %w", path, setting.Key, err)
+		}
+		setting.UpdatedBy = "fixtures"
+		if _, err := s.Set(setting, 0); err != nil {
+			return 0, err
+		}
+	}
+	return len(settings), nil
+}
+
+// Set creates or replaces a setting. A non-zero expectedVersion must be
+// the current version of the setting, so it never matches a deleted one.
+func (s *Store) Set(in Setting, expectedVersion int) (*Setting, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := settingKey{in.Key, in.TenantID, in.AppCode}
+	old := s.settings[k]
+	version := 0
+	if old != nil {
+		version = old.Version
+	}
+	if expectedVersion != 0 && expectedVersion != version {
+		return nil, errVersionConflict
+	}
+
+	in.Version = s.versions[k] + 1
+	s.versions[k] = in.Version
+	in.UpdatedAt = time.Now().UTC()
+	s.settings[k] = &in
+	c := Change{Action: "set", Type: in.Type, Value: in.Value}
+	if old != nil {
+		c.OldValue = old.Value
+	}
+	s.record(c, &in)
+	return &in, nil
+}
+
+// Delete removes a setting, uncovering the one of the layer below.
+func (s *Store) Delete(key, tenantID, appCode, by string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := settingKey{key, tenantID, appCode}
+	old := s.settings[k]
+	if old == nil {
+		return errNotFound
+	}
+	delete(s.settings, k)
+	deleted := *old
+	deleted.Version = s.versions[k] + 1
+	s.versions[k] = deleted.Version
+	deleted.UpdatedBy = by
+	s.record(Change{Action: "delete", Type: old.Type, OldValue: old.Value}, &deleted)
+	return nil
+}
+
+// record appends c, completed from setting, to the log. The caller holds
+// the lock.
+func (s *Store) record(c Change, setting *Setting) {
+	s.revision++
+	c.Revision = s.revision
+	c.Key = setting.Key
+	c.Layer = setting.Layer()
+	c.TenantID = setting.TenantID
+	c.AppCode = setting.AppCode
+	c.Version = setting.Version
+	c.Time = time.Now().UTC()
+	c.UpdatedBy = setting.UpdatedBy
+	s.changes = append(s.changes, c)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Settings lists the settings of a layer, or of every layer when layer is
+// empty, sorted by key.
+func (s *Store) Settings(layer, tenantID, appCode string) []*Setting {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []*Setting{}
+	for _, setting := range s.settings {
+		if layer != "" && setting.Layer() != layer ||
+			tenantID != "" && setting.TenantID != tenantID ||
+			appCode != "" && setting.AppCode != appCode {
+			continue
+		}
+		out = append(out, setting)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.TenantID != b.TenantID {
+			return a.TenantID < b.TenantID
+		}
+		return a.AppCode < b.AppCode
+	})
+	return out
+}
+
+// Effective resolves every key for the tenant's app, the most specific
+// layer winning, and returns the values with the current revision.
+func (s *Store) Effective(tenantID, appCode string) (map[string]Value, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := map[string]Value{}
+	rank := map[string]int{}
+	for k, setting := range s.settings {
+		var r int
+		switch {
+		case k.tenantID == "":
+			r = 1
+		case k.tenantID != tenantID:
+			continue
+		case k.appCode == "":
+			r = 2
+		case k.appCode == appCode:
+			r = 3
+		default:
+			continue
+		}
+		if r > rank[k.key] {
+			rank[k.key] = r
+			values[k.key] = Value{Type: setting.Type, Value: setting.Value, Layer: setting.Layer(), Version: setting.Version}
+		}
+	}
+	return values, s.revision
+}
+
+// Revision is the number of the latest change.
+func (s *Store) Revision() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.revision
+}
+
+// History returns the changes after revision since, oldest first, that
+// match key when it is set.
+func (s *Store) History(key string, since int64) []Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []Change{}
+	for _, c := range s.changes {
+		if c.Revision > since && (key == "" || c.Key == key) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Watch waits until there are changes after revision since that affect
+// the tenant's app and returns them with the current revision. When ctx
+// is done first it returns no changes.
+func (s *Store) Watch(ctx context.Context, since int64, tenantID, appCode string) ([]Change, int64) {
+	for {
+		s.mu.Lock()
+		var out []Change
+		for _, c := range s.changes {
+			if c.Revision > since && c.Affects(tenantID, appCode) {
+				out = append(out, c)
+			}
+		}
+		revision, changed := s.revision, s.changed
+		s.mu.Unlock()
+
+		if len(out) > 0 {
+			return out, revision
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return []Change{}, revision
+		}
+	}
+}
+
+// ValidateValue checks that value is JSON of the given type.
+func ValidateValue(typ string, value json.RawMessage) error {
+	if len(value) == 0 {
+		return errors.New("value is required")
+	}
+	var v any
+	if err := json.Unmarshal(value, &v); err != nil {
+		return fmt.Errorf("value is not valid JSON: %w", err)
+	}
+	ok := false
+	switch typ {
+	case TypeString:
+		_, ok = v.(string)
+	case TypeNumber:
+		_, ok = v.(float64)
+	case TypeBool:
+		_, ok = v.(bool)
+	case TypeJSON:
+		ok = true
+	default:
+		return fmt.Errorf("type must be one of %s, %s, %s, %s", TypeString, TypeNumber, TypeBool, TypeJSON)
+	}
+	if !ok {
+		return fmt.Errorf("value is not a %s", typ)
+	}
+	return nil
+}
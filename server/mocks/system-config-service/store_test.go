@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func set(t *testing.T, s *Store, key, tenantID, appCode, value string) *Setting {
+	t.Helper()
+	setting, err := s.Set(Setting{Key: key, TenantID: tenantID, AppCode: appCode, Type: TypeJSON, Value: json.RawMessage(value)}, 0)
+	if err != nil {
+		t.Fatalf("Set(%s) error = %v", key, err)
+	}
+	return setting
+}
+
+func TestStoreEffective(t *testing.T) {
+	s := NewStore()
+	set(t, s, "theme", "", "", `"light"`)
+	set(t, s, "theme", "tenant-1", "", `"dark"`)
+	set(t, s, "theme", "tenant-1", "HRM_APP", `"blue"`)
+	set(t, s, "limit", "", "", `10`)
+	set(t, s, "limit", "tenant-1", "CRM_APP", `20`)
+	set(t, s, "only.tenant2", "tenant-2", "", `true`)
+
+	tests := []struct {
+		tenantID, appCode string
+		want              map[string]string // key: layer value
+	}{
+		{"", "", map[string]string{"theme": `global "light"`, "limit": "global 10"}},
+		{"tenant-1", "", map[string]string{"theme": `tenant "dark"`, "limit": "global 10"}},
+		{"tenant-1", "HRM_APP", map[string]string{"theme": `app "blue"`, "limit": "global 10"}},
+		{"tenant-1", "CRM_APP", map[string]string{"theme": `tenant "dark"`, "limit": "app 20"}},
+		{"tenant-2", "HRM_APP", map[string]string{"theme": `global "light"`, "limit": "global 10", "only.tenant2": "tenant true"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tenantID+"/"+tt.appCode, func(t *testing.T) {
+			values, revision := s.Effective(tt.tenantID, tt.appCode)
+			if revision != 6 {
+				t.Errorf("revision = %d, want 6", revision)
+			}
+			got := map[string]string{}
+			for k, v := range values {
+				got[k] = v.Layer + " " + string(v.Value)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("values = %v, want %v", got, tt.want)
+			}
+			for k, want := range tt.want {
+				if got[k] != want {
+					t.Errorf("%s = %q, want %q", k, got[k], want)
+				}
+			}
+		})
+	}
+
+	// Deleting the app setting uncovers the tenant one.
+	if err := s.Delete("theme", "tenant-1", "HRM_APP", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if values, _ := s.Effective("tenant-1", "HRM_APP"); values["theme"].Layer != LayerTenant {
+		t.Errorf("after delete theme comes from %s, want %s", values["theme"].Layer, LayerTenant)
+	}
+}
+
+func TestStoreExpectedVersion(t *testing.T) {
+	s := NewStore()
+	in := Setting{Key: "theme", Type: TypeString, Value: json.RawMessage(`"light"`)}
+	if _, err := s.Set(in, 1); !errors.Is(err, errVersionConflict) {
+		t.Errorf("Set() of a new setting with expected_version 1 error = %v, want %v", err, errVersionConflict)
+	}
+	first, _ := s.Set(in, 0)
+	if _, err := s.Set(in, first.Version); err != nil {
+		t.Fatalf("Set() with the current version error = %v", err)
+	}
+	if _, err := s.Set(in, first.Version); !errors.Is(err, errVersionConflict) {
+		t.Errorf("Set() with a stale version error = %v, want %v", err, errVersionConflict)
+	}
+
+	// The count goes on across a delete, so versions are never reused.
+	if err := s.Delete("theme", "", "", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Set(in, 2); !errors.Is(err, errVersionConflict) {
+		t.Errorf("Set() of a deleted setting with its old version error = %v, want %v", err, errVersionConflict)
+	}
+	recreated, _ := s.Set(in, 0)
+	if recreated.Version != 4 {
+		t.Errorf("recreated version = %d, want 4", recreated.Version)
+	}
+}
+
+func TestStoreWatch(t *testing.T) {
+	s := NewStore()
+	set(t, s, "theme", "", "", `"light"`)
+
+	type result struct {
+		changes  []Change
+		revision int64
+	}
+	done := make(chan result)
+	go func() {
+		changes, revision := s.Watch(context.Background(), 1, "tenant-1", "HRM_APP")
+		done <- result{changes, revision}
+	}()
+
+	// Changes of another tenant or app do not wake the watcher up.
+	time.Sleep(10 * time.Millisecond)
+	set(t, s, "theme", "tenant-2", "", `"dark"`)
+	set(t, s, "theme", "tenant-1", "CRM_APP", `"red"`)
+	select {
+	case r := <-done:
+		t.Fatalf("Watch() returned %+v for unrelated changes", r.changes)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	set(t, s, "theme", "tenant-1", "", `"dark"`)
+	select {
+	case r := <-done:
+		if len(r.changes) != 1 || r.changes[0].Revision != 4 || r.revision != 4 {
+			t.Errorf("Watch() = %+v at %d, want the tenant change at 4", r.changes, r.revision)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Watch() did not return after a change")
+	}
+
+	// Changes that already happened are returned at once.
+	changes, _ := s.Watch(context.Background(), 0, "tenant-2", "")
+	if len(changes) != 2 {
+		t.Errorf("Watch() from 0 for tenant-2 = %d changes, want 2", len(changes))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if changes, revision := s.Watch(ctx, 4, "", ""); len(changes) != 0 || revision != 4 {
+		t.Errorf("Watch() on timeout = %+v at %d, want none at 4", changes, revision)
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+var (
+	plans    = []string{"free", "pro", "enterprise"}
+	statuses = []string{"active", "suspended", "inactive"}
+
+	slugPattern    = regexp.MustCompile(`^[a-z0-9-]+$`)
+	domainPattern  = regexp.MustCompile(`^[a-z0-9.-]+$`)
+	prefixPattern  = regexp.MustCompile(`^/[a-z0-9-/]*$`)
+	appCodePattern = regexp.MustCompile(`^[A-Z0-9_]+$`)
+)
+
+// reservedPrefixes are system paths no tenant may route.
+var reservedPrefixes = []string{"/api", "/admin", "/login", "/static", "/health"}
+
+// TenantServer implements /api/v1/tenants and the /api/v1/routes table.
+type TenantServer struct {
+	Store *Store
+}
+
+// LookupResponse is the result of a route lookup.
+type LookupResponse struct {
+	TenantID       string `json:"tenant_id"`
+	AppCode        string `json:"app_code"`
+	IsCustomDomain bool   `json:"is_custom_domain"`
+	RouteID        string `json:"route_id"`
+	Domain         string `json:"domain"`
+	PathPrefix     string `json:"path_prefix"`
+}
+
+func (s *TenantServer) Register(svc *mockkit.Service) {
+	svc.HandleFunc("/api/v1/tenants", s.tenants)
+	svc.HandleFunc("/api/v1/tenants/", s.tenant)
+	svc.HandleFunc("/api/v1/routes", s.routes)
+	svc.HandleFunc("/api/v1/routes/lookup", s.lookup)
+	svc.HandleFunc("/api/v1/routes/", s.route)
+}
+
+func (s *TenantServer) tenants(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		mockkit.WriteJSON(w, http.StatusOK, map[string]any{"data": s.Store.Tenants()})
+	case http.MethodPost:
+		var in tenantInput
+		if !mockkit.Decode(w, r, &in) {
+			return
+		}
+		in.defaults()
+		if problems := in.validate(true); len(problems) > 0 {
+			mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the tenant is invalid", problems...)
+			return
+		}
+		t, err := s.Store.CreateTenant(Tenant{Name: strings.TrimSpace(*in.Name), Slug: *in.Slug, Plan: *in.Plan, Status: *in.Status})
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.Header().Set("Location", "/api/v1/tenants/"+t.ID)
+		mockkit.WriteJSON(w, http.StatusCreated, t)
+	default:
+		mockkit.MethodNotAllowed(w, r, "GET, POST")
+	}
+}
+
+func (s *TenantServer) tenant(w http.ResponseWriter, r *http.Request) {
+	id, sub, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/v1/tenants/"), "/")
+	if sub == "routes" {
+		if r.Method != http.MethodGet {
+			mockkit.MethodNotAllowed(w, r, "GET")
+			return
+		}
+		if _, err := s.Store.Tenant(id); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		mockkit.WriteJSON(w, http.StatusOK, map[string]any{"data": s.Store.Routes(id)})
+		return
+	}
+	if id == "" || sub != "" {
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", "no such endpoint")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		t, err := s.Store.Tenant(id)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		mockkit.WriteJSON(w, http.StatusOK, t)
+	case http.MethodPatch:
+		var in tenantInput
+		if !mockkit.Decode(w, r, &in) {
+			return
+		}
+		if problems := in.validate(false); len(problems) > 0 {
+			mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the tenant is invalid", problems...)
+			return
+		}
+		t, err := s.Store.UpdateTenant(id, func(t *Tenant) {
+			if in.Name != nil {
+				t.Name = strings.TrimSpace(*in.Name)
+			}
+			if in.Slug != nil {
+				t.Slug = *in.Slug
+			}
+			if in.Plan != nil {
+				t.Plan = *in.Plan
+			}
+			if in.Status != nil {
+				t.Status = *in.Status
+			}
+		})
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		mockkit.WriteJSON(w, http.StatusOK, t)
+	case http.MethodDelete:
+		if err := s.Store.DeleteTenant(id); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		mockkit.MethodNotAllowed(w, r, "GET, PATCH, DELETE")
+	}
+}
+
+func (s *TenantServer) routes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		mockkit.WriteJSON(w, http.StatusOK, map[string]any{"data": s.Store.Routes(r.URL.Query().Get("tenant_id"))})
+	case http.MethodPost:
+		var in Route
+		in.IsActive = true
+		if !mockkit.Decode(w, r, &in) {
+			return
+		}
+		in.Domain = strings.ToLower(strings.TrimSpace(in.Domain))
+		if in.PathPrefix == "" {
+			in.PathPrefix = "/"
+		}
+		if problems := validateRoute(&in); len(problems) > 0 {
+			mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the route is invalid", problems...)
+			return
+		}
+		route, err := s.Store.CreateRoute(in)
+		if errors.Is(err, errTenantNotFound) {
+			mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the route is invalid", []mockkit.FieldError{{Field: "tenant_id", Message: "no such tenant"}}...)
+			return
+		}
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.Header().Set("Location", "/api/v1/routes/"+route.ID)
+		mockkit.WriteJSON(w, http.StatusCreated, route)
+	default:
+		mockkit.MethodNotAllowed(w, r, "GET, POST")
+	}
+}
+
+func (s *TenantServer) route(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/routes/")
+	if id == "" || strings.Contains(id, "/") {
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", "no such endpoint")
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		route, err := s.Store.Route(id)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		mockkit.WriteJSON(w, http.StatusOK, route)
+	case http.MethodDelete:
+		if err := s.Store.DeleteRoute(id); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		mockkit.MethodNotAllowed(w, r, "GET, DELETE")
+	}
+}
+
+// lookup resolves ?domain=&path= to a tenant and application. The domain
+// may carry a port, as a Host header does.
+func (s *TenantServer) lookup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		mockkit.MethodNotAllowed(w, r, "GET")
+		return
+	}
+	q := r.URL.Query()
+	domain := strings.TrimSuffix(strings.ToLower(q.Get("domain")), ".")
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	if domain == "" {
+		mockkit.WriteError(w, http.StatusBadRequest, "validation_failed", "invalid query parameters", []mockkit.FieldError{{Field: "domain", Message: "is required"}}...)
+		return
+	}
+	p := q.Get("path")
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	p = path.Clean(p)
+
+	route, err := s.Store.Lookup(domain, p)
+	switch {
+	case errors.Is(err, errNoMapping):
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", "Tenant mapping not found")
+	case errors.Is(err, errAmbiguousMatch):
+		mockkit.WriteError(w, http.StatusConflict, "conflict", "Tenant mapping is not unique")
+	default:
+		mockkit.WriteJSON(w, http.StatusOK, LookupResponse{
+			TenantID:       route.TenantID,
+			AppCode:        route.AppCode,
+			IsCustomDomain: route.IsCustomDomain,
+			RouteID:        route.ID,
+			Domain:         route.Domain,
+			PathPrefix:     route.PathPrefix,
+		})
+	}
+}
+
+// validateRoute checks a new route against the constraints of
+// tenant_app_routes and normalises its path prefix.
+func validateRoute(r *Route) []mockkit.FieldError {
+	var problems []mockkit.FieldError
+	if r.TenantID == "" {
+		problems = append(problems, mockkit.FieldError{Field: "tenant_id", Message: "is required"})
+	}
+	if !appCodePattern.MatchString(r.AppCode) {
+		problems = append(problems, mockkit.FieldError{Field: "app_code", Message: "must be upper case letters, digits and underscores, e.g. HRM_APP"})
+	}
+	if !domainPattern.MatchString(r.Domain) {
+		problems = append(problems, mockkit.FieldError{Field: "domain", Message: "must be a lower case host name"})
+	}
+
+	if !prefixPattern.MatchString(r.PathPrefix) {
+		problems = append(problems, mockkit.FieldError{Field: "path_prefix", Message: "must start with / and contain only lower case letters, digits, - and /"})
+		return problems
+	}
+	r.PathPrefix = path.Clean(r.PathPrefix)
+	for _, reserved := range reservedPrefixes {
+		if prefixMatches(reserved, r.PathPrefix) {
+			problems = append(problems, mockkit.FieldError{Field: "path_prefix", Message: reserved + " is reserved for the platform"})
+		}
+	}
+	return problems
+}
+
+// tenantInput is the body of tenant requests. Fields left out of a PATCH
+// are unchanged.
+type tenantInput struct {
+	Name   *string `json:"name"`
+	Slug   *string `json:"slug"`
+	Plan   *string `json:"plan"`
+	Status *string `json:"status"`
+}
+
+func (in *tenantInput) defaults() {
+	if in.Plan == nil {
+		plan := "free"
+		in.Plan = &plan
+	}
+	if in.Status == nil {
+		status := "active"
+		in.Status = &status
+	}
+}
+
+func (in tenantInput) validate(all bool) []mockkit.FieldError {
+	var problems []mockkit.FieldError
+	if in.Name != nil && strings.TrimSpace(*in.Name) == "" || in.Name == nil && all {
+		problems = append(problems, mockkit.FieldError{Field: "name", Message: "is required"})
+	}
+	if in.Slug != nil && !slugPattern.MatchString(*in.Slug) || in.Slug == nil && all {
+		problems = append(problems, mockkit.FieldError{Field: "slug", Message: "must be lower case letters, digits and -"})
+	}
+	if in.Plan != nil && !oneOf(*in.Plan, plans) {
+		problems = append(problems, mockkit.FieldError{Field: "plan", Message: "must be one of " + strings.Join(plans, ", ")})
+	}
+	if in.Status != nil && !oneOf(*in.Status, statuses) {
+		problems = append(problems, mockkit.FieldError{Field: "status", Message: "must be one of " + strings.Join(statuses, ", ")})
+	}
+	return problems
+}
+
+func oneOf(v string, values []string) bool {
+	for _, value := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errTenantNotFound), errors.Is(err, errRouteNotFound):
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, errSlugTaken):
+		mockkit.WriteError(w, http.StatusConflict, "conflict", err.Error(), []mockkit.FieldError{{Field: "slug", Message: "is already used by another tenant"}}...)
+	case errors.Is(err, errRouteTaken):
+		mockkit.WriteError(w, http.StatusConflict, "conflict", err.Error(), []mockkit.FieldError{{Field: "path_prefix", Message: "is already routed on this domain"}}...)
+	default:
+		mockkit.WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
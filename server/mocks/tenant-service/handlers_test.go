@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// newTestServer serves a store loaded from the given tenant and route
+// fixtures, so that duplicates in them are kept as they are.
+func newTestServer(t *testing.T, tenants, routes string) http.Handler {
+	t.Helper()
+	dir := t.TempDir()
+	store := NewStore()
+	for name, data := range map[string]string{"tenants.json": tenants, "routes.json": routes} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.LoadTenants(filepath.Join(dir, "tenants.json")); err != nil {
+		t.Fatalf("LoadTenants() error = %v", err)
+	}
+	if _, err := store.LoadRoutes(filepath.Join(dir, "routes.json")); err != nil {
+		t.Fatalf("LoadRoutes() error = %v", err)
+	}
+	svc := mockkit.New("tenant-service", "0")
+	(&TenantServer{Store: store}).Register(svc)
+	return svc.Handler()
+}
+
+const (
+	testTenants = `[{"id":"tenant-1","name":"Acme","slug":"acme","plan":"pro","status":"active"},
+		{"id":"tenant-2","name":"Beta","slug":"beta","plan":"free","status":"active"}]`
+	testRoutes = `[
+		{"id":"route-1","tenant_id":"tenant-1","app_code":"HRM_APP","domain":"acme.local","path_prefix":"/hrm","is_active":true},
+		{"id":"route-2","tenant_id":"tenant-1","app_code":"PORTAL","domain":"acme.local","path_prefix":"/","is_active":true},
+		{"id":"route-3","tenant_id":"tenant-1","app_code":"CRM_APP","domain":"dup.local","path_prefix":"/crm","is_active":true},
+		{"id":"route-4","tenant_id":"tenant-2","app_code":"CRM_APP","domain":"dup.local","path_prefix":"/crm","is_active":true}]`
+)
+
+func TestLookupEndpoint(t *testing.T) {
+	h := newTestServer(t, testTenants, testRoutes)
+
+	tests := []struct {
+		name       string
+		domain     string
+		path       string
+		wantStatus int
+		wantRoute  string
+		wantCode   string
+	}{
+		{"exact prefix", "acme.local", "/hrm", http.StatusOK, "route-1", ""},
+		{"below prefix", "acme.local", "/hrm/users", http.StatusOK, "route-1", ""},
+		{"similar prefix falls back to /", "acme.local", "/hrms", http.StatusOK, "route-2", ""},
+		{"port is stripped", "acme.local:8080", "/hrm", http.StatusOK, "route-1", ""},
+		{"case and trailing dot", "ACME.local.", "/hrm", http.StatusOK, "route-1", ""},
+		{"path is cleaned", "acme.local", "hrm/../hrm/./users", http.StatusOK, "route-1", ""},
+		{"duplicate fixture routes", "dup.local", "/crm/leads", http.StatusConflict, "", "conflict"},
+		{"unknown domain", "other.local", "/", http.StatusNotFound, "", "not_found"},
+		{"no domain", "", "/", http.StatusBadRequest, "", "validation_failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := url.Values{"domain": {tt.domain}, "path": {tt.path}}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/routes/lookup?"+q.Encode(), nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if tt.wantRoute != "" {
+				var got LookupResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatal(err)
+				}
+				if got.RouteID != tt.wantRoute {
+					t.Errorf("route = %q, want %q", got.RouteID, tt.wantRoute)
+				}
+				return
+			}
+			var body mockkit.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error.Code != tt.wantCode {
+				t.Errorf("error code = %q, want %q", body.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCreateRoute(t *testing.T) {
+	h := newTestServer(t, testTenants, testRoutes)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantField  string
+	}{
+		{"new route", `{"tenant_id":"tenant-2","app_code":"HRM_APP","domain":"beta.local","path_prefix":"/hrm/","is_active":true}`, http.StatusCreated, ""},
+		{"same domain and prefix", `{"tenant_id":"tenant-2","app_code":"HRM_APP","domain":"acme.local","path_prefix":"/hrm"}`, http.StatusConflict, "path_prefix"},
+		{"reserved prefix", `{"tenant_id":"tenant-1","app_code":"API","domain":"acme.local","path_prefix":"/api/v2"}`, http.StatusUnprocessableEntity, "path_prefix"},
+		{"unknown tenant", `{"tenant_id":"tenant-9","app_code":"HRM_APP","domain":"acme.local","path_prefix":"/new"}`, http.StatusUnprocessableEntity, "tenant_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/routes", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if tt.wantField == "" {
+				var route Route
+				if err := json.Unmarshal(w.Body.Bytes(), &route); err != nil {
+					t.Fatal(err)
+				}
+				if route.PathPrefix != "/hrm" {
+					t.Errorf("path_prefix = %q, want it cleaned to /hrm", route.PathPrefix)
+				}
+				return
+			}
+			var body mockkit.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body.Error.Details) == 0 || body.Error.Details[0].Field != tt.wantField {
+				t.Errorf("details = %+v, want a %s detail", body.Error.Details, tt.wantField)
+			}
+		})
+	}
+}
@@ -1,36 +1,32 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
-	"net/http"
-	"os"
-)
-
-type HealthResponse struct {
-	Status  string `json:"status"`
-	Service string `json:"service"`
-}
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(HealthResponse{
-		Status:  "healthy",
-		Service: "tenant-service",
-	})
-}
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8083"
+	svc := mockkit.New("tenant-service", "8083")
+
+	store := NewStore()
+	for _, f := range []struct {
+		env, def string
+		load     func(string) (int, error)
+	}{
+		{"TENANTS_FILE", "/fixtures/tenants.json", store.LoadTenants},
+		{"ROUTES_FILE", "/fixtures/tenant_app_routes.json", store.LoadRoutes},
+	} {
+		path := mockkit.Env(f.env, f.def)
+		if n, err := f.load(path); err != nil {
+			log.Printf("Nothing loaded from %s: %v", path, err)
+		} else {
+			log.Printf("Loaded %d records from %s", n, path)
+		}
 	}
 
-	http.HandleFunc("/health", healthHandler)
-	
-	log.Printf("Tenant Service mock service starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	(&TenantServer{Store: store}).Register(svc)
+	if err := svc.Run(); err != nil {
 		log.Fatal(err)
 	}
 }
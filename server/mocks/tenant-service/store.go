@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// Tenant is an organisation, as in server/fixtures/tenants.json.
+type Tenant struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	Plan      string    `json:"plan"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Route maps a domain and path prefix to a tenant and application, as a
+// row of tenant_app_routes (docs/architecture/Routing.md).
+type Route struct {
+	ID             string    `json:"id"`
+	TenantID       string    `json:"tenant_id"`
+	AppCode        string    `json:"app_code"`
+	Domain         string    `json:"domain"`
+	PathPrefix     string    `json:"path_prefix"`
+	IsPrimary      bool      `json:"is_primary"`
+	IsCustomDomain bool      `json:"is_custom_domain"`
+	IsActive       bool      `json:"is_active"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+var (
+	errTenantNotFound = errors.New("tenant not found")
+	errRouteNotFound  = errors.New("route not found")
+	errSlugTaken      = errors.New("slug already in use")
+	errRouteTaken     = errors.New("domain and path prefix already routed")
+	errNoMapping      = errors.New("no route matches")
+	errAmbiguousMatch = errors.New("several routes match")
+)
+
+// Store keeps tenants and routes in memory. Fixture routes are loaded as
+// they are, so a duplicate (domain, path_prefix) in the file shows up as a
+// lookup conflict instead of being rejected.
+type Store struct {
+	mu      sync.Mutex
+	tenants []*Tenant
+	routes  []*Route
+}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+func (s *Store) LoadTenants(path string) (int, error) {
+	var tenants []*Tenant
+	if err := mockkit.LoadJSON(path, &tenants); err != nil {
+		return 0, err
+	}
+	for _, t := range tenants {
+		if t.UpdatedAt.IsZero() {
+			t.UpdatedAt = t.CreatedAt
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tenants = append(s.tenants, tenants...)
+	return len(tenants), nil
+}
+
+func (s *Store) LoadRoutes(path string) (int, error) {
+	var routes []*Route
+	if err := mockkit.LoadJSON(path, &routes); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes = append(s.routes, routes...)
+	return len(routes), nil
+}
+
+func (s *Store) Tenants() []Tenant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Tenant, len(s.tenants))
+	for i, t := range s.tenants {
+		out[i] = *t
+	}
+	return out
+}
+
+func (s *Store) Tenant(id string) (Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t := s.tenant(id); t != nil {
+		return *t, nil
+	}
+	return Tenant{}, errTenantNotFound
+}
+
+func (s *Store) CreateTenant(t Tenant) (Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.slugTaken(t.Slug, "") {
+		return Tenant{}, errSlugTaken
+	}
+	t.ID = "tenant-" + mockkit.RandomID(6)
+	t.CreatedAt = time.Now().UTC()
+	t.UpdatedAt = t.CreatedAt
+	s.tenants = append(s.tenants, &t)
+	return t, nil
+}
+
+func (s *Store) UpdateTenant(id string, change func(*Tenant)) (Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.tenant(id)
+	if t == nil {
+		return Tenant{}, errTenantNotFound
+	}
+	updated := *t
+	change(&updated)
+	if s.slugTaken(updated.Slug, id) {
+		return Tenant{}, errSlugTaken
+	}
+	updated.UpdatedAt = time.Now().UTC()
+	*t = updated
+	return updated, nil
+}
+
+// DeleteTenant removes a tenant and, like the foreign key, its routes.
+func (s *Store) DeleteTenant(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.tenants {
+		if t.ID == id {
+			s.tenants = append(s.tenants[:i], s.tenants[i+1:]...)
+			routes := s.routes[:0]
+			for _, r := range s.routes {
+				if r.TenantID != id {
+					routes = append(routes, r)
+				}
+			}
+			s.routes = routes
+			return nil
+		}
+	}
+	return errTenantNotFound
+}
+
+func (s *Store) tenant(id string) *Tenant {
+	for _, t := range s.tenants {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Store) slugTaken(slug, except string) bool {
+	for _, t := range s.tenants {
+		if t.Slug == slug && t.ID != except {
+			return true
+		}
+	}
+	return false
+}
+
+// Routes returns the routes of a tenant, or all routes when tenant is "".
+func (s *Store) Routes(tenant string) []Route {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []Route{}
+	for _, r := range s.routes {
+		if tenant == "" || r.TenantID == tenant {
+			out = append(out, *r)
+		}
+	}
+	return out
+}
+
+func (s *Store) Route(id string) (Route, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.routes {
+		if r.ID == id {
+			return *r, nil
+		}
+	}
+	return Route{}, errRouteNotFound
+}
+
+// CreateRoute adds a route, enforcing the UNIQUE (domain, path_prefix)
+// constraint and the tenant foreign key.
+func (s *Store) CreateRoute(r Route) (Route, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tenant(r.TenantID) == nil {
+		return Route{}, errTenantNotFound
+	}
+	for _, existing := range s.routes {
+		if existing.Domain == r.Domain && existing.PathPrefix == r.PathPrefix {
+			return Route{}, errRouteTaken
+		}
+	}
+	r.ID = "route-" + mockkit.RandomID(6)
+	r.CreatedAt = time.Now().UTC()
+	s.routes = append(s.routes, &r)
+	return r, nil
+}
+
+func (s *Store) DeleteRoute(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range s.routes {
+		if r.ID == id {
+			s.routes = append(s.routes[:i], s.routes[i+1:]...)
+			return nil
+		}
+	}
+	return errRouteNotFound
+}
+
+// Lookup returns the active route of domain with the longest path prefix
+// matching path. It fails with errNoMapping when none matches and with
+// errAmbiguousMatch when several routes share that prefix.
+func (s *Store) Lookup(domain, path string) (Route, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matches []*Route
+	for _, r := range s.routes {
+		if r.IsActive && r.Domain == domain && prefixMatches(r.PathPrefix, path) {
+			matches = append(matches, r)
+		}
+	}
+	if len(matches) == 0 {
+		return Route{}, errNoMapping
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return len(matches[i].PathPrefix) > len(matches[j].PathPrefix) })
+	if len(matches) > 1 && matches[1].PathPrefix == matches[0].PathPrefix {
+		return Route{}, errAmbiguousMatch
+	}
+	return *matches[0], nil
+}
+
+// prefixMatches reports whether path is prefix or below it; /hrm matches
+// /hrm and /hrm/users but not /hrms.
+func prefixMatches(prefix, path string) bool {
+	if prefix == "/" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStoreLookup(t *testing.T) {
+	s := &Store{routes: []*Route{
+		{ID: "root", TenantID: "tenant-1", AppCode: "PORTAL", Domain: "acme.local", PathPrefix: "/", IsActive: true},
+		{ID: "hrm", TenantID: "tenant-1", AppCode: "HRM_APP", Domain: "acme.local", PathPrefix: "/hrm", IsActive: true},
+		{ID: "hrm-admin", TenantID: "tenant-1", AppCode: "HRM_ADMIN", Domain: "acme.local", PathPrefix: "/hrm/admin", IsActive: true},
+		{ID: "crm", TenantID: "tenant-2", AppCode: "CRM_APP", Domain: "beta.local", PathPrefix: "/crm", IsActive: true},
+		{ID: "crm-off", TenantID: "tenant-2", AppCode: "CRM_OLD", Domain: "beta.local", PathPrefix: "/crm/v1", IsActive: false},
+	}}
+
+	tests := []struct {
+		domain, path string
+		want         string
+		wantErr      error
+	}{
+		{"acme.local", "/", "root", nil},
+		{"acme.local", "/hrm", "hrm", nil},
+		{"acme.local", "/hrm/users/42", "hrm", nil},
+		{"acme.local", "/hrm/admin", "hrm-admin", nil},
+		{"acme.local", "/hrm/admin/roles", "hrm-admin", nil},
+		{"acme.local", "/hrms", "root", nil},
+		{"acme.local", "/hrm/administrators", "hrm", nil},
+		{"beta.local", "/crm/v1/leads", "crm", nil},
+		{"beta.local", "/crms", "", errNoMapping},
+		{"gamma.local", "/", "", errNoMapping},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain+tt.path, func(t *testing.T) {
+			got, err := s.Lookup(tt.domain, tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lookup() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.ID != tt.want {
+				t.Errorf("Lookup() = %q, want %q", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreLookupAmbiguous(t *testing.T) {
+	s := &Store{routes: []*Route{
+		{ID: "a", TenantID: "tenant-1", Domain: "acme.local", PathPrefix: "/hrm", IsActive: true},
+		{ID: "b", TenantID: "tenant-2", Domain: "acme.local", PathPrefix: "/hrm", IsActive: true},
+		{ID: "c", TenantID: "tenant-1", Domain: "acme.local", PathPrefix: "/hrm/admin", IsActive: true},
+	}}
+	if _, err := s.Lookup("acme.local", "/hrm/users"); !errors.Is(err, errAmbiguousMatch) {
+		t.Errorf("Lookup() error = %v, want %v", err, errAmbiguousMatch)
+	}
+	// A longer prefix still wins over the duplicates.
+	if got, err := s.Lookup("acme.local", "/hrm/admin"); err != nil || got.ID != "c" {
+		t.Errorf("Lookup() = %q, %v, want c", got.ID, err)
+	}
+}
+
+func TestStoreCreateRouteIsUnique(t *testing.T) {
+	s := &Store{tenants: []*Tenant{{ID: "tenant-1"}}}
+	r := Route{TenantID: "tenant-1", AppCode: "HRM_APP", Domain: "acme.local", PathPrefix: "/hrm", IsActive: true}
+	if _, err := s.CreateRoute(r); err != nil {
+		t.Fatalf("CreateRoute() error = %v", err)
+	}
+	if _, err := s.CreateRoute(r); !errors.Is(err, errRouteTaken) {
+		t.Errorf("duplicate CreateRoute() error = %v, want %v", err, errRouteTaken)
+	}
+	r.TenantID = "tenant-9"
+	r.PathPrefix = "/crm"
+	if _, err := s.CreateRoute(r); !errors.Is(err, errTenantNotFound) {
+		t.Errorf("CreateRoute() for a missing tenant error = %v, want %v", err, errTenantNotFound)
+	}
+}
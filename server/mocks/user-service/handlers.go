@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// roles are the role names of server/fixtures/roles.json.
+var roles = []string{"admin", "user", "viewer"}
+
+// UserServer implements the /api/v1/users endpoints. Every request is
+// scoped to the tenant in the X-Tenant-ID header.
+type UserServer struct {
+	Store *Store
+}
+
+// ListResponse is a page of users. NextCursor is set when HasMore is.
+type ListResponse struct {
+	Data       []User `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// userInput is the body of create and update requests. Fields left out of
+// a PATCH are unchanged.
+type userInput struct {
+	Email    *string `json:"email"`
+	Name     *string `json:"name"`
+	Role     *string `json:"role"`
+	TenantID *string `json:"tenant_id"`
+}
+
+func (s *UserServer) Register(svc *mockkit.Service) {
+	svc.HandleFunc("/api/v1/users", s.withTenant(s.collection))
+	svc.HandleFunc("/api/v1/users/", s.withTenant(s.item))
+}
+
+type tenantHandler func(w http.ResponseWriter, r *http.Request, tenant string)
+
+func (s *UserServer) withTenant(h tenantHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant := strings.TrimSpace(r.Header.Get("X-Tenant-ID"))
+		if tenant == "" {
+			mockkit.WriteError(w, http.StatusBadRequest, "tenant_required", "the X-Tenant-ID header is required")
+			return
+		}
+		h(w, r, tenant)
+	}
+}
+
+func (s *UserServer) collection(w http.ResponseWriter, r *http.Request, tenant string) {
+	switch r.Method {
+	case http.MethodGet:
+		s.list(w, r, tenant)
+	case http.MethodPost:
+		s.create(w, r, tenant)
+	default:
+		mockkit.MethodNotAllowed(w, r, "GET, POST")
+	}
+}
+
+func (s *UserServer) item(w http.ResponseWriter, r *http.Request, tenant string) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/users/")
+	if id == "" || strings.Contains(id, "/") {
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", "no such endpoint")
+		return
+	}
+	if id == "me" {
+		// The gateway identifies the caller with X-User-ID.
+		if id = r.Header.Get("X-User-ID"); id == "" {
+			mockkit.WriteError(w, http.StatusUnauthorized, "unauthenticated", "the X-User-ID header is required for /users/me")
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		u, err := s.Store.Get(tenant, id)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		mockkit.WriteJSON(w, http.StatusOK, u)
+	case http.MethodPut, http.MethodPatch:
+		s.update(w, r, tenant, id)
+	case http.MethodDelete:
+		if err := s.Store.Delete(tenant, id); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		mockkit.MethodNotAllowed(w, r, "GET, PUT, PATCH, DELETE")
+	}
+}
+
+func (s *UserServer) list(w http.ResponseWriter, r *http.Request, tenant string) {
+	q := r.URL.Query()
+	var problems []mockkit.FieldError
+
+	limit := defaultLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			problems = append(problems, mockkit.FieldError{Field: "limit", Message: "must be a number from 1 to " + strconv.Itoa(maxLimit)})
+		}
+		limit = n
+	}
+	var after *User
+	if v := q.Get("cursor"); v != "" {
+		var err error
+		if after, err = decodeCursor(v); err != nil {
+			problems = append(problems, mockkit.FieldError{Field: "cursor", Message: "is not a cursor returned by this endpoint"})
+		}
+	}
+	role := q.Get("role")
+	if role != "" && !validRole(role) {
+		problems = append(problems, mockkit.FieldError{Field: "role", Message: "must be one of " + strings.Join(roles, ", ")})
+	}
+	if len(problems) > 0 {
+		mockkit.WriteError(w, http.StatusBadRequest, "validation_failed", "invalid query parameters", problems...)
+		return
+	}
+
+	search := strings.ToLower(q.Get("q"))
+	filter := func(u *User) bool {
+		if role != "" && u.Role != role {
+			return false
+		}
+		return search == "" || strings.Contains(strings.ToLower(u.Email), search) || strings.Contains(strings.ToLower(u.Name), search)
+	}
+
+	page, more := s.Store.List(tenant, filter, after, limit)
+	resp := ListResponse{Data: page, HasMore: more}
+	if resp.Data == nil {
+		resp.Data = []User{}
+	}
+	if more {
+		resp.NextCursor = encodeCursor(page[len(page)-1])
+	}
+	mockkit.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *UserServer) create(w http.ResponseWriter, r *http.Request, tenant string) {
+	var in userInput
+	if !mockkit.Decode(w, r, &in) {
+		return
+	}
+	if in.Role == nil {
+		role := "user"
+		in.Role = &role
+	}
+	if problems := in.validate(tenant, true); len(problems) > 0 {
+		mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the user is invalid", problems...)
+		return
+	}
+
+	u, err := s.Store.Create(User{
+		Email:    strings.TrimSpace(*in.Email),
+		Name:     strings.TrimSpace(*in.Name),
+		Role:     *in.Role,
+		TenantID: tenant,
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/users/"+u.ID)
+	mockkit.WriteJSON(w, http.StatusCreated, u)
+}
+
+// update replaces the user with PUT, which needs every field, and changes
+// the given fields with PATCH.
+func (s *UserServer) update(w http.ResponseWriter, r *http.Request, tenant, id string) {
+	var in userInput
+	if !mockkit.Decode(w, r, &in) {
+		return
+	}
+	if problems := in.validate(tenant, r.Method == http.MethodPut); len(problems) > 0 {
+		mockkit.WriteError(w, http.StatusUnprocessableEntity, "validation_failed", "the user is invalid", problems...)
+		return
+	}
+
+	u, err := s.Store.Update(tenant, id, func(u *User) error {
+		if in.Email != nil {
+			u.Email = strings.TrimSpace(*in.Email)
+		}
+		if in.Name != nil {
+			u.Name = strings.TrimSpace(*in.Name)
+		}
+		if in.Role != nil {
+			u.Role = *in.Role
+		}
+		return nil
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	mockkit.WriteJSON(w, http.StatusOK, u)
+}
+
+// validate checks the given fields; with all set, missing fields are
+// reported too.
+func (in userInput) validate(tenant string, all bool) []mockkit.FieldError {
+	var problems []mockkit.FieldError
+	required := func(field string, v *string) bool {
+		if v == nil || strings.TrimSpace(*v) == "" {
+			if v != nil || all {
+				problems = append(problems, mockkit.FieldError{Field: field, Message: "is required"})
+			}
+			return false
+		}
+		return true
+	}
+
+	if required("email", in.Email) {
+		addr, err := mail.ParseAddress(*in.Email)
+		if err != nil || addr.Address != strings.TrimSpace(*in.Email) || !strings.Contains(addr.Address[strings.LastIndex(addr.Address, "@"):], ".") {
+			problems = append(problems, mockkit.FieldError{Field: "email", Message: "must be a valid email address"})
+		}
+	}
+	if required("name", in.Name) && len(*in.Name) > 100 {
+		problems = append(problems, mockkit.FieldError{Field: "name", Message: "must be at most 100 characters"})
+	}
+	if required("role", in.Role) && !validRole(*in.Role) {
+		problems = append(problems, mockkit.FieldError{Field: "role", Message: "must be one of " + strings.Join(roles, ", ")})
+	}
+	if in.TenantID != nil && *in.TenantID != tenant {
+		problems = append(problems, mockkit.FieldError{Field: "tenant_id", Message: "must match the X-Tenant-ID header"})
+	}
+	return problems
+}
+
+func validRole(role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type cursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+}
+
+func encodeCursor(u User) string {
+	data, _ := json.Marshal(cursor{CreatedAt: u.CreatedAt, ID: u.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*User, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.ID == "" {
+		return nil, errors.New("empty cursor")
+	}
+	return &User{ID: c.ID, CreatedAt: c.CreatedAt}, nil
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errNotFound):
+		mockkit.WriteError(w, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, errEmailTaken):
+		mockkit.WriteError(w, http.StatusConflict, "conflict", err.Error(), []mockkit.FieldError{{Field: "email", Message: "is already used by another user of this tenant"}}...)
+	default:
+		mockkit.WriteError(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// newTestServer serves a store holding users, created a minute apart in
+// the order given.
+func newTestServer(t *testing.T, users ...User) (http.Handler, *Store) {
+	t.Helper()
+	store := &Store{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range users {
+		u := users[i]
+		u.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		u.UpdatedAt = u.CreatedAt
+		store.users = append(store.users, &u)
+	}
+	svc := mockkit.New("user-service", "0")
+	(&UserServer{Store: store}).Register(svc)
+	return svc.Handler(), store
+}
+
+func do(t *testing.T, h http.Handler, method, target, tenant, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	if tenant != "" {
+		r.Header.Set("X-Tenant-ID", tenant)
+	}
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+}
+
+func TestListPaginatesWithCursor(t *testing.T) {
+	h, _ := newTestServer(t,
+		User{ID: "u1", Email: "a@example.com", Name: "A", TenantID: "tenant-1", Role: "user"},
+		User{ID: "x1", Email: "a@example.com", Name: "A", TenantID: "tenant-2", Role: "user"},
+		User{ID: "u2", Email: "b@example.com", Name: "B", TenantID: "tenant-1", Role: "admin"},
+		User{ID: "u3", Email: "c@example.com", Name: "C", TenantID: "tenant-1", Role: "user"},
+		User{ID: "x2", Email: "b@example.com", Name: "B", TenantID: "tenant-2", Role: "user"},
+		User{ID: "u4", Email: "d@example.com", Name: "D", TenantID: "tenant-1", Role: "user"},
+		User{ID: "u5", Email: "e@example.com", Name: "E", TenantID: "tenant-1", Role: "viewer"},
+	)
+
+	var ids []string
+	var pages []int
+	target := "/api/v1/users?limit=2"
+	for i := 0; i < 10; i++ {
+		w := do(t, h, http.MethodGet, target, "tenant-1", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s", target, w.Code, w.Body)
+		}
+		var page ListResponse
+		decodeBody(t, w, &page)
+		pages = append(pages, len(page.Data))
+		for _, u := range page.Data {
+			ids = append(ids, u.ID)
+		}
+		if page.HasMore != (page.NextCursor != "") {
+			t.Fatalf("has_more = %v with next_cursor %q", page.HasMore, page.NextCursor)
+		}
+		if !page.HasMore {
+			break
+		}
+		target = "/api/v1/users?limit=2&cursor=" + page.NextCursor
+	}
+	if want := []string{"u1", "u2", "u3", "u4", "u5"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+	if want := []int{2, 2, 1}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("page sizes = %v, want %v", pages, want)
+	}
+
+	// A cursor stays valid when the user it points at is deleted.
+	w := do(t, h, http.MethodGet, "/api/v1/users?limit=2", "tenant-1", "")
+	var first ListResponse
+	decodeBody(t, w, &first)
+	do(t, h, http.MethodDelete, "/api/v1/users/u2", "tenant-1", "")
+	var next ListResponse
+	decodeBody(t, do(t, h, http.MethodGet, "/api/v1/users?limit=2&cursor="+first.NextCursor, "tenant-1", ""), &next)
+	if len(next.Data) == 0 || next.Data[0].ID != "u3" {
+		t.Errorf("page after a deleted cursor user = %+v, want it to start at u3", next.Data)
+	}
+
+	for _, q := range []string{"limit=0", "limit=101", "cursor=bogus", "role=owner"} {
+		w := do(t, h, http.MethodGet, "/api/v1/users?"+q, "tenant-1", "")
+		var body mockkit.ErrorResponse
+		decodeBody(t, w, &body)
+		if w.Code != http.StatusBadRequest || body.Error.Code != "validation_failed" || len(body.Error.Details) != 1 {
+			t.Errorf("GET ?%s = %d %+v, want a 400 with one detail", q, w.Code, body.Error)
+		}
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	h, _ := newTestServer(t,
+		User{ID: "u1", Email: "ann@example.com", Name: "Ann", TenantID: "tenant-1", Role: "user"},
+		User{ID: "x1", Email: "bob@example.com", Name: "Bob", TenantID: "tenant-2", Role: "user"},
+	)
+
+	var list ListResponse
+	decodeBody(t, do(t, h, http.MethodGet, "/api/v1/users", "tenant-1", ""), &list)
+	if len(list.Data) != 1 || list.Data[0].ID != "u1" {
+		t.Errorf("tenant-1 list = %+v, want only u1", list.Data)
+	}
+
+	for _, tt := range []struct{ method, body string }{
+		{http.MethodGet, ""},
+		{http.MethodPatch, `{"name":"Mallory"}`},
+		{http.MethodDelete, ""},
+	} {
+		if w := do(t, h, tt.method, "/api/v1/users/x1", "tenant-1", tt.body); w.Code != http.StatusNotFound {
+			t.Errorf("%s another tenant's user = %d, want 404", tt.method, w.Code)
+		}
+	}
+	if w := do(t, h, http.MethodGet, "/api/v1/users/x1", "tenant-2", ""); w.Code != http.StatusOK {
+		t.Errorf("GET own user = %d, want 200", w.Code)
+	}
+
+	// Emails are unique per tenant only.
+	if w := do(t, h, http.MethodPost, "/api/v1/users", "tenant-2", `{"email":"ann@example.com","name":"Ann"}`); w.Code != http.StatusCreated {
+		t.Errorf("create with another tenant's email = %d %s, want 201", w.Code, w.Body)
+	}
+
+	w := do(t, h, http.MethodGet, "/api/v1/users", "", "")
+	var body mockkit.ErrorResponse
+	decodeBody(t, w, &body)
+	if w.Code != http.StatusBadRequest || body.Error.Code != "tenant_required" {
+		t.Errorf("request without X-Tenant-ID = %d %+v", w.Code, body.Error)
+	}
+}
+
+func TestErrorEnvelopes(t *testing.T) {
+	h, _ := newTestServer(t, User{ID: "u1", Email: "ann@example.com", Name: "Ann", TenantID: "tenant-1", Role: "user"})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantFields []string
+	}{
+		{"missing fields", http.MethodPost, "/api/v1/users", `{}`, http.StatusUnprocessableEntity, "validation_failed", []string{"email", "name"}},
+		{"invalid fields", http.MethodPost, "/api/v1/users", `{"email":"not-an-email","name":"X","role":"owner","tenant_id":"tenant-2"}`, http.StatusUnprocessableEntity, "validation_failed", []string{"email", "role", "tenant_id"}},
+		{"PUT needs every field", http.MethodPut, "/api/v1/users/u1", `{"name":"Ann"}`, http.StatusUnprocessableEntity, "validation_failed", []string{"email", "role"}},
+		{"duplicate email", http.MethodPost, "/api/v1/users", `{"email":"ANN@example.com","name":"Other"}`, http.StatusConflict, "conflict", []string{"email"}},
+		{"second user", http.MethodPost, "/api/v1/users", `{"email":"bob@example.com","name":"Bob"}`, http.StatusCreated, "", nil},
+		{"unknown field", http.MethodPost, "/api/v1/users", `{"email":"c@example.com","name":"C","age":3}`, http.StatusBadRequest, "invalid_json", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(t, h, tt.method, tt.target, "tenant-1", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", w.Code, w.Body, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var body mockkit.ErrorResponse
+			decodeBody(t, w, &body)
+			var fields []string
+			for _, d := range body.Error.Details {
+				fields = append(fields, d.Field)
+			}
+			if body.Error.Code != tt.wantCode || !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("error = %s with fields %v, want %s with %v", body.Error.Code, fields, tt.wantCode, tt.wantFields)
+			}
+		})
+	}
+
+	// Changing the email onto a taken one conflicts as well.
+	var list ListResponse
+	decodeBody(t, do(t, h, http.MethodGet, "/api/v1/users?q=bob", "tenant-1", ""), &list)
+	if len(list.Data) != 1 {
+		t.Fatalf("search for bob = %+v", list.Data)
+	}
+	if w := do(t, h, http.MethodPatch, "/api/v1/users/"+list.Data[0].ID, "tenant-1", `{"email":"ann@example.com"}`); w.Code != http.StatusConflict {
+		t.Errorf("PATCH onto a taken email = %d, want 409", w.Code)
+	}
+}
+
+func TestStorePersistsToDataFile(t *testing.T) {
+	dir := t.TempDir()
+	seed := filepath.Join(dir, "users.json")
+	if err := os.WriteFile(seed, []byte(`[{"id":"user-1","email":"ann@example.com","name":"Ann","tenant_id":"tenant-1","role":"admin","created_at":"2024-01-01T00:00:00Z"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data := filepath.Join(dir, "data", "users.json")
+
+	store, err := OpenStore(data, seed)
+	if err != nil {
+		t.Fatalf("OpenStore() error = %v", err)
+	}
+	if store.Len() != 1 {
+		t.Fatalf("seeded %d users, want 1", store.Len())
+	}
+	created, err := store.Create(User{Email: "bob@example.com", Name: "Bob", TenantID: "tenant-1", Role: "user"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := store.Update("tenant-1", "user-1", func(u *User) error { u.Name = "Ann Admin"; return nil }); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := os.Stat(data + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	// Reopening reads the data file and no longer the seed.
+	if err := os.WriteFile(seed, []byte(`[]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenStore(data, seed)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got, err := reopened.Get("tenant-1", created.ID)
+	if err != nil || got.Email != "bob@example.com" || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("reopened user = %+v, %v", got, err)
+	}
+	if ann, _ := reopened.Get("tenant-1", "user-1"); ann.Name != "Ann Admin" {
+		t.Errorf("reopened user-1 name = %q, want the updated name", ann.Name)
+	}
+
+	if err := reopened.Delete("tenant-1", created.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	final, err := OpenStore(data, seed)
+	if err != nil || final.Len() != 1 {
+		t.Errorf("after delete the data file holds %d users (%v), want 1", final.Len(), err)
+	}
+}
+
+func TestStoreKeepsMemoryWhenSaveFails(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "blocker")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The data file sits under a regular file, so every save fails.
+	store := &Store{path: filepath.Join(blocker, "users.json")}
+	store.users = []*User{{ID: "user-1", Email: "ann@example.com", Name: "Ann", TenantID: "tenant-1", Role: "admin"}}
+
+	if _, err := store.Create(User{Email: "bob@example.com", Name: "Bob", TenantID: "tenant-1", Role: "user"}); err == nil {
+		t.Error("Create() succeeded without saving")
+	}
+	if store.Len() != 1 {
+		t.Errorf("after a failed Create the store holds %d users, want 1", store.Len())
+	}
+	if _, err := store.Update("tenant-1", "user-1", func(u *User) error { u.Name = "Ann Admin"; return nil }); err == nil {
+		t.Error("Update() succeeded without saving")
+	}
+	if ann, _ := store.Get("tenant-1", "user-1"); ann.Name != "Ann" {
+		t.Errorf("after a failed Update the name is %q, want Ann", ann.Name)
+	}
+	if err := store.Delete("tenant-1", "user-1"); err == nil {
+		t.Error("Delete() succeeded without saving")
+	}
+	if _, err := store.Get("tenant-1", "user-1"); err != nil {
+		t.Errorf("after a failed Delete: %v", err)
+	}
+}
@@ -1,36 +1,25 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
-	"net/http"
 	"os"
-)
-
-type HealthResponse struct {
-	Status  string `json:"status"`
-	Service string `json:"service"`
-}
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(HealthResponse{
-		Status:  "healthy",
-		Service: "user-service",
-	})
-}
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8082"
+	svc := mockkit.New("user-service", "8082")
+
+	// DATA_FILE, when set, keeps the users across restarts. It is seeded
+	// from USERS_FILE the first time.
+	store, err := OpenStore(os.Getenv("DATA_FILE"), mockkit.Env("USERS_FILE", "/fixtures/users.json"))
+	if err != nil {
+		log.Fatal(err)
 	}
+	log.Printf("Serving %d users", store.Len())
 
-	http.HandleFunc("/health", healthHandler)
-	
-	log.Printf("User Service mock service starting on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
+	(&UserServer{Store: store}).Register(svc)
+	if err := svc.Run(); err != nil {
 		log.Fatal(err)
 	}
 }
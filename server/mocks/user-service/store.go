@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vhvplatform/go-framework/mocks/mockkit"
+)
+
+// User is a user account. The password hash of the fixtures belongs to the
+// auth-service and is not kept.
+type User struct {
+	ID        string    `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	TenantID  string    `json:"tenant_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var (
+	errNotFound   = errors.New("user not found")
+	errEmailTaken = errors.New("email already in use")
+)
+
+// Store holds the users in memory and, when path is set, saves them to a
+// JSON file after every change.
+type Store struct {
+	mu    sync.Mutex
+	path  string
+	users []*User
+}
+
+// OpenStore loads the users from path, or from seed when path is empty or
+// does not exist yet.
+func OpenStore(path, seed string) (*Store, error) {
+	s := &Store{path: path}
+	if path != "" {
+		if err := s.load(path); err == nil {
+			log.Printf("Loaded users from %s", path)
+			return s, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	if err := s.load(seed); err != nil {
+		log.Printf("No fixture users loaded from %s: %v", seed, err)
+	} else {
+		log.Printf("Seeded users from %s", seed)
+	}
+	return s, nil
+}
+
+func (s *Store) load(path string) error {
+	var users []*User
+	if err := mockkit.LoadJSON(path, &users); err != nil {
+		return err
+	}
+	for _, u := range users {
+		if u.UpdatedAt.IsZero() {
+			u.UpdatedAt = u.CreatedAt
+		}
+	}
+	s.users = users
+	return nil
+}
+
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users)
+}
+
+// List returns the users of a tenant matching filter, in creation order,
+// starting after the given position.
+func (s *Store) List(tenant string, filter func(*User) bool, after *User, limit int) (page []User, more bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var users []*User
+	for _, u := range s.users {
+		if u.TenantID == tenant && filter(u) {
+			users = append(users, u)
+		}
+	}
+	sort.SliceStable(users, func(i, j int) bool { return before(users[i], users[j]) })
+
+	for _, u := range users {
+		if after != nil && !before(after, u) {
+			continue
+		}
+		if len(page) == limit {
+			return page, true
+		}
+		page = append(page, *u)
+	}
+	return page, false
+}
+
+// before orders users by creation time, then ID.
+func before(a, b *User) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+func (s *Store) Get(tenant, id string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u := s.find(tenant, id); u != nil {
+		return *u, nil
+	}
+	return User{}, errNotFound
+}
+
+func (s *Store) Create(u User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.emailTaken(u.TenantID, u.Email, "") {
+		return User{}, errEmailTaken
+	}
+	u.ID = "user-" + mockkit.RandomID(6)
+	u.CreatedAt = time.Now().UTC()
+	u.UpdatedAt = u.CreatedAt
+	if err := s.commit(append(slices.Clone(s.users), &u)); err != nil {
+		return User{}, err
+	}
+	return u, nil
+}
+
+// Update applies change to the user and saves it unless change fails.
+func (s *Store) Update(tenant, id string, change func(*User) error) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(tenant, id)
+	if i < 0 {
+		return User{}, errNotFound
+	}
+	updated := *s.users[i]
+	if err := change(&updated); err != nil {
+		return User{}, err
+	}
+	if s.emailTaken(tenant, updated.Email, id) {
+		return User{}, errEmailTaken
+	}
+	updated.UpdatedAt = time.Now().UTC()
+	users := slices.Clone(s.users)
+	users[i] = &updated
+	if err := s.commit(users); err != nil {
+		return User{}, err
+	}
+	return updated, nil
+}
+
+func (s *Store) Delete(tenant, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := s.index(tenant, id)
+	if i < 0 {
+		return errNotFound
+	}
+	return s.commit(slices.Delete(slices.Clone(s.users), i, i+1))
+}
+
+func (s *Store) find(tenant, id string) *User {
+	if i := s.index(tenant, id); i >= 0 {
+		return s.users[i]
+	}
+	return nil
+}
+
+func (s *Store) index(tenant, id string) int {
+	return slices.IndexFunc(s.users, func(u *User) bool { return u.TenantID == tenant && u.ID == id })
+}
+
+func (s *Store) emailTaken(tenant, email, except string) bool {
+	for _, u := range s.users {
+		if u.TenantID == tenant && u.ID != except && strings.EqualFold(u.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// commit saves users and only then makes them the content of the store, so
+// a change that could not be saved is not kept in memory either.
+func (s *Store) commit(users []*User) error {
+	if err := s.save(users); err != nil {
+		return err
+	}
+	s.users = users
+	return nil
+}
+
+// save writes users to the data file, if any. The file is replaced
+// atomically so a crash never leaves it half written.
+func (s *Store) save(users []*User) error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
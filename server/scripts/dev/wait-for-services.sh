@@ -15,6 +15,10 @@
 #   TIMEOUT - Maximum wait time in seconds (default: 300)
 #   INTERVAL - Check interval in seconds (default: 5)
 #
+# Prefer the CLI equivalent, which also checks /health JSON status and
+# backs off exponentially:
+#   saas wait [service...] --timeout 5m
+#
 # Exit Codes:
 #   0 - All services healthy
 #   1 - Timeout or service unhealthy
@@ -37,8 +41,8 @@ set -e
 echo "⏳ Waiting for services to be healthy..."
 
 DOCKER_DIR="$(dirname "$0")/../../docker"
-MAX_WAIT=120  # Maximum wait time in seconds
-SLEEP_INTERVAL=5
+MAX_WAIT=${TIMEOUT:-300}  # Maximum wait time in seconds
+SLEEP_INTERVAL=${INTERVAL:-5}
 
 services=(
     "localhost:27017"
@@ -25,7 +25,8 @@ response=$(curl -s "${API_BASE}/api/v1" || echo "Not available")
 echo "   Response: ${response}"
 echo ""
 
-# Test 3: Register user
+# Test 3: Register user. The gateway resolves the tenant from the host, so
+# the body names none.
 echo "3. User Registration"
 echo "   POST ${API_BASE}/api/v1/auth/register"
 response=$(curl -s -X POST "${API_BASE}/api/v1/auth/register" \
@@ -33,8 +34,7 @@ response=$(curl -s -X POST "${API_BASE}/api/v1/auth/register" \
     -d '{
         "email": "test'$(date +%s)'@example.com",
         "password": "testpassword123",
-        "name": "Test User",
-        "tenant_id": "test-tenant"
+        "name": "Test User"
     }' || echo "Registration endpoint not available")
 echo "   Response: ${response}"
 echo ""
@@ -0,0 +1,1030 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/history"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/k8s"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/runner"
+)
+
+const testSaasYAML = `project: test
+compose_files: [docker-compose.yml]
+services:
+  - name: auth-service
+    aliases: [auth]
+    groups: [core]
+  - name: api-gateway
+    aliases: [gateway]
+    groups: [core]
+  - name: mongodb
+    aliases: [mongo]
+    groups: [infra]
+`
+
+const testComposeYAML = `services:
+  mongodb: {}
+  auth-service:
+    build: ./auth
+    ports: ["50051:50051", "127.0.0.1:8081:8081/tcp"]
+    environment:
+      DB_HOST: mongodb
+      LOG_LEVEL: info
+      JWT_SECRET: ${JWT_SECRET:-dev}
+      CACHE_TTL: ${CACHE_TTL:-30s}
+    depends_on: [mongodb]
+  api-gateway:
+    build: ./gateway
+    depends_on: [auth-service]
+`
+
+// cli is a test harness that runs saas commands against a temporary
+// project with a recording runner.
+type cli struct {
+	t    *testing.T
+	dir  string
+	fake *runner.Fake
+	out  bytes.Buffer
+}
+
+func newCLI(t *testing.T) *cli {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range map[string]string{"saas.yaml": testSaasYAML, "docker-compose.yml": testComposeYAML} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &cli{t: t, dir: dir, fake: &runner.Fake{}}
+	prevRunner, prevPath := execRunner, configPath
+	execRunner, configPath, cfg, composeServicesAdded = c.fake, filepath.Join(dir, "saas.yaml"), nil, false
+	t.Cleanup(func() {
+		execRunner, cmdRunner, configPath, cfg, composeServicesAdded = prevRunner, prevRunner, prevPath, nil, false
+	})
+	return c
+}
+
+// failOn makes every command whose line ends with suffix fail.
+func (c *cli) failOn(suffix string) {
+	c.fake.Respond = func(cmd runner.Command) error {
+		if strings.HasSuffix(cmd.String(), suffix) {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+}
+
+// run executes the saas command line with flags reset to their defaults.
+func (c *cli) run(args ...string) error {
+	root := resetRootCmd()
+	resetFlags(root)
+	root.SetOut(&c.out)
+	root.SetErr(&c.out)
+	root.SetArgs(append(args, "--config", filepath.Join(c.dir, "saas.yaml")))
+	return root.Execute()
+}
+
+// commands returns the recorded command lines with the project directory
+// replaced by $DIR.
+func (c *cli) commands() []string {
+	var out []string
+	for _, line := range c.fake.Commands() {
+		out = append(out, strings.ReplaceAll(line, c.dir, "$DIR"))
+	}
+	return out
+}
+
+func (c *cli) expect(want ...string) {
+	c.t.Helper()
+	if got := c.commands(); !reflect.DeepEqual(got, want) {
+		c.t.Errorf("commands:\n  got  %q\n  want %q", got, want)
+	}
+}
+
+// resetFlags restores every flag of cmd and its subcommands to its default,
+// since the flag variables are shared package state.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+const composeCmd = "docker compose -f $DIR/docker-compose.yml"
+
+func TestStartInvocations(t *testing.T) {
+	c := newCLI(t)
+	if err := c.run("start", "gateway"); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	c.expect(
+		composeCmd+" up -d --no-deps mongodb",
+		composeCmd+" up -d --no-deps auth-service",
+		composeCmd+" up -d --no-deps api-gateway",
+	)
+}
+
+func TestStartFailureSkipsDependents(t *testing.T) {
+	c := newCLI(t)
+	c.failOn(" mongodb")
+
+	err := c.run("start", "gateway")
+	if err == nil || !strings.Contains(err.Error(), "failed to start services") {
+		t.Fatalf("start error = %v", err)
+	}
+	c.expect(composeCmd + " up -d --no-deps mongodb")
+}
+
+func TestStartViaMake(t *testing.T) {
+	c := newCLI(t)
+	if err := c.run("start", "--via-make", "auth"); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	c.expect("make restart-service SERVICE=auth-service")
+	if dir := c.fake.Calls()[0].Dir; dir != c.dir {
+		t.Errorf("make ran in %q, want the project directory %q", dir, c.dir)
+	}
+
+	err := c.run("start", "--via-make", "--group", "core")
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != exitInvalidArgs {
+		t.Errorf("--via-make with --group: error = %v, want exit code %d", err, exitInvalidArgs)
+	}
+}
+
+func TestWaitRejectsNonPositiveInterval(t *testing.T) {
+	c := newCLI(t)
+	for _, interval := range []string{"0", "-1s"} {
+		err := c.run("wait", "mongodb", "--interval", interval)
+		var exit *exitError
+		if !errors.As(err, &exit) || exit.code != exitInvalidArgs || !strings.Contains(err.Error(), "--interval must be greater than zero") {
+			t.Errorf("--interval %s: error = %v, want exit code %d", interval, err, exitInvalidArgs)
+		}
+	}
+}
+
+func TestStartWaitSkipsServicesWithoutPort(t *testing.T) {
+	c := newCLI(t)
+	// mongodb has no port in saas.yaml, so only auth-service is waited for.
+	if err := c.run("start", "mongo", "auth", "--wait", "--dry-run"); err != nil {
+		t.Fatalf("start --wait: %v", err)
+	}
+}
+
+// exitStatus returns the error of a process that exited with code.
+func exitStatus(t *testing.T, code int) error {
+	t.Helper()
+	err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	if err == nil {
+		t.Fatalf("sh exited 0, want %d", code)
+	}
+	return err
+}
+
+func TestShellFallsBackToBashOnlyWithoutSh(t *testing.T) {
+	const probe = composeCmd + " exec -T auth-service sh -c true"
+	for _, tt := range []struct {
+		name         string
+		probeStatus  int
+		shellStatus  int
+		wantCommands []string
+		wantCode     int
+	}{
+		{"sh works", 0, 0, []string{probe, composeCmd + " exec auth-service sh"}, 0},
+		{"sh missing", 127, 0, []string{probe, composeCmd + " exec auth-service bash"}, 0},
+		{"sh not executable", 126, 0, []string{probe, composeCmd + " exec auth-service bash"}, 0},
+		{"last command failed", 0, 1, []string{probe, composeCmd + " exec auth-service sh"}, 1},
+		// A session whose last command was not found also exits 127.
+		{"last command not found", 0, 127, []string{probe, composeCmd + " exec auth-service sh"}, 127},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCLI(t)
+			c.fake.Respond = func(cmd runner.Command) error {
+				status := tt.shellStatus
+				if slices.Contains(cmd.Args, "-T") {
+					status = tt.probeStatus
+				}
+				if status != 0 {
+					return exitStatus(t, status)
+				}
+				return nil
+			}
+			err := c.run("shell", "auth")
+
+			c.expect(tt.wantCommands...)
+			var exit *exitError
+			switch {
+			case tt.wantCode == 0 && err != nil:
+				t.Errorf("error = %v, want nil", err)
+			case tt.wantCode != 0 && (!errors.As(err, &exit) || exit.code != tt.wantCode || exit.err != nil):
+				t.Errorf("error = %v, want a silent exit code %d", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestStartUnknownService(t *testing.T) {
+	c := newCLI(t)
+	err := c.run("start", "auht")
+	if err == nil || !strings.Contains(err.Error(), `did you mean auth-service?`) {
+		t.Errorf("start error = %v", err)
+	}
+	c.expect()
+}
+
+func TestStopInvocations(t *testing.T) {
+	c := newCLI(t)
+	if err := c.run("stop", "auth", "mongo"); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	c.expect(
+		composeCmd+" stop auth-service",
+		composeCmd+" stop mongodb",
+	)
+
+	c.fake.Reset()
+	if err := c.run("stop"); err != nil {
+		t.Fatalf("stop all: %v", err)
+	}
+	c.expect(
+		composeCmd+" stop api-gateway",
+		composeCmd+" stop auth-service",
+		composeCmd+" stop mongodb",
+		composeCmd+" down",
+	)
+}
+
+func TestStopFailure(t *testing.T) {
+	c := newCLI(t)
+	c.failOn(" stop auth-service")
+
+	err := c.run("stop")
+	if err == nil || !strings.Contains(err.Error(), "failed to stop services") {
+		t.Fatalf("stop error = %v", err)
+	}
+	for _, line := range c.commands() {
+		if strings.HasSuffix(line, " down") {
+			t.Errorf("down must not run after a failed stop: %q", c.commands())
+		}
+	}
+}
+
+func TestStopEmptySelectionStopsNothing(t *testing.T) {
+	c := newCLI(t)
+	err := c.run("stop", "--all-except", "auth,gateway,mongo")
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != exitInvalidArgs || !strings.Contains(err.Error(), "no services") {
+		t.Errorf("stop error = %v, want an invalid-arguments error", err)
+	}
+	c.expect()
+}
+
+func TestLogsInvocations(t *testing.T) {
+	c := newCLI(t)
+	c.fake.Respond = func(cmd runner.Command) error {
+		io.WriteString(cmd.Stdout, `{"level":"error","msg":"boom"}`+"\nstarted\n")
+		return nil
+	}
+
+	if err := c.run("logs", "auth", "-f", "--since", "10m", "--tail", "50", "--level", "error"); err != nil {
+		t.Fatalf("logs: %v", err)
+	}
+	c.expect(composeCmd + " logs --no-color --no-log-prefix --follow --since 10m --tail 50 auth-service")
+	if got, want := c.out.String(), "auth-service | {\"level\":\"error\",\"msg\":\"boom\"}\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestLogsFailure(t *testing.T) {
+	c := newCLI(t)
+	c.failOn(" auth-service")
+
+	err := c.run("logs", "auth")
+	if err == nil || !strings.Contains(err.Error(), "failed to view logs") {
+		t.Errorf("logs error = %v", err)
+	}
+
+	err = c.run("logs", "--grep", "(")
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != exitInvalidArgs {
+		t.Errorf("invalid --grep: error = %v, want exit code %d", err, exitInvalidArgs)
+	}
+}
+
+func TestTestInvocations(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"test"}, "make test"},
+		{[]string{"test", "--type", "unit"}, "make test-unit"},
+		{[]string{"test", "--type", "integration"}, "make test-integration"},
+		{[]string{"test", "--type", "e2e"}, "make test-e2e"},
+		{[]string{"test", "--type", "load"}, "make test-load"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			c := newCLI(t)
+			if err := c.run(tt.args...); err != nil {
+				t.Fatalf("test: %v", err)
+			}
+			c.expect(tt.want)
+		})
+	}
+}
+
+func TestTestFailure(t *testing.T) {
+	c := newCLI(t)
+	c.failOn("make test-unit")
+
+	err := c.run("test", "--type", "unit")
+	if err == nil || !strings.Contains(err.Error(), "tests failed") {
+		t.Errorf("test error = %v", err)
+	}
+}
+
+const testBaseYAML = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: test
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-config
+  namespace: test
+data:
+  LOG_LEVEL: info
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api-gateway
+  namespace: test
+  labels: {component: gateway}
+spec:
+  template:
+    spec:
+      containers:
+      - name: api-gateway
+        image: registry.test/go-api-gateway:latest
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api-gateway
+  namespace: test
+spec:
+  type: LoadBalancer
+`
+
+// writeBase writes the Kubernetes manifests rendered by saas deploy.
+func (c *cli) writeBase() {
+	c.t.Helper()
+	dir := filepath.Join(c.dir, "k8s", "base")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		c.t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "platform.yaml"), []byte(testBaseYAML), 0o644); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// fakeCluster makes saas deploy talk to an in-memory cluster.
+func (c *cli) fakeCluster() *k8s.Fake {
+	cluster := &k8s.Fake{}
+	prev := newKubeClient
+	newKubeClient = func(*config.Environment) k8s.Client { return cluster }
+	c.t.Cleanup(func() { newKubeClient = prev })
+	return cluster
+}
+
+func TestDeployInvocations(t *testing.T) {
+	c := newCLI(t)
+	c.writeBase()
+	if err := c.run("deploy", "dev", "--image-tag", "v2"); err != nil {
+		t.Fatalf("deploy dev: %v", err)
+	}
+	c.expect(
+		"git rev-parse --short=12 HEAD",
+		"kubectl diff -f -",
+		"kubectl apply -f -",
+		"kubectl apply -f -",
+		"kubectl apply -f -",
+		"kubectl apply -f -",
+		"kubectl rollout status deployment/api-gateway -n go-dev --timeout 5m0s",
+	)
+
+	c.fake.Reset()
+	if err := c.run("deploy", "local", "--via-make"); err != nil {
+		t.Fatalf("deploy local --via-make: %v", err)
+	}
+	c.expect("make deploy-local")
+
+	c.fake.Reset()
+	err := c.run("deploy", "staging", "--via-make")
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != exitInvalidArgs || !strings.Contains(err.Error(), "only has deploy targets for local and dev") {
+		t.Errorf("deploy staging --via-make: error = %v, want exit code %d", err, exitInvalidArgs)
+	}
+	c.expect()
+}
+
+func TestDeployToFakeCluster(t *testing.T) {
+	c := newCLI(t)
+	c.writeBase()
+	cluster := c.fakeCluster()
+
+	image := func() string {
+		live, ok := cluster.Get("Deployment", "go-dev", "api-gateway")
+		if !ok {
+			t.Fatal("api-gateway was not deployed")
+		}
+		return k8s.Images([]k8s.Object{live})["api-gateway"]
+	}
+
+	if err := c.run("deploy", "dev", "--image-tag", "v1"); err != nil {
+		t.Fatalf("deploy v1: %v", err)
+	}
+	if err := c.run("deploy", "dev", "--image-tag", "api-gateway=v2", "--set", "LOG_LEVEL=warn", "--set", "deployment/api-gateway:spec.replicas=4"); err != nil {
+		t.Fatalf("deploy v2: %v", err)
+	}
+	if got := image(); got != "registry.test/go-api-gateway:v2" {
+		t.Errorf("image = %s, want v2", got)
+	}
+	live, _ := cluster.Get("Deployment", "go-dev", "api-gateway")
+	if got := live["spec"].(map[string]any)["replicas"]; got != 4 {
+		t.Errorf("replicas = %v, want --set to override the overlay", got)
+	}
+	cm, _ := cluster.Get("ConfigMap", "go-dev", "test-config")
+	if want := map[string]any{"LOG_LEVEL": "warn", "ENVIRONMENT": "development", "MONGODB_DATABASE": "go_dev"}; !reflect.DeepEqual(cm["data"], want) {
+		t.Errorf("config data = %v, want %v", cm["data"], want)
+	}
+
+	c.out.Reset()
+	cluster.ResetLog()
+	if err := c.run("deploy", "dev", "--image-tag", "v3", "--diff"); err != nil {
+		t.Fatalf("deploy --diff: %v", err)
+	}
+	if got := cluster.Log(); !reflect.DeepEqual(got, []string{"diff"}) {
+		t.Errorf("--diff must not apply, got %q", got)
+	}
+	if !strings.Contains(c.out.String(), "~ deployment/api-gateway") {
+		t.Errorf("--diff output:\n%s", c.out.String())
+	}
+
+	if err := c.run("deploy", "dev", "--rollback"); err != nil {
+		t.Fatalf("deploy --rollback: %v", err)
+	}
+	if got := image(); got != "registry.test/go-api-gateway:v1" {
+		t.Errorf("image after rollback = %s, want v1", got)
+	}
+}
+
+func TestDeployHistory(t *testing.T) {
+	c := newCLI(t)
+	c.writeBase()
+	cluster := c.fakeCluster()
+	c.fake.Respond = func(cmd runner.Command) error {
+		if cmd.Name == "git" {
+			fmt.Fprintln(cmd.Stdout, "3f2c1d0a9b8e")
+		}
+		return nil
+	}
+
+	image := func() string {
+		live, _ := cluster.Get("Deployment", "go-dev", "api-gateway")
+		return k8s.Images([]k8s.Object{live})["api-gateway"]
+	}
+
+	for _, tag := range []string{"v1", "v2"} {
+		if err := c.run("deploy", "dev", "--image-tag", tag); err != nil {
+			t.Fatalf("deploy %s: %v", tag, err)
+		}
+	}
+	live, _ := cluster.Get("Deployment", "go-dev", "api-gateway")
+	annotations := live["metadata"].(map[string]any)["annotations"].(map[string]any)
+	if annotations["saas.vhvplatform.io/deployment-id"] != "2" || annotations["saas.vhvplatform.io/git-sha"] != "3f2c1d0a9b8e" {
+		t.Errorf("annotations = %v", annotations)
+	}
+
+	// A failed deployment is recorded but never rolled back to.
+	cluster.Fail = func(op string, obj k8s.Object) error {
+		if op == "rollout-status" {
+			return errors.New("progress deadline exceeded")
+		}
+		return nil
+	}
+	if err := c.run("deploy", "dev", "--image-tag", "v3"); err == nil {
+		t.Fatal("deploy v3 should fail")
+	}
+	cluster.Fail = nil
+
+	c.out.Reset()
+	if err := c.run("deploy", "history", "dev"); err != nil {
+		t.Fatalf("deploy history: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(c.out.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "ID") {
+		t.Fatalf("history output:\n%s", c.out.String())
+	}
+	for i, want := range []string{"3 .* failed .*api-gateway:v3", "2 .* 3f2c1d0a9b8e .* succeeded .*api-gateway:v2", "1 .* succeeded .*api-gateway:v1"} {
+		if !regexp.MustCompile(want).MatchString(lines[i+1]) {
+			t.Errorf("history line %d = %q, want %q", i+1, lines[i+1], want)
+		}
+	}
+
+	if err := c.run("deploy", "rollback", "dev", "--to", "3"); err == nil || !strings.Contains(err.Error(), "deployment #3 of dev failed") {
+		t.Errorf("rollback to a failed deployment: error = %v", err)
+	}
+	if err := c.run("deploy", "rollback", "dev", "--to", "9"); err == nil || !strings.Contains(err.Error(), "no deployment 9 of dev") {
+		t.Errorf("rollback to a missing deployment: error = %v", err)
+	}
+
+	// Without --to, the deployment before the last successful one is restored.
+	if err := c.run("deploy", "rollback", "dev"); err != nil {
+		t.Fatalf("deploy rollback: %v", err)
+	}
+	if got := image(); got != "registry.test/go-api-gateway:v1" {
+		t.Errorf("image after rollback = %s, want v1", got)
+	}
+	if err := c.run("deploy", "rollback", "dev", "--to", "#2"); err != nil {
+		t.Fatalf("deploy rollback --to #2: %v", err)
+	}
+	if got := image(); got != "registry.test/go-api-gateway:v2" {
+		t.Errorf("image after rollback to #2 = %s, want v2", got)
+	}
+
+	c.out.Reset()
+	if err := c.run("deploy", "history", "dev", "-o", "json", "-n", "2"); err != nil {
+		t.Fatalf("deploy history -o json: %v", err)
+	}
+	var records []history.Record
+	if err := json.Unmarshal(c.out.Bytes(), &records); err != nil {
+		t.Fatalf("history json: %v\n%s", err, c.out.String())
+	}
+	if len(records) != 2 || records[0].ID != 5 || records[0].RollbackOf != 2 || records[1].RollbackOf != 1 {
+		t.Errorf("records = %+v", records)
+	}
+	if records[0].GitSHA != "3f2c1d0a9b8e" || records[0].Images["api-gateway"] != "registry.test/go-api-gateway:v2" || records[0].ConfigHash == "" {
+		t.Errorf("record #5 = %+v", records[0])
+	}
+
+	// Dry runs and diffs are not recorded.
+	if err := c.run("deploy", "dev", "--dry-run"); err != nil {
+		t.Fatalf("deploy --dry-run: %v", err)
+	}
+	if err := c.run("deploy", "dev", "--diff"); err != nil {
+		t.Fatalf("deploy --diff: %v", err)
+	}
+	if got, _ := (&history.Store{Dir: filepath.Join(c.dir, ".saas", "deployments")}).NextID("dev"); got != 6 {
+		t.Errorf("next id = %d, want 6", got)
+	}
+
+	var exit *exitError
+	if err := c.run("deploy", "history", "dev", "-o", "yaml"); !errors.As(err, &exit) || exit.code != exitInvalidArgs {
+		t.Errorf("history -o yaml: error = %v", err)
+	}
+	c.out.Reset()
+	if err := c.run("deploy", "history", "local"); err != nil || !strings.Contains(c.out.String(), "No deployments of local recorded yet") {
+		t.Errorf("history of a new environment: %v\n%s", err, c.out.String())
+	}
+	if err := c.run("deploy", "rollback", "local"); err == nil {
+		t.Error("rollback without history should fail")
+	}
+}
+
+func TestPromote(t *testing.T) {
+	c := newCLI(t)
+	c.writeBase()
+	overlay := filepath.Join(c.dir, "k8s", "overlays", "staging")
+	if err := os.MkdirAll(overlay, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	pdb := "apiVersion: policy/v1\nkind: PodDisruptionBudget\nmetadata: {name: api-gateway, namespace: test}\nspec: {minAvailable: 1}\n"
+	if err := os.WriteFile(filepath.Join(overlay, "pdb.yaml"), []byte(pdb), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cluster := c.fakeCluster()
+
+	var exit *exitError
+	if err := c.run("promote", "dev", "dev"); !errors.As(err, &exit) || exit.code != exitInvalidArgs {
+		t.Errorf("promote to itself: error = %v", err)
+	}
+	if err := c.run("promote", "dev", "staging", "--confirm", "--approved-by", "alice"); err == nil || !strings.Contains(err.Error(), "nothing to promote") {
+		t.Errorf("promote without history: error = %v", err)
+	}
+
+	for _, tag := range []string{"v1", "v2"} {
+		if err := c.run("deploy", "dev", "--image-tag", tag); err != nil {
+			t.Fatalf("deploy %s: %v", tag, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "staging is a protected environment; pass --confirm"},
+		{[]string{"--confirm"}, "staging requires 1 approval(s)"},
+		{[]string{"--confirm", "--approved-by", currentUser()}, "staging requires 1 approval(s)"},
+	} {
+		err := c.run(append([]string{"promote", "dev", "staging"}, tt.args...)...)
+		if !errors.As(err, &exit) || exit.code != exitInvalidArgs || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("promote %v: error = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+	if err := c.run("deploy", "staging", "--image-tag", "v2"); err == nil || !strings.Contains(err.Error(), "protected") {
+		t.Errorf("deploy to staging without --confirm: error = %v", err)
+	}
+	if _, ok := cluster.Get("Deployment", "go-staging", "api-gateway"); ok {
+		t.Fatal("a refused promotion reached the cluster")
+	}
+
+	if err := c.run("promote", "dev", "staging", "--confirm", "--approved-by", "alice"); err != nil {
+		t.Fatalf("promote dev staging: %v", err)
+	}
+	live, ok := cluster.Get("Deployment", "go-staging", "api-gateway")
+	if !ok {
+		t.Fatal("api-gateway was not promoted to go-staging")
+	}
+	if got := k8s.Images([]k8s.Object{live})["api-gateway"]; got != "registry.test/go-api-gateway:v2" {
+		t.Errorf("promoted image = %s, want v2", got)
+	}
+	if _, ok := cluster.Get("PodDisruptionBudget", "go-staging", "api-gateway"); !ok {
+		t.Error("the manifests of overlay_path were not applied")
+	}
+	if cm, _ := cluster.Get("ConfigMap", "go-staging", "test-config"); cm["data"].(map[string]any)["ENVIRONMENT"] != "staging" {
+		t.Errorf("staging config = %v, want the overlay of staging", cm["data"])
+	}
+
+	records, err := historyStore(&config.Config{Dir: c.dir}).List("staging")
+	if err != nil || len(records) != 1 {
+		t.Fatalf("staging history = %v, %v", records, err)
+	}
+	if r := records[0]; r.PromotedFrom != "dev#2" || !reflect.DeepEqual(r.ApprovedBy, []string{"alice"}) || r.Status != history.StatusSucceeded {
+		t.Errorf("staging record = %+v", r)
+	}
+
+	// An earlier deployment can be promoted, and dry runs skip the checks.
+	if err := c.run("promote", "dev", "staging", "--id", "1", "--dry-run"); err != nil {
+		t.Errorf("promote --id 1 --dry-run: %v", err)
+	}
+}
+
+func TestDeployFailures(t *testing.T) {
+	c := newCLI(t)
+	c.writeBase()
+
+	var exit *exitError
+	if err := c.run("deploy", "prod"); !errors.As(err, &exit) || exit.code != exitInvalidArgs || !strings.Contains(err.Error(), "unknown environment: prod (available: local, dev, staging)") {
+		t.Errorf("deploy prod error = %v", err)
+	}
+	if err := c.run("deploy"); err == nil {
+		t.Error("deploy without an environment should fail")
+	}
+	if err := c.run("deploy", "dev", "--set", "broken"); !errors.As(err, &exit) || exit.code != exitInvalidArgs {
+		t.Errorf("invalid --set: error = %v, want exit code %d", err, exitInvalidArgs)
+	}
+	if err := c.run("deploy", "dev", "--set", "deployment/nope:spec.replicas=1"); err == nil || !strings.Contains(err.Error(), "object not found") {
+		t.Errorf("--set on a missing object: error = %v", err)
+	}
+	c.expect()
+
+	c.failOn("make deploy-dev")
+	if err := c.run("deploy", "dev", "--via-make"); err == nil || !strings.Contains(err.Error(), "deployment failed") {
+		t.Errorf("deploy dev --via-make error = %v", err)
+	}
+
+	cluster := c.fakeCluster()
+	cluster.Fail = func(op string, obj k8s.Object) error {
+		if op == "rollout-status" {
+			return errors.New("timed out")
+		}
+		return nil
+	}
+	err := c.run("deploy", "dev")
+	if err == nil || !strings.Contains(err.Error(), "deployment failed: rollout of deployment/api-gateway did not finish") {
+		t.Errorf("deploy error = %v", err)
+	}
+}
+
+func TestK8sGenerate(t *testing.T) {
+	c := newCLI(t)
+	c.writeBase()
+	base := filepath.Join(c.dir, "k8s", "base")
+
+	if err := c.run("k8s", "generate", "auth"); err != nil {
+		t.Fatalf("k8s generate: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(base, "auth-service.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, err := k8s.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("generated manifest does not decode: %v", err)
+	}
+	var refs []string
+	for _, obj := range objs {
+		refs = append(refs, obj.Ref())
+	}
+	if want := []string{"configmap/auth-service-config", "deployment/auth-service", "service/auth-service"}; !reflect.DeepEqual(refs, want) {
+		t.Fatalf("objects = %v, want %v", refs, want)
+	}
+	// LOG_LEVEL is in the shared ConfigMap and JWT_SECRET is a credential.
+	if want := map[string]any{"DB_HOST": "mongodb-service", "CACHE_TTL": "30s"}; !reflect.DeepEqual(objs[0]["data"], want) {
+		t.Errorf("ConfigMap data = %v, want %v", objs[0]["data"], want)
+	}
+	if got := k8s.Images(objs)["auth-service"]; got != "<your-registry>/go-auth-service:latest" {
+		t.Errorf("image = %s", got)
+	}
+	if !strings.Contains(string(data), "port: 50051") || !strings.Contains(string(data), "path: /health") {
+		t.Errorf("manifest should expose both ports and probe /health:\n%s", data)
+	}
+
+	// Existing files are kept without --force.
+	os.WriteFile(filepath.Join(base, "auth-service.yaml"), []byte("# hand-written\n"), 0o644)
+	if err := c.run("k8s", "generate", "auth"); err != nil {
+		t.Fatalf("k8s generate: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(base, "auth-service.yaml")); string(data) != "# hand-written\n" {
+		t.Error("existing manifest was overwritten without --force")
+	}
+	if err := c.run("k8s", "generate", "auth", "--force"); err != nil {
+		t.Fatalf("k8s generate --force: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(base, "auth-service.yaml")); string(data) == "# hand-written\n" {
+		t.Error("--force should overwrite the manifest")
+	}
+
+	var exit *exitError
+	if err := c.run("k8s", "generate", "mongo"); !errors.As(err, &exit) || exit.code != exitInvalidArgs {
+		t.Errorf("generate mongo: error = %v, want exit code %d", err, exitInvalidArgs)
+	}
+	if err := c.run("k8s", "generate", "gateway"); err == nil || !strings.Contains(err.Error(), "publishes no ports") {
+		t.Errorf("generate gateway: error = %v", err)
+	}
+}
+
+func TestK8sBaseMatchesGenerate(t *testing.T) {
+	newCLI(t)
+	var out bytes.Buffer
+	root := resetRootCmd()
+	resetFlags(root)
+	root.SetOut(&out)
+	root.SetArgs([]string{"k8s", "generate", "--stdout", "--config", "../../saas.yaml"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("k8s generate: %v", err)
+	}
+
+	generated := map[string]string{}
+	// Each manifest starts with "---" and its header comment; the documents
+	// within one are separated by a bare "---".
+	for _, manifest := range strings.Split(out.String(), "---\n# ")[1:] {
+		name, _, _ := strings.Cut(manifest, " ")
+		generated[name] = "# " + manifest
+	}
+	files, err := filepath.Glob("../../k8s/base/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checked := 0
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".yaml")
+		if !strings.HasPrefix(string(data), generatedHeader(name)) {
+			continue // hand-written
+		}
+		checked++
+		if string(data) != generated[name] {
+			t.Errorf("%s differs from `saas k8s generate %s`; regenerate it with --force", path, name)
+		}
+		for _, key := range []string{"USERS_FILE", "DATA_FILE", "TENANTS_FILE", "ROUTES_FILE", "CONFIG_FILE", "SMTP_LISTEN_PORT"} {
+			if strings.Contains(string(data), key) {
+				t.Errorf("%s holds the mock-only variable %s", path, key)
+			}
+		}
+	}
+	if checked == 0 {
+		t.Error("no generated manifests in server/k8s/base")
+	}
+}
+
+// fakeFaultAPI serves the /__admin/faults API of a mock and records the
+// requests it gets.
+func (c *cli) fakeFaultAPI() *[]string {
+	c.t.Helper()
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && strings.Contains(string(body), `"status":700`):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"error":{"code":"validation_failed","message":"the fault is invalid","details":[{"field":"status","message":"must be from 200 to 599"}]}}`)
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"id":"fault-1","path":"/api/v1/auth/login","status":503,"rate":0.5,"ttl":"1m0s","expires_at":"2030-01-01T00:00:00Z"}`)
+		case r.Method == http.MethodGet:
+			fmt.Fprint(w, `{"data":[{"id":"fault-1","path":"/api/v1/auth/login","status":503,"rate":0.5,"hits":3,"expires_at":"2030-01-01T00:00:00Z"}]}`)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	c.t.Cleanup(srv.Close)
+
+	u, _ := url.Parse(srv.URL)
+	yaml := fmt.Sprintf(`project: test
+host: %s
+compose_files: [docker-compose.yml]
+services:
+  - name: auth-service
+    aliases: [auth]
+    port: %s
+    health_path: /health
+    groups: [core]
+  - name: mongodb
+    port: 27017
+    groups: [infra]
+`, u.Hostname(), u.Port())
+	if err := os.WriteFile(filepath.Join(c.dir, "saas.yaml"), []byte(yaml), 0o644); err != nil {
+		c.t.Fatal(err)
+	}
+	return &requests
+}
+
+func TestMockFault(t *testing.T) {
+	c := newCLI(t)
+	requests := c.fakeFaultAPI()
+
+	if err := c.run("mock", "fault", "add", "auth", "--path", "/api/v1/auth/login", "--method", "post", "--status", "503", "--rate", "0.5", "--ttl", "1m"); err != nil {
+		t.Fatalf("mock fault add: %v", err)
+	}
+	if err := c.run("mock", "fault", "list"); err != nil {
+		t.Fatalf("mock fault list: %v", err)
+	}
+	if err := c.run("mock", "fault", "clear", "auth", "--id", "fault-1"); err != nil {
+		t.Fatalf("mock fault clear: %v", err)
+	}
+	if err := c.run("mock", "fault", "clear"); err != nil {
+		t.Fatalf("mock fault clear: %v", err)
+	}
+
+	want := []string{
+		`POST /__admin/faults {"path":"/api/v1/auth/login","method":"POST","status":503,"rate":0.5,"ttl":"1m0s"}`,
+		"GET /__admin/faults",
+		"DELETE /__admin/faults/fault-1",
+		"DELETE /__admin/faults",
+	}
+	if !reflect.DeepEqual(*requests, want) {
+		t.Errorf("requests:\n  got  %q\n  want %q", *requests, want)
+	}
+	for _, line := range []string{
+		"💥 auth-service fault-1: status 503 on * /api/v1/auth/login*",
+		"auth-service  fault-1  * /api/v1/auth/login*  status 503  0.5   3",
+		"🧹 auth-service: removed fault-1",
+		"🧹 auth-service: cleared",
+	} {
+		if !strings.Contains(c.out.String(), line) {
+			t.Errorf("output should contain %q, got:\n%s", line, c.out.String())
+		}
+	}
+}
+
+func TestMockFaultErrors(t *testing.T) {
+	c := newCLI(t)
+	requests := c.fakeFaultAPI()
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no effect", []string{"add", "auth"}, "give at least one of"},
+		{"no HTTP port", []string{"add", "mongodb", "--latency", "1s"}, `service "mongodb" has no HTTP port`},
+		{"rejected", []string{"add", "auth", "--status", "700"}, "auth-service rejected the rule: the fault is invalid; status must be from 200 to 599"},
+		{"id without service", []string{"clear", "--id", "fault-1"}, "--id needs exactly one service"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.run(append([]string{"mock", "fault"}, tt.args...)...)
+			var exit *exitError
+			if !errors.As(err, &exit) || exit.code != exitInvalidArgs || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want exit code %d and %q", err, exitInvalidArgs, tt.want)
+			}
+		})
+	}
+
+	*requests = nil
+	if err := c.run("mock", "fault", "add", "auth", "--drop", "--dry-run"); err != nil {
+		t.Fatalf("add --dry-run: %v", err)
+	}
+	if len(*requests) != 0 || !strings.Contains(c.out.String(), "Would add to auth-service: drop on * /*") {
+		t.Errorf("dry run sent %q and printed:\n%s", *requests, c.out.String())
+	}
+}
+
+func TestExecuteExitCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, 0},
+		{"plain error", errors.New("boom"), 1},
+		{"exit code", withExitCode(exitInvalidArgs, errors.New("bad flag")), exitInvalidArgs},
+		{"silent exit code", withExitCode(exitUnhealthy, nil), exitUnhealthy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &cobra.Command{
+				Use:           "saas",
+				SilenceErrors: true,
+				SilenceUsage:  true,
+				RunE:          func(*cobra.Command, []string) error { return tt.err },
+			}
+			root.SetArgs(nil)
+			if got := execute(root); got != tt.want {
+				t.Errorf("execute() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	c := newCLI(t)
+	if err := c.run("stop", "auth", "--dry-run"); err != nil {
+		t.Fatalf("stop --dry-run: %v", err)
+	}
+	c.expect()
+	if !strings.Contains(c.out.String(), "+ docker compose -f "+c.dir+"/docker-compose.yml stop auth-service\n") {
+		t.Errorf("dry run should print the stop command, got:\n%s", c.out.String())
+	}
+
+	c.out.Reset()
+	c.writeBase()
+	if err := c.run("deploy", "dev", "--dry-run"); err != nil {
+		t.Fatalf("deploy --dry-run: %v", err)
+	}
+	// Reading the commit is not a change, so it runs.
+	c.expect("git rev-parse --short=12 HEAD")
+	// Each apply shows the manifest it would send.
+	out := c.out.String()
+	if !strings.Contains(out, "+ kubectl apply -f - <<'EOF'\n") {
+		t.Errorf("dry run should print the kubectl commands, got:\n%s", out)
+	}
+	for _, want := range []string{"kind: Deployment\n", "  name: api-gateway\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dry run output lacks %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestVerbose(t *testing.T) {
+	c := newCLI(t)
+	if err := c.run("test", "--type", "unit", "-v"); err != nil {
+		t.Fatalf("test -v: %v", err)
+	}
+	c.expect("make test-unit")
+
+	out := c.out.String()
+	if !strings.Contains(out, "▶ make test-unit\n") || !strings.Contains(out, "✔ make test-unit (") {
+		t.Errorf("verbose output should time the command, got:\n%s", out)
+	}
+}
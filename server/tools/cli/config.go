@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/compose"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+)
+
+var (
+	configOutput string
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show or validate the CLI configuration",
+	Long: `Inspect the project configuration in saas.yaml.
+
+The file is looked up in the current directory and its parents, unless
+--config or $SAAS_CONFIG points at one. Without a file the built-in
+defaults are used.
+
+Examples:
+  saas config show            # Print the effective configuration
+  saas config show -o json    # ...as JSON
+  saas config validate        # Check saas.yaml against docker-compose.yml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		source := c.Path
+		if source == "" {
+			source = "built-in defaults"
+		}
+
+		switch configOutput {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(c); err != nil {
+				return fmt.Errorf("failed to encode configuration: %w", err)
+			}
+		case "yaml":
+			fmt.Printf("# Source: %s\n", source)
+			enc := yaml.NewEncoder(os.Stdout)
+			enc.SetIndent(2)
+			if err := enc.Encode(c); err != nil {
+				return fmt.Errorf("failed to encode configuration: %w", err)
+			}
+			return enc.Close()
+		default:
+			return withExitCode(exitInvalidArgs, fmt.Errorf("unknown output format: %s (use yaml or json)", configOutput))
+		}
+		return nil
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate saas.yaml against the compose files",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if c.Path == "" {
+			fmt.Printf("⚠️  No %s found, validating built-in defaults\n", config.FileName)
+		} else {
+			fmt.Printf("🔍 Validating %s...\n", c.Path)
+		}
+
+		err = c.Validate()
+		if err == nil {
+			err = validateComposeServices(c)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "❌ Configuration is invalid:")
+			for _, e := range unwrapAll(err) {
+				fmt.Fprintf(os.Stderr, "   - %v\n", e)
+			}
+			return withExitCode(1, nil)
+		}
+
+		fmt.Printf("✅ Configuration is valid (%d services, %d environments)\n", len(c.Services), len(c.Environments))
+		return nil
+	},
+}
+
+// validateComposeServices checks that every registry service exists in the
+// compose files.
+func validateComposeServices(c *config.Config) error {
+	project, err := compose.Load(c.ComposePaths(false)...)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, svc := range c.Services {
+		if project.Service(svc.ComposeName()) == nil {
+			errs = append(errs, fmt.Errorf("service %s: compose service %q not found in %v", svc.Name, svc.ComposeName(), c.ComposeFiles))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unwrapAll flattens an errors.Join tree into its leaf errors.
+func unwrapAll(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var out []error
+		for _, e := range joined.Unwrap() {
+			out = append(out, unwrapAll(e)...)
+		}
+		return out
+	}
+	return []error{err}
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configValidateCmd)
+
+	configShowCmd.Flags().StringVarP(&configOutput, "output", "o", "yaml", "Output format: yaml, json")
+}
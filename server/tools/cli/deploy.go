@@ -1,47 +1,542 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"os/user"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/history"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/k8s"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/runner"
+)
+
+var (
+	deployImageTags []string
+	deploySet       []string
+	deployRollback  bool
+	deployDiffOnly  bool
+	deployRender    bool
+	deployTimeout   time.Duration
+	deployConfirm   bool
+	deployApprovers []string
+	historyOutput   string
+	historyLimit    int
+	rollbackTo      string
 )
 
+// historyDir holds the deployment records, relative to the project
+// directory.
+const historyDir = ".saas/deployments"
+
+// annotationPrefix namespaces the annotations deploy sets on every object
+// it applies.
+const annotationPrefix = "saas.vhvplatform.io/"
+
+// makeDeployEnvironments are the environments with a deploy-<env> target
+// in the Makefile, which --via-make delegates to.
+var makeDeployEnvironments = []string{"local", "dev"}
+
+// newKubeClient returns the client used to talk to the cluster of env.
+// Tests replace it with a k8s.Fake.
+var newKubeClient = func(env *config.Environment) k8s.Client {
+	return &k8s.Kubectl{Runner: cmdRunner, Context: env.KubeContext}
+}
+
 var deployCmd = &cobra.Command{
-	Use:   "deploy [environment]",
+	Use:   "deploy <environment>",
 	Short: "Deploy to environment",
-	Long: `Deploy services to specified environment.
+	Long: `Deploy the platform to a Kubernetes environment from saas.yaml.
+
+The manifests in k8s_base (server/k8s/base) are rendered with the overlay of
+the environment: its namespace, image registry and tag, replica counts,
+ConfigMap entries and 'set' expressions. The result is diffed against the
+cluster and applied in order (namespace, configuration, infrastructure,
+services, gateway), waiting for every rollout before moving on.
+
+--set takes KEY=value to set an entry of the <project>-config ConfigMap, or
+kind/name:path.to.field=value to set any field, e.g.
+deployment/api-gateway:spec.replicas=1. List elements are addressed by
+index. Flags are applied after the overlay in saas.yaml.
 
-Environments:
-  local     # Deploy to local Kubernetes cluster
-  dev       # Deploy to development environment
+Every deployment is recorded in .saas/deployments with its git commit,
+images, configuration hash, time and user, and the applied objects are
+annotated with its ID. See 'saas deploy history' and 'saas deploy rollback'.
+
+Protected environments are only deployed to with --confirm, and those with
+required_approvals need that many people besides you named with
+--approved-by.
 
 Examples:
-  saas deploy local   # Deploy to local cluster
-  saas deploy dev     # Deploy to dev environment`,
-	Args: cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		env := args[0]
-
-		var target string
-		switch env {
-		case "local":
-			target = "deploy-local"
-			fmt.Println("☸️  Deploying to local Kubernetes...")
-		case "dev":
-			target = "deploy-dev"
-			fmt.Println("☸️  Deploying to development environment...")
-		default:
-			fmt.Fprintf(os.Stderr, "❌ Unknown environment: %s\n", env)
-			fmt.Println("Available environments: local, dev")
-			os.Exit(1)
-		}
-
-		if err := runCommand("make", target); err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Deployment failed: %v\n", err)
-			os.Exit(1)
-		}
-
-		fmt.Println("✅ Deployment complete!")
+  saas deploy local                          # Deploy to local cluster
+  saas deploy dev --image-tag v1.4.2         # Deploy a release
+  saas deploy dev --image-tag api-gateway=sha-3f2c1d
+  saas deploy dev --set LOG_LEVEL=info       # Override a config entry
+  saas deploy dev --diff                     # Only show what would change
+  saas deploy dev --render > dev.yaml        # Print the rendered manifests
+  saas deploy dev --rollback                 # Undo the last rollout
+  saas deploy staging --confirm --approved-by alice
+  saas deploy local --via-make               # Use the Makefile target instead`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeEnvironments(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, env, err := loadEnvironment(args[0])
+		if err != nil {
+			return err
+		}
+
+		if viaMake {
+			if !slices.Contains(makeDeployEnvironments, env.Name) {
+				return withExitCode(exitInvalidArgs, fmt.Errorf("--via-make is not supported for %s: the Makefile only has deploy targets for %s", env.Name, strings.Join(makeDeployEnvironments, " and ")))
+			}
+			fmt.Printf("☸️  Deploying to %s...\n", env.Name)
+			if err := runCommand("make", "deploy-"+env.Name); err != nil {
+				return fmt.Errorf("deployment failed: %w", err)
+			}
+			fmt.Println("✅ Deployment complete!")
+			return nil
+		}
+
+		o, err := overlayFor(c, env, deployImageTags, deploySet)
+		if err != nil {
+			return err
+		}
+		objs, err := renderEnvironment(c, env, o)
+		if err != nil {
+			return err
+		}
+
+		if deployRollback {
+			if err := checkProtection(env); err != nil {
+				return err
+			}
+			d := &k8s.Deployer{Client: newKubeClient(env), Out: cmd.OutOrStdout(), Timeout: deployTimeout}
+			fmt.Printf("↩️  Rolling back %s...\n", env.Name)
+			if err := d.Rollback(context.Background(), objs); err != nil {
+				return fmt.Errorf("rollback failed: %w", err)
+			}
+			fmt.Println("✅ Rollback complete!")
+			return nil
+		}
+
+		if err := k8s.CheckImages(objs); err != nil {
+			return err
+		}
+		if deployRender {
+			manifest, err := k8s.Encode(objs)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(manifest)
+			return err
+		}
+
+		return applyDeployment(cmd.OutOrStdout(), c, env, objs, history.Record{Set: deploySet})
 	},
 }
+
+var deployHistoryCmd = &cobra.Command{
+	Use:   "history <environment>",
+	Short: "List the recorded deployments of an environment",
+	Long: `List the deployments recorded for an environment, newest first, with the
+git commit, configuration hash, status and image tags of each.
+
+Examples:
+  saas deploy history dev           # Table of deployments
+  saas deploy history dev -n 5      # The last five
+  saas deploy history dev -o json   # Full records as JSON`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeEnvironments(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if historyOutput != "table" && historyOutput != "json" {
+			return withExitCode(exitInvalidArgs, fmt.Errorf("unknown output format: %s (use table or json)", historyOutput))
+		}
+		c, env, err := loadEnvironment(args[0])
+		if err != nil {
+			return err
+		}
+
+		records, err := historyStore(c).List(env.Name)
+		if err != nil {
+			return fmt.Errorf("failed to read deployment history: %w", err)
+		}
+		sort.SliceStable(records, func(i, j int) bool { return records[i].ID > records[j].ID })
+		if historyLimit > 0 && len(records) > historyLimit {
+			records = records[:historyLimit]
+		}
+		return writeHistory(cmd.OutOrStdout(), historyOutput, env.Name, records)
+	},
+}
+
+var deployRollbackCmd = &cobra.Command{
+	Use:   "rollback <environment>",
+	Short: "Redeploy an earlier recorded deployment",
+	Long: `Redeploy the images and --set values of an earlier deployment from the
+history, and record the result as a new deployment.
+
+Without --to, the last successful deployment before the current one is
+restored. The manifests are rendered from the current checkout, so a
+warning is printed when the configuration differs from the restored
+deployment. To undo the last rollout with Kubernetes' own revision history
+instead, use 'saas deploy <env> --rollback'.
+
+Examples:
+  saas deploy rollback dev          # Back to the previous deployment
+  saas deploy rollback dev --to 4   # Back to deployment #4`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeEnvironments(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, env, err := loadEnvironment(args[0])
+		if err != nil {
+			return err
+		}
+
+		store := historyStore(c)
+		var target *history.Record
+		if rollbackTo != "" {
+			id, err := history.ParseID(rollbackTo)
+			if err != nil {
+				return withExitCode(exitInvalidArgs, err)
+			}
+			if target, err = store.Get(env.Name, id); err != nil {
+				return err
+			}
+			if target.Status != history.StatusSucceeded {
+				return fmt.Errorf("deployment #%d of %s %s; pick a successful one", target.ID, env.Name, target.Status)
+			}
+		} else {
+			current, err := store.LastSucceeded(env.Name)
+			if err != nil {
+				return err
+			}
+			if target, err = store.LastSucceeded(env.Name, current.ID); err != nil {
+				return fmt.Errorf("nothing to roll back to: #%d is the only successful deployment of %s", current.ID, env.Name)
+			}
+		}
+
+		o, err := overlayFor(c, env, nil, target.Set)
+		if err != nil {
+			return err
+		}
+		o.Images = target.Images
+		objs, err := renderEnvironment(c, env, o)
+		if err != nil {
+			return err
+		}
+		if err := k8s.CheckImages(objs); err != nil {
+			return err
+		}
+
+		fmt.Printf("↩️  Rolling %s back to deployment #%d (commit %s by %s)\n", env.Name, target.ID, target.GitSHA, target.User)
+		if hash := k8s.ConfigHash(objs); hash != target.ConfigHash {
+			fmt.Printf("⚠️  The configuration differs from deployment #%d (%s, now %s); only its images and --set values are restored\n",
+				target.ID, target.ConfigHash, hash)
+		}
+		return applyDeployment(cmd.OutOrStdout(), c, env, objs, history.Record{Set: target.Set, RollbackOf: target.ID})
+	},
+}
+
+// loadEnvironment loads the configuration and looks up an environment.
+func loadEnvironment(name string) (*config.Config, *config.Environment, error) {
+	c, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	env := c.Environment(name)
+	if env == nil {
+		return nil, nil, withExitCode(exitInvalidArgs, fmt.Errorf("unknown environment: %s (available: %s)", name, strings.Join(c.EnvironmentNames(), ", ")))
+	}
+	return c, env, nil
+}
+
+// completeEnvironments offers environment names for the first n arguments.
+func completeEnvironments(n int) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= n {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		c, err := loadConfig()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var out []string
+		for _, name := range c.EnvironmentNames() {
+			if strings.HasPrefix(name, toComplete) && !slices.Contains(args, name) {
+				out = append(out, name)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func historyStore(c *config.Config) *history.Store {
+	return &history.Store{Dir: c.Resolve(historyDir)}
+}
+
+// checkProtection refuses to change a protected environment without
+// --confirm, or one that lacks the approvals it requires. Dry runs are let
+// through.
+func checkProtection(env *config.Environment) error {
+	if dryRun {
+		return nil
+	}
+	if env.Protected && !deployConfirm {
+		return withExitCode(exitInvalidArgs, fmt.Errorf("%s is a protected environment; pass --confirm to deploy to it", env.Name))
+	}
+	if got := len(approvers()); got < env.RequiredApprovals {
+		return withExitCode(exitInvalidArgs, fmt.Errorf("%s requires %d approval(s) from someone other than %s, got %d (use --approved-by)", env.Name, env.RequiredApprovals, currentUser(), got))
+	}
+	return nil
+}
+
+// approvers returns the distinct people named with --approved-by, without
+// the current user, who cannot approve their own deployment.
+func approvers() []string {
+	me := currentUser()
+	var names []string
+	for _, name := range deployApprovers {
+		name = strings.TrimSpace(name)
+		if name != "" && name != me && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// applyDeployment diffs and applies objs to env and records the deployment
+// in the history, completing rec. Dry runs and --diff record nothing.
+func applyDeployment(out io.Writer, c *config.Config, env *config.Environment, objs []k8s.Object, rec history.Record) error {
+	if !deployDiffOnly {
+		if err := checkProtection(env); err != nil {
+			return err
+		}
+	}
+
+	store := historyStore(c)
+	id, err := store.NextID(env.Name)
+	if err != nil {
+		return fmt.Errorf("failed to read deployment history: %w", err)
+	}
+	rec.ID = id
+	rec.Env = env.Name
+	rec.Time = time.Now().UTC()
+	rec.User = currentUser()
+	rec.GitSHA = gitSHA(c)
+	rec.Images = k8s.Images(objs)
+	rec.ConfigHash = k8s.ConfigHash(objs)
+	rec.ApprovedBy = approvers()
+
+	k8s.Annotate(objs, map[string]string{
+		annotationPrefix + "deployment-id": strconv.Itoa(rec.ID),
+		annotationPrefix + "git-sha":       rec.GitSHA,
+		annotationPrefix + "config-hash":   rec.ConfigHash,
+		annotationPrefix + "deployed-by":   rec.User,
+	})
+
+	ctx := context.Background()
+	d := &k8s.Deployer{Client: newKubeClient(env), Out: out, Timeout: deployTimeout}
+
+	fmt.Printf("☸️  Deploying to %s (namespace %s)...\n", env.Name, env.Namespace)
+	if !dryRun {
+		// The diff of a dry run would always be empty.
+		d.Diff(ctx, objs)
+	}
+	if deployDiffOnly {
+		return nil
+	}
+
+	applyErr := d.Apply(ctx, objs)
+	rec.Status = history.StatusSucceeded
+	if applyErr != nil {
+		rec.Status, rec.Error = history.StatusFailed, applyErr.Error()
+	}
+	if !dryRun {
+		if err := store.Append(rec); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not record the deployment: %v\n", err)
+		} else {
+			fmt.Printf("📒 Recorded as deployment #%d of %s\n", rec.ID, env.Name)
+		}
+	}
+
+	if applyErr != nil {
+		return fmt.Errorf("deployment failed: %w", applyErr)
+	}
+	fmt.Println("✅ Deployment complete!")
+	return nil
+}
+
+// renderEnvironment loads the base manifests and the overlay directory of
+// env, and applies overlay o.
+func renderEnvironment(c *config.Config, env *config.Environment, o k8s.Overlay) ([]k8s.Object, error) {
+	done := step("render manifests")
+	defer done()
+
+	base, err := k8s.LoadDir(c.Resolve(c.K8sBase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifests: %w", err)
+	}
+	if env.OverlayPath != "" {
+		extra, err := k8s.LoadDir(c.Resolve(env.OverlayPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load manifests of %s: %w", env.Name, err)
+		}
+		base = k8s.Merge(base, extra)
+	}
+
+	objs, err := k8s.Render(base, o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", env.Name, err)
+	}
+	k8s.Sort(objs)
+	return objs, nil
+}
+
+// overlayFor returns the overlay of env from saas.yaml followed by
+// imageTags (tag or service=tag) and sets (--set expressions).
+func overlayFor(c *config.Config, env *config.Environment, imageTags, sets []string) (k8s.Overlay, error) {
+	o := k8s.Overlay{
+		Namespace:     env.Namespace,
+		Labels:        map[string]string{"environment": env.Name},
+		ImageRegistry: env.Overlay.ImageRegistry,
+		ImageTags:     map[string]string{},
+		Replicas:      env.Overlay.Replicas,
+		ConfigMap:     c.Project + "-config",
+		Config:        env.Overlay.Config,
+	}
+	if env.Overlay.ImageTag != "" {
+		o.ImageTags[""] = env.Overlay.ImageTag
+	}
+	for _, t := range imageTags {
+		if name, tag, ok := strings.Cut(t, "="); ok {
+			o.ImageTags[name] = tag
+		} else {
+			o.ImageTags[""] = t
+		}
+	}
+
+	for _, s := range append(append([]string{}, env.Overlay.Set...), sets...) {
+		p, err := k8s.ParsePatch(s)
+		if err != nil {
+			return o, withExitCode(exitInvalidArgs, err)
+		}
+		o.Patches = append(o.Patches, p)
+	}
+	return o, nil
+}
+
+// gitSHA returns the commit checked out in the project directory, or
+// "unknown". It only reads the repository, so it also runs in dry-run mode.
+func gitSHA(c *config.Config) string {
+	out, err := runner.Output(context.Background(), execRunner, runner.Command{
+		Name: "git",
+		Args: []string{"rev-parse", "--short=12", "HEAD"},
+		Dir:  c.Dir,
+	})
+	if sha := strings.TrimSpace(string(out)); err == nil && sha != "" {
+		return sha
+	}
+	return "unknown"
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+// writeHistory prints records as a table or as JSON.
+func writeHistory(w io.Writer, format, env string, records []history.Record) error {
+	if format == "json" {
+		if records == nil {
+			records = []history.Record{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintf(w, "No deployments of %s recorded yet\n", env)
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTIME\tUSER\tCOMMIT\tCONFIG\tSTATUS\tIMAGES")
+	for _, r := range records {
+		status := r.Status
+		switch {
+		case r.RollbackOf != 0:
+			status += fmt.Sprintf(" (rollback to #%d)", r.RollbackOf)
+		case r.PromotedFrom != "":
+			status += " (promoted from " + r.PromotedFrom + ")"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.ID, r.Time.Local().Format("2006-01-02 15:04:05"), r.User, r.GitSHA, r.ConfigHash, status, imageTags(r.Images))
+	}
+	return tw.Flush()
+}
+
+// imageTags summarises images as name:tag pairs, or as the tag alone when
+// every image has the same one.
+func imageTags(images map[string]string) string {
+	names := make([]string, 0, len(images))
+	tags := map[string]bool{}
+	for name, image := range images {
+		names = append(names, name)
+		_, tag := k8s.SplitImage(image)
+		tags[tag] = true
+	}
+	sort.Strings(names)
+	if len(names) > 1 && len(tags) == 1 {
+		_, tag := k8s.SplitImage(images[names[0]])
+		return tag
+	}
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		_, tag := k8s.SplitImage(images[name])
+		parts[i] = name + ":" + tag
+	}
+	return strings.Join(parts, ", ")
+}
+
+// addProtectionFlags registers the flags checkProtection reads.
+func addProtectionFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&deployConfirm, "confirm", false, "Confirm a deployment to a protected environment")
+	cmd.Flags().StringSliceVar(&deployApprovers, "approved-by", nil, "People who approved the deployment (repeatable)")
+}
+
+func init() {
+	deployCmd.Flags().StringSliceVar(&deployImageTags, "image-tag", nil, "Image tag for every service, or service=tag for one (repeatable)")
+	deployCmd.Flags().StringArrayVar(&deploySet, "set", nil, "Override a value: KEY=value or kind/name:path=value (repeatable)")
+	deployCmd.Flags().BoolVar(&deployRollback, "rollback", false, "Roll every workload back to its previous Kubernetes revision")
+	deployCmd.Flags().BoolVar(&deployDiffOnly, "diff", false, "Show the diff against the cluster without applying")
+	deployCmd.Flags().BoolVar(&deployRender, "render", false, "Print the rendered manifests and exit")
+	deployCmd.Flags().DurationVar(&deployTimeout, "timeout", 5*time.Minute, "Maximum time to wait for each rollout")
+	deployCmd.Flags().BoolVar(&viaMake, "via-make", false, "Delegate to the Makefile target instead of kubectl")
+	deployCmd.MarkFlagsMutuallyExclusive("rollback", "diff", "render")
+	addProtectionFlags(deployCmd)
+
+	deployHistoryCmd.Flags().StringVarP(&historyOutput, "output", "o", "table", "Output format: table or json")
+	deployHistoryCmd.Flags().IntVarP(&historyLimit, "limit", "n", 0, "Show only the newest n deployments")
+
+	deployRollbackCmd.Flags().StringVar(&rollbackTo, "to", "", "ID of the deployment to restore (default: the previous one)")
+	deployRollbackCmd.Flags().DurationVar(&deployTimeout, "timeout", 5*time.Minute, "Maximum time to wait for each rollout")
+	addProtectionFlags(deployRollbackCmd)
+
+	deployCmd.AddCommand(deployHistoryCmd, deployRollbackCmd)
+}
@@ -0,0 +1,327 @@
+// Package compose reads docker-compose files and resolves the dependency
+// graph between the services they declare.
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Project is the merged view of one or more docker-compose files.
+type Project struct {
+	// Files are the compose files the project was loaded from, in order.
+	Files []string
+	// Services maps compose service names to their definitions.
+	Services map[string]*Service
+}
+
+// Service is the subset of a compose service definition the CLI relies on.
+type Service struct {
+	Name          string            `yaml:"-"`
+	Image         string            `yaml:"image,omitempty"`
+	Build         *Build            `yaml:"build,omitempty"`
+	ContainerName string            `yaml:"container_name,omitempty"`
+	Ports         []string          `yaml:"ports,omitempty"`
+	Environment   Environment       `yaml:"environment,omitempty"`
+	DependsOn     DependsOn         `yaml:"depends_on,omitempty"`
+	Healthcheck   *Healthcheck      `yaml:"healthcheck,omitempty"`
+	Volumes       []string          `yaml:"volumes,omitempty"`
+	Profiles      []string          `yaml:"profiles,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty"`
+}
+
+// Build describes how the image of a service is built.
+type Build struct {
+	Context    string `yaml:"context,omitempty"`
+	Dockerfile string `yaml:"dockerfile,omitempty"`
+}
+
+// UnmarshalYAML accepts both the short (string) and long (mapping) build syntax.
+func (b *Build) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		b.Context = node.Value
+		return nil
+	}
+	type plain Build
+	return node.Decode((*plain)(b))
+}
+
+// Healthcheck is the container-level health check declared in compose.
+type Healthcheck struct {
+	Test     []string `yaml:"test,omitempty"`
+	Interval string   `yaml:"interval,omitempty"`
+	Timeout  string   `yaml:"timeout,omitempty"`
+	Retries  int      `yaml:"retries,omitempty"`
+}
+
+// Dependency is a single depends_on entry.
+type Dependency struct {
+	Condition string `yaml:"condition,omitempty"`
+}
+
+// DependsOn maps dependency names to their start condition.
+type DependsOn map[string]Dependency
+
+// UnmarshalYAML accepts both the list and mapping forms of depends_on.
+func (d *DependsOn) UnmarshalYAML(node *yaml.Node) error {
+	*d = DependsOn{}
+	if node.Kind == yaml.SequenceNode {
+		var names []string
+		if err := node.Decode(&names); err != nil {
+			return err
+		}
+		for _, name := range names {
+			(*d)[name] = Dependency{Condition: "service_started"}
+		}
+		return nil
+	}
+	return node.Decode((*map[string]Dependency)(d))
+}
+
+// Environment holds service environment variables.
+type Environment map[string]string
+
+// UnmarshalYAML accepts both the list (KEY=value) and mapping forms.
+func (e *Environment) UnmarshalYAML(node *yaml.Node) error {
+	*e = Environment{}
+	if node.Kind == yaml.SequenceNode {
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			key, value, _ := strings.Cut(item, "=")
+			(*e)[key] = value
+		}
+		return nil
+	}
+	return node.Decode((*map[string]string)(e))
+}
+
+type file struct {
+	Services map[string]*Service `yaml:"services"`
+}
+
+// Load reads the given compose files and merges them in order, the way
+// `docker compose -f a.yml -f b.yml` does for the fields the CLI uses.
+func Load(files ...string) (*Project, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no compose files given")
+	}
+
+	project := &Project{Services: map[string]*Service{}}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read compose file: %w", err)
+		}
+
+		var f file
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+		}
+
+		for name, svc := range f.Services {
+			if svc == nil {
+				svc = &Service{}
+			}
+			svc.Name = name
+			if existing, ok := project.Services[name]; ok {
+				existing.merge(svc)
+			} else {
+				project.Services[name] = svc
+			}
+		}
+		project.Files = append(project.Files, path)
+	}
+
+	if err := project.validate(); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// merge applies an override file's definition on top of s.
+func (s *Service) merge(o *Service) {
+	if o.Image != "" {
+		s.Image = o.Image
+	}
+	if o.Build != nil {
+		s.Build = o.Build
+	}
+	if o.ContainerName != "" {
+		s.ContainerName = o.ContainerName
+	}
+	if o.Healthcheck != nil {
+		s.Healthcheck = o.Healthcheck
+	}
+	if o.Profiles != nil {
+		s.Profiles = o.Profiles
+	}
+	s.Ports = append(s.Ports, o.Ports...)
+	s.Volumes = append(s.Volumes, o.Volumes...)
+	for k, v := range o.Environment {
+		if s.Environment == nil {
+			s.Environment = Environment{}
+		}
+		s.Environment[k] = v
+	}
+	for k, v := range o.DependsOn {
+		if s.DependsOn == nil {
+			s.DependsOn = DependsOn{}
+		}
+		s.DependsOn[k] = v
+	}
+	for k, v := range o.Labels {
+		if s.Labels == nil {
+			s.Labels = map[string]string{}
+		}
+		s.Labels[k] = v
+	}
+}
+
+func (p *Project) validate() error {
+	for _, name := range p.Names() {
+		for dep := range p.Services[name].DependsOn {
+			if _, ok := p.Services[dep]; !ok {
+				return fmt.Errorf("service %q depends on undefined service %q", name, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// Names returns all service names in alphabetical order.
+func (p *Project) Names() []string {
+	names := make([]string, 0, len(p.Services))
+	for name := range p.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Service returns the named service, or nil if it is not defined.
+func (p *Project) Service(name string) *Service {
+	return p.Services[name]
+}
+
+// Dependencies returns the sorted direct dependencies of a service.
+func (s *Service) Dependencies() []string {
+	deps := make([]string, 0, len(s.DependsOn))
+	for dep := range s.DependsOn {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// CycleError reports a dependency cycle between services.
+type CycleError struct {
+	Services []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("dependency cycle between services: %s", strings.Join(e.Services, ", "))
+}
+
+// UnknownServiceError reports a service name that is not in the project.
+type UnknownServiceError struct {
+	Name string
+}
+
+func (e *UnknownServiceError) Error() string {
+	return fmt.Sprintf("unknown service %q", e.Name)
+}
+
+// Levels groups the requested services and their transitive dependencies
+// into start levels. Every service in a level depends only on services in
+// earlier levels, so levels must run in order while the services within a
+// level are independent of each other. With no names, all services are used.
+func (p *Project) Levels(names ...string) ([][]string, error) {
+	selected, err := p.closure(names)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := map[string]int{}
+	for name := range selected {
+		count := 0
+		for dep := range p.Services[name].DependsOn {
+			if selected[dep] {
+				count++
+			}
+		}
+		remaining[name] = count
+	}
+
+	var levels [][]string
+	for len(remaining) > 0 {
+		var level []string
+		for name, count := range remaining {
+			if count == 0 {
+				level = append(level, name)
+			}
+		}
+		if len(level) == 0 {
+			cycle := make([]string, 0, len(remaining))
+			for name := range remaining {
+				cycle = append(cycle, name)
+			}
+			sort.Strings(cycle)
+			return nil, &CycleError{Services: cycle}
+		}
+		sort.Strings(level)
+
+		for _, done := range level {
+			delete(remaining, done)
+		}
+		for name := range remaining {
+			for _, done := range level {
+				if _, ok := p.Services[name].DependsOn[done]; ok {
+					remaining[name]--
+				}
+			}
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// closure returns the named services plus everything they depend on.
+func (p *Project) closure(names []string) (map[string]bool, error) {
+	if len(names) == 0 {
+		names = p.Names()
+	}
+
+	selected := map[string]bool{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		if selected[name] {
+			return nil
+		}
+		svc, ok := p.Services[name]
+		if !ok {
+			return &UnknownServiceError{Name: name}
+		}
+		selected[name] = true
+		for dep := range svc.DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,163 @@
+package compose
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeCompose(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "docker-compose.yml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write compose file: %v", err)
+	}
+	return path
+}
+
+func TestLoadRepositoryComposeFile(t *testing.T) {
+	project, err := Load("../../../../docker/docker-compose.yml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	gateway := project.Service("api-gateway")
+	if gateway == nil {
+		t.Fatal("expected api-gateway service")
+	}
+	want := []string{"auth-service", "notification-service", "system-config-service", "tenant-service", "user-service"}
+	if got := gateway.Dependencies(); !reflect.DeepEqual(got, want) {
+		t.Errorf("api-gateway dependencies = %v, want %v", got, want)
+	}
+	if gateway.Environment["AUTH_SERVICE_URL"] != "http://auth-service:8081" {
+		t.Errorf("expected merged common variables, got %v", gateway.Environment)
+	}
+
+	levels, err := project.Levels("api-gateway")
+	if err != nil {
+		t.Fatalf("Levels() error = %v", err)
+	}
+	wantLevels := [][]string{
+		{"mongodb", "rabbitmq", "redis"},
+		{"auth-service", "notification-service", "system-config-service", "tenant-service", "user-service"},
+		{"api-gateway"},
+	}
+	if !reflect.DeepEqual(levels, wantLevels) {
+		t.Errorf("Levels() = %v, want %v", levels, wantLevels)
+	}
+}
+
+func TestLoadRepositoryOpenAPIOverride(t *testing.T) {
+	project, err := Load("../../../../docker/docker-compose.yml", "../../../../docker/docker-compose.openapi.yml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	users := project.Service("user-service")
+	want := &Build{Context: "../mocks", Dockerfile: "openapi/Dockerfile"}
+	if !reflect.DeepEqual(users.Build, want) {
+		t.Errorf("build = %+v, want %+v", users.Build, want)
+	}
+	if users.Image != "go-framework/openapi-mock:local" {
+		t.Errorf("image = %q, want the OpenAPI mock image", users.Image)
+	}
+	if users.Environment["OPENAPI_SPEC"] != "/specs/user-service.yaml" || users.Environment["PORT"] != "8082" {
+		t.Errorf("environment = %v", users.Environment)
+	}
+	if gateway := project.Service("api-gateway"); gateway.Build.Dockerfile != "api-gateway/Dockerfile" {
+		t.Errorf("api-gateway build = %+v, want the hand-written mock", gateway.Build)
+	}
+}
+
+func TestLoadRepositoryRecordReplayOverrides(t *testing.T) {
+	for file, mode := range map[string]string{"docker-compose.record.yml": "record", "docker-compose.replay.yml": "replay"} {
+		project, err := Load("../../../../docker/docker-compose.yml", "../../../../docker/"+file)
+		if err != nil {
+			t.Fatalf("Load(%s) error = %v", file, err)
+		}
+		gateway := project.Service("api-gateway")
+		if gateway.Environment["MOCK_MODE"] != mode {
+			t.Errorf("%s: MOCK_MODE = %q, want %q", file, gateway.Environment["MOCK_MODE"], mode)
+		}
+		if gateway.Build.Dockerfile != "api-gateway/Dockerfile" || gateway.Environment["TENANT_SERVICE_URL"] == "" {
+			t.Errorf("%s: api-gateway lost its base settings: %+v", file, gateway)
+		}
+	}
+}
+
+func TestLoadMergesOverrideFiles(t *testing.T) {
+	base := writeCompose(t, `
+services:
+  api:
+    image: api:1
+    environment:
+      LOG_LEVEL: info
+`)
+	override := writeCompose(t, `
+services:
+  api:
+    environment:
+      - LOG_LEVEL=debug
+      - HOT_RELOAD=true
+    depends_on:
+      - db
+  db:
+    image: mongo
+`)
+
+	project, err := Load(base, override)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	api := project.Service("api")
+	if api.Image != "api:1" {
+		t.Errorf("image = %q, want api:1", api.Image)
+	}
+	if api.Environment["LOG_LEVEL"] != "debug" || api.Environment["HOT_RELOAD"] != "true" {
+		t.Errorf("environment not merged: %v", api.Environment)
+	}
+	if got := api.Dependencies(); !reflect.DeepEqual(got, []string{"db"}) {
+		t.Errorf("dependencies = %v, want [db]", got)
+	}
+}
+
+func TestLevelsDetectsCycles(t *testing.T) {
+	path := writeCompose(t, `
+services:
+  a:
+    depends_on: [b]
+  b:
+    depends_on: [a]
+  c: {}
+`)
+	project, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	_, err = project.Levels()
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("expected CycleError, got %v", err)
+	}
+	if !reflect.DeepEqual(cycle.Services, []string{"a", "b"}) {
+		t.Errorf("cycle services = %v, want [a b]", cycle.Services)
+	}
+}
+
+func TestLevelsUnknownService(t *testing.T) {
+	path := writeCompose(t, "services:\n  a: {}\n")
+	project, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	_, err = project.Levels("missing")
+	var unknown *UnknownServiceError
+	if !errors.As(err, &unknown) || unknown.Name != "missing" {
+		t.Fatalf("expected UnknownServiceError for missing, got %v", err)
+	}
+}
@@ -0,0 +1,394 @@
+// Package config loads the project-level saas.yaml file that describes the
+// services, compose files and environments the CLI works with.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the project configuration file.
+const FileName = "saas.yaml"
+
+// Check kinds understood by the health checks.
+const (
+	CheckHTTP  = "http"
+	CheckTCP   = "tcp"
+	CheckRedis = "redis"
+)
+
+// Config is the typed content of saas.yaml.
+type Config struct {
+	// Path is the file the configuration was loaded from. It is empty when
+	// the built-in defaults are used.
+	Path string `yaml:"-" json:"source,omitempty"`
+	// Dir is the project directory; relative paths are resolved against it.
+	Dir string `yaml:"-" json:"dir"`
+
+	Project         string    `yaml:"project" json:"project"`
+	Host            string    `yaml:"host,omitempty" json:"host,omitempty"`
+	ComposeFiles    []string  `yaml:"compose_files" json:"compose_files"`
+	DevComposeFiles []string  `yaml:"dev_compose_files,omitempty" json:"dev_compose_files,omitempty"`
+	Services        []Service `yaml:"services" json:"services"`
+	// K8sBase is the directory of the Kubernetes manifests `saas deploy`
+	// renders for every environment.
+	K8sBase string `yaml:"k8s_base,omitempty" json:"k8s_base,omitempty"`
+	// K8sExcludeEnv lists the compose environment variables `saas k8s
+	// generate` leaves out of the generated ConfigMaps, such as the fixture
+	// paths only the mocks read.
+	K8sExcludeEnv []string      `yaml:"k8s_exclude_env,omitempty" json:"k8s_exclude_env,omitempty"`
+	Environments  []Environment `yaml:"environments,omitempty" json:"environments,omitempty"`
+}
+
+// Service is one entry of the service registry.
+type Service struct {
+	// Name is the canonical service name.
+	Name string `yaml:"name" json:"name"`
+	// Aliases are short names accepted on the command line.
+	Aliases []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	// Compose is the docker-compose service name. Defaults to Name.
+	Compose string `yaml:"compose,omitempty" json:"compose,omitempty"`
+	// Port is the host port used for health checks.
+	Port int `yaml:"port,omitempty" json:"port,omitempty"`
+	// HealthPath is the HTTP health endpoint, e.g. /health.
+	HealthPath string `yaml:"health_path,omitempty" json:"health_path,omitempty"`
+	// Check is the probe kind: http, tcp or redis. Defaults to http when
+	// HealthPath is set and tcp otherwise.
+	Check string `yaml:"check,omitempty" json:"check,omitempty"`
+	// Repo is the source repository URL.
+	Repo string `yaml:"repo,omitempty" json:"repo,omitempty"`
+	// Source is the local checkout of Repo. `saas rebuild` builds the image
+	// from it when it contains a Dockerfile, and from the compose build
+	// context (the mock under server/mocks) otherwise.
+	Source string `yaml:"source,omitempty" json:"source,omitempty"`
+	// Groups are labels used to select services together, e.g. infra.
+	Groups []string `yaml:"groups,omitempty" json:"groups,omitempty"`
+}
+
+// Environment is a deployment target.
+type Environment struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	KubeContext string `yaml:"kube_context,omitempty" json:"kube_context,omitempty"`
+	Namespace   string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	// OverlayPath is a directory of extra manifests for this environment.
+	// They are added to the base manifests, replacing those of the same
+	// kind and name.
+	OverlayPath string `yaml:"overlay_path,omitempty" json:"overlay_path,omitempty"`
+	// Protected environments are only deployed to with --confirm.
+	Protected bool `yaml:"protected,omitempty" json:"protected,omitempty"`
+	// RequiredApprovals is the number of people other than the deployer
+	// who must be named with --approved-by.
+	RequiredApprovals int `yaml:"required_approvals,omitempty" json:"required_approvals,omitempty"`
+	// Overlay holds the changes made to the base manifests for this
+	// environment.
+	Overlay Overlay `yaml:"overlay,omitempty" json:"overlay,omitempty"`
+}
+
+// Overlay customises the base Kubernetes manifests for an environment.
+type Overlay struct {
+	// ImageRegistry replaces the registry of every container image.
+	ImageRegistry string `yaml:"image_registry,omitempty" json:"image_registry,omitempty"`
+	// ImageTag replaces the tag of every container image.
+	ImageTag string `yaml:"image_tag,omitempty" json:"image_tag,omitempty"`
+	// Replicas maps workload names to their replica count.
+	Replicas map[string]int `yaml:"replicas,omitempty" json:"replicas,omitempty"`
+	// Config holds entries merged into the <project>-config ConfigMap.
+	Config map[string]string `yaml:"config,omitempty" json:"config,omitempty"`
+	// Set holds extra `saas deploy --set` expressions.
+	Set []string `yaml:"set,omitempty" json:"set,omitempty"`
+}
+
+// ComposeName returns the docker-compose service name.
+func (s Service) ComposeName() string {
+	if s.Compose != "" {
+		return s.Compose
+	}
+	return s.Name
+}
+
+// CheckKind returns the effective probe kind.
+func (s Service) CheckKind() string {
+	if s.Check != "" {
+		return s.Check
+	}
+	if s.HealthPath != "" {
+		return CheckHTTP
+	}
+	return CheckTCP
+}
+
+// Discover walks up from dir looking for saas.yaml and returns its path, or
+// an empty string when no file is found.
+func Discover(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads and parses a configuration file. Defaults are applied for
+// fields the file leaves empty.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Path = abs
+	cfg.Dir = filepath.Dir(abs)
+	cfg.applyDefaults()
+	return cfg, nil
+}
+
+// LoadFrom loads the configuration found by walking up from dir, falling
+// back to the built-in defaults rooted at dir when there is none.
+func LoadFrom(dir string) (*Config, error) {
+	path, err := Discover(dir)
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		return Load(path)
+	}
+
+	cfg := Default()
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Dir = abs
+	return cfg, nil
+}
+
+func (c *Config) applyDefaults() {
+	def := Default()
+	if c.Host == "" {
+		c.Host = def.Host
+	}
+	if len(c.ComposeFiles) == 0 {
+		c.ComposeFiles = def.ComposeFiles
+	}
+	if len(c.Services) == 0 {
+		c.Services = def.Services
+	}
+	if c.K8sBase == "" {
+		c.K8sBase = def.K8sBase
+	}
+	if len(c.K8sExcludeEnv) == 0 {
+		c.K8sExcludeEnv = def.K8sExcludeEnv
+	}
+	if len(c.Environments) == 0 {
+		c.Environments = def.Environments
+	}
+}
+
+// Resolve returns path made absolute relative to the project directory.
+func (c *Config) Resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.Dir, path)
+}
+
+// ComposePaths returns the absolute compose files to use, including the
+// development overrides when dev is true.
+func (c *Config) ComposePaths(dev bool) []string {
+	files := c.ComposeFiles
+	if dev {
+		files = append(append([]string{}, files...), c.DevComposeFiles...)
+	}
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = c.Resolve(f)
+	}
+	return paths
+}
+
+// Service returns the service whose name, alias or compose name matches
+// exactly, or nil.
+func (c *Config) Service(name string) *Service {
+	for i := range c.Services {
+		svc := &c.Services[i]
+		if svc.Name == name || svc.ComposeName() == name {
+			return svc
+		}
+		for _, alias := range svc.Aliases {
+			if alias == name {
+				return svc
+			}
+		}
+	}
+	return nil
+}
+
+// InGroup reports whether the service carries the group label.
+func (s Service) InGroup(group string) bool {
+	for _, g := range s.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupNames returns the sorted names of all groups used by services.
+func (c *Config) GroupNames() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, svc := range c.Services {
+		for _, g := range svc.Groups {
+			if !seen[g] {
+				seen[g] = true
+				names = append(names, g)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GroupMembers returns the services in any of the given groups, in
+// registry order.
+func (c *Config) GroupMembers(groups ...string) ([]Service, error) {
+	known := c.GroupNames()
+	for _, g := range groups {
+		if !slices.Contains(known, g) {
+			return nil, fmt.Errorf("unknown group %q (available: %s)", g, strings.Join(known, ", "))
+		}
+	}
+
+	var out []Service
+	for _, svc := range c.Services {
+		for _, g := range groups {
+			if svc.InGroup(g) {
+				out = append(out, svc)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// Environment returns the named environment, or nil.
+func (c *Config) Environment(name string) *Environment {
+	for i := range c.Environments {
+		if c.Environments[i].Name == name {
+			return &c.Environments[i]
+		}
+	}
+	return nil
+}
+
+// EnvironmentNames returns the names of all configured environments.
+func (c *Config) EnvironmentNames() []string {
+	names := make([]string, len(c.Environments))
+	for i, env := range c.Environments {
+		names[i] = env.Name
+	}
+	return names
+}
+
+// Validate reports every problem found in the configuration.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if len(c.ComposeFiles) == 0 {
+		errs = append(errs, errors.New("compose_files: at least one compose file is required"))
+	}
+	for _, f := range append(append([]string{}, c.ComposeFiles...), c.DevComposeFiles...) {
+		if _, err := os.Stat(c.Resolve(f)); err != nil {
+			errs = append(errs, fmt.Errorf("compose file %s: %w", f, err))
+		}
+	}
+
+	seen := map[string]string{}
+	claim := func(key, owner string) {
+		if prev, ok := seen[key]; ok && prev != owner {
+			errs = append(errs, fmt.Errorf("name %q is used by both %s and %s", key, prev, owner))
+			return
+		}
+		seen[key] = owner
+	}
+	for i, svc := range c.Services {
+		if svc.Name == "" {
+			errs = append(errs, fmt.Errorf("services[%d]: name is required", i))
+			continue
+		}
+		claim(svc.Name, svc.Name)
+		for _, alias := range svc.Aliases {
+			claim(alias, svc.Name)
+		}
+		if svc.Port < 0 || svc.Port > 65535 {
+			errs = append(errs, fmt.Errorf("service %s: port %d out of range", svc.Name, svc.Port))
+		}
+		switch svc.CheckKind() {
+		case CheckHTTP, CheckTCP, CheckRedis:
+		default:
+			errs = append(errs, fmt.Errorf("service %s: unknown check %q (use http, tcp or redis)", svc.Name, svc.Check))
+		}
+		if svc.CheckKind() == CheckHTTP && svc.HealthPath != "" && !strings.HasPrefix(svc.HealthPath, "/") {
+			errs = append(errs, fmt.Errorf("service %s: health_path must start with /", svc.Name))
+		}
+	}
+
+	envs := map[string]bool{}
+	for i, env := range c.Environments {
+		if env.Name == "" {
+			errs = append(errs, fmt.Errorf("environments[%d]: name is required", i))
+			continue
+		}
+		if envs[env.Name] {
+			errs = append(errs, fmt.Errorf("environment %q is defined more than once", env.Name))
+		}
+		envs[env.Name] = true
+		if env.OverlayPath != "" {
+			if _, err := os.Stat(c.Resolve(env.OverlayPath)); err != nil {
+				errs = append(errs, fmt.Errorf("environment %s: overlay_path: %w", env.Name, err))
+			}
+		}
+		if env.RequiredApprovals < 0 {
+			errs = append(errs, fmt.Errorf("environment %s: required_approvals must not be negative", env.Name))
+		}
+		for _, set := range env.Overlay.Set {
+			if !strings.Contains(set, "=") {
+				errs = append(errs, fmt.Errorf("environment %s: overlay set %q must be key=value", env.Name, set))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestRepositoryConfigMatchesDefaults(t *testing.T) {
+	cfg, err := Load("../../../../saas.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	def := Default()
+	if !reflect.DeepEqual(cfg.Services, def.Services) {
+		t.Error("server/saas.yaml services differ from Default(); keep them in sync")
+	}
+	if !reflect.DeepEqual(cfg.Environments, def.Environments) {
+		t.Error("server/saas.yaml environments differ from Default(); keep them in sync")
+	}
+	if !reflect.DeepEqual(cfg.K8sExcludeEnv, def.K8sExcludeEnv) {
+		t.Error("server/saas.yaml k8s_exclude_env differs from Default(); keep them in sync")
+	}
+}
+
+func TestLoadFromWalksUp(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, FileName), "project: demo\ncompose_files: [compose.yml]\n")
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFrom(nested)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if cfg.Project != "demo" || cfg.Dir != root {
+		t.Errorf("got project %q dir %q, want demo in %s", cfg.Project, cfg.Dir, root)
+	}
+	if got := cfg.ComposePaths(false); !reflect.DeepEqual(got, []string{filepath.Join(root, "compose.yml")}) {
+		t.Errorf("ComposePaths() = %v", got)
+	}
+	if len(cfg.Services) == 0 {
+		t.Error("expected default services to be applied")
+	}
+}
+
+func TestLoadFromFallsBackToDefaults(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := LoadFrom(dir)
+	if err != nil {
+		t.Fatalf("LoadFrom() error = %v", err)
+	}
+	if cfg.Path != "" || cfg.Dir != dir {
+		t.Errorf("expected defaults rooted at %s, got path %q dir %q", dir, cfg.Path, cfg.Dir)
+	}
+}
+
+func TestLoadRejectsUnknownFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeFile(t, path, "project: demo\nservcies: []\n")
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "servcies") {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+}
+
+func TestServiceLookup(t *testing.T) {
+	cfg := Default()
+	for _, name := range []string{"auth-service", "auth"} {
+		if svc := cfg.Service(name); svc == nil || svc.Name != "auth-service" {
+			t.Errorf("Service(%q) = %v, want auth-service", name, svc)
+		}
+	}
+	if svc := cfg.Service("api-gateway-service"); svc != nil {
+		t.Errorf("Service(api-gateway-service) = %v, want nil", svc)
+	}
+}
+
+func TestValidateReportsProblems(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "compose.yml"), "services: {}\n")
+	cfg := &Config{
+		Dir:          dir,
+		ComposeFiles: []string{"compose.yml", "missing.yml"},
+		Services: []Service{
+			{Name: "a", Aliases: []string{"x"}, Check: "icmp"},
+			{Name: "b", Aliases: []string{"x"}, Port: 70000},
+		},
+		Environments: []Environment{
+			{Name: "dev"},
+			{Name: "dev"},
+			{Name: "staging", OverlayPath: "overlays/staging", RequiredApprovals: -1},
+		},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"missing.yml", `name "x" is used by both a and b`, "unknown check", "out of range", "more than once", "staging: overlay_path", "required_approvals must not be negative"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cfg := Default()
+	cfg.AddComposeServices([]string{"auth-service", "mailhog"})
+
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"auth-service", "auth-service"},
+		{"auth", "auth-service"},
+		{"gateway", "api-gateway"},
+		{"noti", "notification-service"},
+		{"mail", "mailhog"},
+	}
+	for _, tt := range tests {
+		svc, err := cfg.Match(tt.arg)
+		if err != nil {
+			t.Errorf("Match(%q) error = %v", tt.arg, err)
+			continue
+		}
+		if svc.Name != tt.want {
+			t.Errorf("Match(%q) = %s, want %s", tt.arg, svc.Name, tt.want)
+		}
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	cfg := Default()
+
+	_, err := cfg.Match("r")
+	if amb, ok := err.(*AmbiguousServiceError); !ok || !reflect.DeepEqual(amb.Matches, []string{"rabbitmq", "redis"}) {
+		t.Errorf("Match(r) error = %v, want ambiguous rabbitmq/redis", err)
+	}
+
+	_, err = cfg.Match("tenat")
+	if unk, ok := err.(*UnknownServiceError); !ok || !reflect.DeepEqual(unk.Suggestions, []string{"tenant-service"}) {
+		t.Errorf("Match(tenat) error = %v, want suggestion tenant-service", err)
+	}
+	if !strings.Contains(err.Error(), "did you mean tenant-service?") {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
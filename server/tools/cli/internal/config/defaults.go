@@ -0,0 +1,70 @@
+package config
+
+// Default returns the configuration used when no saas.yaml is found. It
+// mirrors server/saas.yaml and the stack in docker/docker-compose.yml.
+func Default() *Config {
+	return &Config{
+		Project:         "go-platform",
+		Host:            "localhost",
+		ComposeFiles:    []string{"docker/docker-compose.yml"},
+		DevComposeFiles: []string{"docker/docker-compose.dev.yml"},
+		Services: []Service{
+			{Name: "api-gateway", Aliases: []string{"gateway", "api"}, Port: 8080, HealthPath: "/health", Repo: repo("go-api-gateway"), Source: source("go-api-gateway"), Groups: []string{"core"}},
+			{Name: "auth-service", Aliases: []string{"auth"}, Port: 8081, HealthPath: "/health", Repo: repo("go-auth-service"), Source: source("go-auth-service"), Groups: []string{"core"}},
+			{Name: "user-service", Aliases: []string{"user", "users"}, Port: 8082, HealthPath: "/health", Repo: repo("go-user-service"), Source: source("go-user-service"), Groups: []string{"core"}},
+			{Name: "tenant-service", Aliases: []string{"tenant", "tenants"}, Port: 8083, HealthPath: "/health", Repo: repo("go-tenant-service"), Source: source("go-tenant-service"), Groups: []string{"core"}},
+			{Name: "notification-service", Aliases: []string{"notification", "notifications"}, Port: 8084, HealthPath: "/health", Repo: repo("go-notification-service"), Source: source("go-notification-service"), Groups: []string{"core"}},
+			{Name: "system-config-service", Aliases: []string{"system-config", "config"}, Port: 8085, HealthPath: "/health", Repo: repo("go-system-config-service"), Source: source("go-system-config-service"), Groups: []string{"core"}},
+			{Name: "mongodb", Aliases: []string{"mongo"}, Port: 27017, Groups: []string{"infra"}},
+			{Name: "redis", Port: 6379, Check: CheckRedis, Groups: []string{"infra"}},
+			{Name: "rabbitmq", Aliases: []string{"rabbit"}, Port: 5672, Groups: []string{"infra"}},
+			{Name: "prometheus", Aliases: []string{"prom"}, Port: 9090, HealthPath: "/-/healthy", Groups: []string{"observability"}},
+			{Name: "grafana", Port: 3000, HealthPath: "/api/health", Groups: []string{"observability"}},
+			{Name: "jaeger", Port: 16686, HealthPath: "/", Groups: []string{"observability"}},
+		},
+		K8sBase:       "k8s/base",
+		K8sExcludeEnv: []string{"USERS_FILE", "DATA_FILE", "TENANTS_FILE", "ROUTES_FILE", "CONFIG_FILE", "SMTP_LISTEN_PORT"},
+		Environments: []Environment{
+			{
+				Name: "local", Description: "Local Kubernetes cluster (minikube, kind, Docker Desktop)", Namespace: "go-dev",
+				Overlay: Overlay{
+					Replicas: map[string]int{"api-gateway": 1},
+					Config:   devConfig(),
+					Set: []string{
+						"service/api-gateway:spec.type=NodePort",
+						"deployment/api-gateway:spec.template.spec.containers.0.imagePullPolicy=IfNotPresent",
+					},
+				},
+			},
+			{
+				Name: "dev", Description: "Shared development cluster", Namespace: "go-dev",
+				Overlay: Overlay{
+					Replicas: map[string]int{"api-gateway": 2},
+					Config:   devConfig(),
+				},
+			},
+			{
+				Name: "staging", Description: "Staging cluster, deployed by promotion from dev", KubeContext: "staging", Namespace: "go-staging",
+				OverlayPath: "k8s/overlays/staging", Protected: true, RequiredApprovals: 1,
+				Overlay: Overlay{
+					Replicas: map[string]int{"api-gateway": 2},
+					Config:   map[string]string{"ENVIRONMENT": "staging", "LOG_LEVEL": "info", "MONGODB_DATABASE": "go_staging"},
+				},
+			},
+		},
+	}
+}
+
+func devConfig() map[string]string {
+	return map[string]string{"ENVIRONMENT": "development", "LOG_LEVEL": "debug", "MONGODB_DATABASE": "go_dev"}
+}
+
+func repo(name string) string {
+	return "https://github.com/vhvplatform/" + name + ".git"
+}
+
+// source is where scripts/setup/clone-repos.sh checks out a service
+// repository, relative to the server directory of this repository.
+func source(name string) string {
+	return "../../" + name
+}
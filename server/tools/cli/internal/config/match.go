@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UnknownServiceError is returned by Match when nothing matches a name.
+type UnknownServiceError struct {
+	Name string
+	// Suggestions are close names, best first.
+	Suggestions []string
+}
+
+func (e *UnknownServiceError) Error() string {
+	msg := fmt.Sprintf("unknown service %q", e.Name)
+	if len(e.Suggestions) > 0 {
+		msg += fmt.Sprintf(", did you mean %s?", strings.Join(e.Suggestions, " or "))
+	}
+	return msg
+}
+
+// AmbiguousServiceError is returned by Match when a prefix matches more than
+// one service.
+type AmbiguousServiceError struct {
+	Name    string
+	Matches []string
+}
+
+func (e *AmbiguousServiceError) Error() string {
+	return fmt.Sprintf("service %q is ambiguous, it matches %s", e.Name, strings.Join(e.Matches, ", "))
+}
+
+// AddComposeServices registers compose services that aren't in the registry
+// yet, so they can be addressed by their compose name.
+func (c *Config) AddComposeServices(names []string) {
+	for _, name := range names {
+		if c.Service(name) == nil {
+			c.Services = append(c.Services, Service{Name: name})
+		}
+	}
+}
+
+// Names returns every name a service can be addressed by: canonical names
+// and aliases, sorted.
+func (c *Config) Names() []string {
+	var names []string
+	for _, svc := range c.Services {
+		names = append(names, svc.Name)
+		names = append(names, svc.Aliases...)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Match resolves a command-line service argument. It accepts a canonical
+// name, an alias, a compose service name or a prefix of any of them that
+// identifies a single service.
+func (c *Config) Match(name string) (*Service, error) {
+	if svc := c.Service(name); svc != nil {
+		return svc, nil
+	}
+
+	var matches []*Service
+	seen := map[string]bool{}
+	for i := range c.Services {
+		svc := &c.Services[i]
+		for _, candidate := range append([]string{svc.Name, svc.ComposeName()}, svc.Aliases...) {
+			if strings.HasPrefix(candidate, name) && !seen[svc.Name] {
+				seen[svc.Name] = true
+				matches = append(matches, svc)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		return nil, &UnknownServiceError{Name: name, Suggestions: c.suggest(name)}
+	default:
+		names := make([]string, len(matches))
+		for i, svc := range matches {
+			names[i] = svc.Name
+		}
+		sort.Strings(names)
+		return nil, &AmbiguousServiceError{Name: name, Matches: names}
+	}
+}
+
+// suggest returns the service names closest to name by edit distance.
+func (c *Config) suggest(name string) []string {
+	limit := len(name)/3 + 1
+	if limit > 3 {
+		limit = 3
+	}
+
+	best := map[string]int{}
+	for _, svc := range c.Services {
+		for _, candidate := range append([]string{svc.Name}, svc.Aliases...) {
+			d := distance(name, candidate)
+			if d > limit {
+				continue
+			}
+			if prev, ok := best[svc.Name]; !ok || d < prev {
+				best[svc.Name] = d
+			}
+		}
+	}
+
+	out := make([]string, 0, len(best))
+	for svc := range best {
+		out = append(out, svc)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if best[out[i]] != best[out[j]] {
+			return best[out[i]] < best[out[j]]
+		}
+		return out[i] < out[j]
+	})
+	if len(out) > 3 {
+		out = out[:3]
+	}
+	return out
+}
+
+// distance is the Levenshtein edit distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
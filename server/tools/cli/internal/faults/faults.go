@@ -0,0 +1,175 @@
+// Package faults manages the fault injection rules of the mock services
+// through the /__admin/faults API every mock serves.
+package faults
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Path is the admin endpoint of the rules.
+const Path = "/__admin/faults"
+
+// Rule is a fault rule as the mocks describe it. Latency and TTL are Go
+// duration strings such as "1.5s".
+type Rule struct {
+	ID        string    `json:"id,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Method    string    `json:"method,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Latency   string    `json:"latency,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	Drop      bool      `json:"drop,omitempty"`
+	Unhealthy bool      `json:"unhealthy,omitempty"`
+	Rate      float64   `json:"rate"`
+	TTL       string    `json:"ttl,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	Hits      int       `json:"hits,omitempty"`
+}
+
+// Effect describes what the rule does, e.g. "latency 2s, status 503".
+func (r Rule) Effect() string {
+	var parts []string
+	if r.Latency != "" {
+		parts = append(parts, "latency "+r.Latency)
+	}
+	if r.Status != 0 {
+		parts = append(parts, fmt.Sprintf("status %d", r.Status))
+	}
+	if r.Drop {
+		parts = append(parts, "drop")
+	}
+	if r.Unhealthy {
+		parts = append(parts, "unhealthy")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Scope describes the requests the rule matches, e.g. "GET /api/v1/auth*".
+func (r Rule) Scope() string {
+	if r.Unhealthy {
+		return "/health, /ready"
+	}
+	method, path := r.Method, r.Path
+	if method == "" {
+		method = "*"
+	}
+	if path == "" {
+		path = "/"
+	}
+	scope := method + " " + path + "*"
+	if r.Tenant != "" {
+		scope += " tenant=" + r.Tenant
+	}
+	return scope
+}
+
+// FieldError is an invalid field reported by the API.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError is an error response of the API.
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Details []FieldError
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	for _, d := range e.Details {
+		msg += fmt.Sprintf("; %s %s", d.Field, d.Message)
+	}
+	return msg
+}
+
+// Client talks to the fault API of one mock.
+type Client struct {
+	// BaseURL is the root of the mock, e.g. http://localhost:8081.
+	BaseURL string
+	HTTP    *http.Client
+}
+
+func NewClient(baseURL string, timeout time.Duration) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTP: &http.Client{Timeout: timeout}}
+}
+
+// Add creates a rule and returns it as the mock stored it.
+func (c *Client) Add(ctx context.Context, rule Rule) (Rule, error) {
+	var out Rule
+	err := c.do(ctx, http.MethodPost, Path, rule, &out)
+	return out, err
+}
+
+// List returns the active rules.
+func (c *Client) List(ctx context.Context) ([]Rule, error) {
+	var out struct {
+		Data []Rule `json:"data"`
+	}
+	err := c.do(ctx, http.MethodGet, Path, nil, &out)
+	return out.Data, err
+}
+
+// Delete removes the rule with id.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, Path+"/"+id, nil, nil)
+}
+
+// Clear removes every rule.
+func (c *Client) Clear(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, Path, nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Code    string       `json:"code"`
+				Message string       `json:"message"`
+				Details []FieldError `json:"details"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return &APIError{Status: resp.StatusCode, Code: e.Error.Code, Message: e.Error.Message, Details: e.Error.Details}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
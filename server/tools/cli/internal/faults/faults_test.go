@@ -0,0 +1,121 @@
+package faults
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeMock serves the fault API the way mockkit does, keeping rules in
+// memory.
+func fakeMock(t *testing.T) (*Client, *[]Rule) {
+	t.Helper()
+	var rules []Rule
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode(map[string]any{"data": rules})
+		case http.MethodPost:
+			var rule Rule
+			json.NewDecoder(r.Body).Decode(&rule)
+			if rule.Status > 599 {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"error":{"code":"validation_failed","message":"the fault is invalid","details":[{"field":"status","message":"must be from 200 to 599"}]}}`))
+				return
+			}
+			rule.ID = "fault-1"
+			rule.Rate = 1
+			rules = append(rules, rule)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(rule)
+		case http.MethodDelete:
+			rules = nil
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	mux.HandleFunc(Path+"/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != Path+"/fault-1" || len(rules) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":"not_found","message":"fault not found"}}`))
+			return
+		}
+		rules = nil
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL+"/", time.Second), &rules
+}
+
+func TestClientAddListDelete(t *testing.T) {
+	client, rules := fakeMock(t)
+	ctx := context.Background()
+
+	added, err := client.Add(ctx, Rule{Path: "/api/v1/auth", Status: 503, Latency: "2s"})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	want := Rule{ID: "fault-1", Path: "/api/v1/auth", Status: 503, Latency: "2s", Rate: 1}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("Add() = %+v, want %+v", added, want)
+	}
+
+	listed, err := client.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if !reflect.DeepEqual(listed, []Rule{want}) {
+		t.Errorf("List() = %+v, want %+v", listed, []Rule{want})
+	}
+
+	if err := client.Delete(ctx, "fault-1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if len(*rules) != 0 {
+		t.Errorf("rules after Delete() = %+v", *rules)
+	}
+
+	var apiErr *APIError
+	if err := client.Delete(ctx, "fault-1"); !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+		t.Errorf("second Delete() error = %v, want a 404 APIError", err)
+	}
+}
+
+func TestClientReportsValidationErrors(t *testing.T) {
+	client, _ := fakeMock(t)
+
+	_, err := client.Add(context.Background(), Rule{Status: 700})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Add() error = %v, want an APIError", err)
+	}
+	if got, want := err.Error(), "the fault is invalid; status must be from 200 to 599"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestRuleDescriptions(t *testing.T) {
+	tests := []struct {
+		rule          Rule
+		effect, scope string
+	}{
+		{Rule{Latency: "2s", Status: 503, Method: "GET", Path: "/api/v1/auth"}, "latency 2s, status 503", "GET /api/v1/auth*"},
+		{Rule{Drop: true, Tenant: "tenant-2"}, "drop", "* /* tenant=tenant-2"},
+		{Rule{Unhealthy: true}, "unhealthy", "/health, /ready"},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Effect(); got != tt.effect {
+			t.Errorf("Effect() = %q, want %q", got, tt.effect)
+		}
+		if got := tt.rule.Scope(); got != tt.scope {
+			t.Errorf("Scope() = %q, want %q", got, tt.scope)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Package health probes service health endpoints and infrastructure ports
+// and classifies each target as healthy, degraded or down.
+package health
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind selects how a target is probed.
+type Kind string
+
+const (
+	// KindHTTP issues a GET request and inspects the JSON health response.
+	KindHTTP Kind = "http"
+	// KindTCP only checks that the port accepts connections.
+	KindTCP Kind = "tcp"
+	// KindRedis sends a Redis PING and expects PONG.
+	KindRedis Kind = "redis"
+)
+
+// State is the classified health of a target.
+type State string
+
+const (
+	// StateHealthy means the target responded quickly and reported healthy.
+	StateHealthy State = "healthy"
+	// StateDegraded means the target responded but was slow or reported a
+	// non-healthy status.
+	StateDegraded State = "degraded"
+	// StateDown means the target could not be reached or returned an error.
+	StateDown State = "down"
+)
+
+// HealthResponse is the body returned by the services' /health endpoints.
+type HealthResponse struct {
+	Status  string `json:"status"`
+	Service string `json:"service"`
+}
+
+// Target is a single thing to probe.
+type Target struct {
+	// Name is the display name, e.g. "auth-service".
+	Name string
+	// Kind selects the probe.
+	Kind Kind
+	// Address is a URL for KindHTTP and host:port otherwise.
+	Address string
+}
+
+// Result is the outcome of probing one target.
+type Result struct {
+	Name      string        `json:"name" yaml:"name"`
+	Kind      Kind          `json:"kind" yaml:"kind"`
+	Address   string        `json:"address" yaml:"address"`
+	State     State         `json:"state" yaml:"state"`
+	Status    string        `json:"status,omitempty" yaml:"status,omitempty"`
+	Latency   time.Duration `json:"-" yaml:"-"`
+	LatencyMS int64         `json:"latency_ms" yaml:"latency_ms"`
+	Error     string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Checker probes targets.
+type Checker struct {
+	// Timeout bounds each individual probe.
+	Timeout time.Duration
+	// SlowThreshold is the latency above which a responding target is
+	// classified as degraded.
+	SlowThreshold time.Duration
+	// Client is used for HTTP probes.
+	Client *http.Client
+}
+
+// NewChecker returns a Checker with the given per-probe timeout and slow
+// threshold.
+func NewChecker(timeout, slow time.Duration) *Checker {
+	return &Checker{
+		Timeout:       timeout,
+		SlowThreshold: slow,
+		Client:        &http.Client{Timeout: timeout},
+	}
+}
+
+// CheckAll probes all targets concurrently and returns results in the order
+// of the targets.
+func (c *Checker) CheckAll(ctx context.Context, targets []Target) []Result {
+	results := make([]Result, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			results[i] = c.Check(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+// Check probes a single target.
+func (c *Checker) Check(ctx context.Context, target Target) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	result := Result{Name: target.Name, Kind: target.Kind, Address: target.Address}
+	started := time.Now()
+
+	var status string
+	var err error
+	switch target.Kind {
+	case KindHTTP:
+		status, err = c.probeHTTP(ctx, target.Address)
+	case KindTCP:
+		err = c.probeTCP(ctx, target.Address)
+	case KindRedis:
+		err = c.probeRedis(ctx, target.Address)
+	default:
+		err = fmt.Errorf("unknown probe kind %q", target.Kind)
+	}
+
+	result.Latency = time.Since(started)
+	result.LatencyMS = result.Latency.Milliseconds()
+	result.Status = status
+	result.State = c.classify(status, result.Latency, err)
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (c *Checker) classify(status string, latency time.Duration, err error) State {
+	if err != nil {
+		return StateDown
+	}
+	if status != "" && !strings.EqualFold(status, string(StateHealthy)) && !strings.EqualFold(status, "ok") {
+		return StateDegraded
+	}
+	if c.SlowThreshold > 0 && latency > c.SlowThreshold {
+		return StateDegraded
+	}
+	return StateHealthy
+}
+
+// probeHTTP returns the status field of a JSON health response, or "" when
+// the endpoint answers 2xx with a body that isn't a HealthResponse.
+func (c *Checker) probeHTTP(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var health HealthResponse
+	_ = json.Unmarshal(body, &health)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if health.Status != "" {
+			return health.Status, fmt.Errorf("HTTP %d (status %q)", resp.StatusCode, health.Status)
+		}
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return health.Status, nil
+}
+
+func (c *Checker) probeTCP(ctx context.Context, addr string) error {
+	conn, err := c.dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (c *Checker) probeRedis(ctx context.Context, addr string) error {
+	conn, err := c.dial(ctx, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		return err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(line) != "+PONG" {
+		return fmt.Errorf("unexpected PING reply %q", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+func (c *Checker) dial(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", addr)
+}
+
+// Summary counts results per state.
+func Summary(results []Result) map[State]int {
+	counts := map[State]int{}
+	for _, r := range results {
+		counts[r.State]++
+	}
+	return counts
+}
@@ -0,0 +1,136 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func healthServer(t *testing.T, code int, status string, delay time.Duration) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(HealthResponse{Status: status, Service: "test"})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCheckClassifiesHTTPTargets(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   int
+		status string
+		delay  time.Duration
+		want   State
+	}{
+		{"healthy", http.StatusOK, "healthy", 0, StateHealthy},
+		{"reports degraded", http.StatusOK, "degraded", 0, StateDegraded},
+		{"slow", http.StatusOK, "healthy", 150 * time.Millisecond, StateDegraded},
+		{"server error", http.StatusServiceUnavailable, "unhealthy", 0, StateDown},
+	}
+
+	checker := NewChecker(2*time.Second, 100*time.Millisecond)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := healthServer(t, tt.code, tt.status, tt.delay)
+			got := checker.Check(context.Background(), Target{Name: "svc", Kind: KindHTTP, Address: srv.URL + "/health"})
+			if got.State != tt.want {
+				t.Errorf("State = %s, want %s (error %q)", got.State, tt.want, got.Error)
+			}
+		})
+	}
+}
+
+func TestCheckTCPAndRedis(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 64)
+			n, _ := conn.Read(buf)
+			if strings.HasPrefix(string(buf[:n]), "PING") {
+				conn.Write([]byte("+PONG\r\n"))
+			}
+			conn.Close()
+		}
+	}()
+
+	checker := NewChecker(time.Second, time.Second)
+	ctx := context.Background()
+
+	if got := checker.Check(ctx, Target{Kind: KindTCP, Address: ln.Addr().String()}); got.State != StateHealthy {
+		t.Errorf("tcp State = %s, want healthy (%s)", got.State, got.Error)
+	}
+	if got := checker.Check(ctx, Target{Kind: KindRedis, Address: ln.Addr().String()}); got.State != StateHealthy {
+		t.Errorf("redis State = %s, want healthy (%s)", got.State, got.Error)
+	}
+}
+
+func TestCheckUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	got := NewChecker(time.Second, time.Second).Check(context.Background(), Target{Kind: KindTCP, Address: addr})
+	if got.State != StateDown || got.Error == "" {
+		t.Errorf("got %+v, want down with error", got)
+	}
+}
+
+func TestWaitBacksOffUntilReady(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		status := "starting"
+		if calls >= 3 {
+			status = "healthy"
+		}
+		json.NewEncoder(w).Encode(HealthResponse{Status: status})
+	}))
+	defer srv.Close()
+
+	checker := NewChecker(time.Second, 0)
+	results := checker.Wait(context.Background(), []Target{{Name: "svc", Kind: KindHTTP, Address: srv.URL}}, WaitOptions{
+		Timeout:  5 * time.Second,
+		Interval: 10 * time.Millisecond,
+	})
+
+	if !results[0].Ready || results[0].Attempts != 3 {
+		t.Errorf("got ready=%v attempts=%d, want ready after 3 attempts", results[0].Ready, results[0].Attempts)
+	}
+}
+
+func TestWaitTimesOut(t *testing.T) {
+	srv := healthServer(t, http.StatusServiceUnavailable, "unhealthy", 0)
+
+	checker := NewChecker(time.Second, 0)
+	results := checker.Wait(context.Background(), []Target{{Name: "svc", Kind: KindHTTP, Address: srv.URL}}, WaitOptions{
+		Timeout:  100 * time.Millisecond,
+		Interval: 20 * time.Millisecond,
+	})
+
+	if results[0].Ready {
+		t.Fatal("expected target not to become ready")
+	}
+	if results[0].Attempts < 2 {
+		t.Errorf("expected several attempts before timing out, got %d", results[0].Attempts)
+	}
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WaitOptions controls how long and how often Wait polls.
+type WaitOptions struct {
+	// Timeout is the overall deadline for all targets.
+	Timeout time.Duration
+	// Interval is the delay before the second attempt. It doubles after
+	// every failed attempt, up to MaxInterval.
+	Interval time.Duration
+	// MaxInterval caps the backoff delay.
+	MaxInterval time.Duration
+	// OnReady, when set, is called as soon as a target becomes ready.
+	OnReady func(WaitResult)
+}
+
+// WaitResult reports how waiting for one target ended.
+type WaitResult struct {
+	Target   Target
+	Ready    bool
+	Attempts int
+	Elapsed  time.Duration
+	// Last is the result of the final probe.
+	Last Result
+}
+
+// Wait polls every target concurrently until it is ready or the timeout
+// expires. A target is ready when a probe classifies it as healthy; latency
+// is not taken into account. Results are returned in the order of targets.
+func (c *Checker) Wait(ctx context.Context, targets []Target, opts WaitOptions) []WaitResult {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	probe := *c
+	probe.SlowThreshold = 0
+
+	results := make([]WaitResult, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			res := probe.waitOne(ctx, target, opts)
+			results[i] = res
+			if res.Ready && opts.OnReady != nil {
+				mu.Lock()
+				opts.OnReady(res)
+				mu.Unlock()
+			}
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+func (c *Checker) waitOne(ctx context.Context, target Target, opts WaitOptions) WaitResult {
+	started := time.Now()
+	res := WaitResult{Target: target}
+	delay := opts.Interval
+
+	for {
+		res.Attempts++
+		res.Last = c.Check(ctx, target)
+		res.Elapsed = time.Since(started)
+		if res.Last.State == StateHealthy {
+			res.Ready = true
+			return res
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			res.Elapsed = time.Since(started)
+			return res
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if opts.MaxInterval > 0 && delay > opts.MaxInterval {
+			delay = opts.MaxInterval
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package history records what `saas deploy` deployed, so deployments can
+// be listed, rolled back to and promoted between environments.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Deployment states.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Record is one deployment to an environment.
+type Record struct {
+	// ID numbers the deployments of an environment from 1.
+	ID   int       `json:"id"`
+	Env  string    `json:"env"`
+	Time time.Time `json:"time"`
+	User string    `json:"user"`
+	// GitSHA is the commit the manifests were rendered from.
+	GitSHA string `json:"git_sha"`
+	// Images maps container names to the deployed image references.
+	Images map[string]string `json:"images"`
+	// ConfigHash identifies the rendered ConfigMaps and Secrets.
+	ConfigHash string `json:"config_hash"`
+	// Set holds the --set expressions given on the command line.
+	Set []string `json:"set,omitempty"`
+	// RollbackOf is the deployment this one restored, if any.
+	RollbackOf int `json:"rollback_of,omitempty"`
+	// PromotedFrom is the deployment whose images were promoted, as
+	// <env>#<id>.
+	PromotedFrom string `json:"promoted_from,omitempty"`
+	// ApprovedBy lists the people named with --approved-by.
+	ApprovedBy []string `json:"approved_by,omitempty"`
+	Status     string   `json:"status"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Store keeps one JSON Lines file of records per environment in Dir.
+type Store struct {
+	Dir string
+}
+
+func (s *Store) path(env string) string {
+	return filepath.Join(s.Dir, env+".jsonl")
+}
+
+// List returns the records of env, oldest first. An environment that was
+// never deployed has no records.
+func (s *Store) List(env string) ([]Record, error) {
+	f, err := os.Open(s.path(env))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", s.path(env), line, err)
+		}
+		records = append(records, r)
+	}
+	return records, scanner.Err()
+}
+
+// NextID returns the ID the next deployment of env gets.
+func (s *Store) NextID(env string) (int, error) {
+	records, err := s.List(env)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 {
+		return 1, nil
+	}
+	return records[len(records)-1].ID + 1, nil
+}
+
+// Get returns the record of env with the given ID.
+func (s *Store) Get(env string, id int) (*Record, error) {
+	records, err := s.List(env)
+	if err != nil {
+		return nil, err
+	}
+	for i := range records {
+		if records[i].ID == id {
+			return &records[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no deployment %d of %s (see 'saas deploy history %s')", id, env, env)
+}
+
+// LastSucceeded returns the newest successful deployment of env, skipping
+// the IDs in skip.
+func (s *Store) LastSucceeded(env string, skip ...int) (*Record, error) {
+	records, err := s.List(env)
+	if err != nil {
+		return nil, err
+	}
+next:
+	for i := len(records) - 1; i >= 0; i-- {
+		if records[i].Status != StatusSucceeded {
+			continue
+		}
+		for _, id := range skip {
+			if records[i].ID == id {
+				continue next
+			}
+		}
+		return &records[i], nil
+	}
+	return nil, fmt.Errorf("no successful deployment of %s recorded", env)
+}
+
+// Append adds r to the history of r.Env.
+func (s *Store) Append(r Record) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path(r.Env), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ParseID parses a deployment ID as given on the command line, with or
+// without a leading '#'.
+func ParseID(s string) (int, error) {
+	if len(s) > 0 && s[0] == '#' {
+		s = s[1:]
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("invalid deployment id %q", s)
+	}
+	return id, nil
+}
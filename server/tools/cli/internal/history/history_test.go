@@ -0,0 +1,83 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	s := &Store{Dir: filepath.Join(t.TempDir(), "deployments")}
+
+	if records, err := s.List("dev"); err != nil || records != nil {
+		t.Fatalf("List of a new environment = %v, %v", records, err)
+	}
+	if id, err := s.NextID("dev"); err != nil || id != 1 {
+		t.Fatalf("NextID = %d, %v, want 1", id, err)
+	}
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, r := range []Record{
+		{ID: 1, Env: "dev", Time: at, Images: map[string]string{"api": "r/api:v1"}, Status: StatusSucceeded},
+		{ID: 2, Env: "dev", Time: at, Images: map[string]string{"api": "r/api:v2"}, Status: StatusSucceeded},
+		{ID: 3, Env: "dev", Time: at, Status: StatusFailed, Error: "rollout timed out"},
+		{ID: 1, Env: "staging", Time: at, Status: StatusSucceeded},
+	} {
+		if err := s.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := s.List("dev")
+	if err != nil || len(records) != 3 {
+		t.Fatalf("List = %v, %v", records, err)
+	}
+	if r := records[1]; !r.Time.Equal(at) || r.Images["api"] != "r/api:v2" {
+		t.Errorf("record 2 did not round-trip: %+v", r)
+	}
+	if id, _ := s.NextID("dev"); id != 4 {
+		t.Errorf("NextID = %d, want 4", id)
+	}
+
+	if r, err := s.LastSucceeded("dev"); err != nil || r.ID != 2 {
+		t.Errorf("LastSucceeded = %+v, %v, want #2", r, err)
+	}
+	if r, err := s.LastSucceeded("dev", 2); err != nil || r.ID != 1 {
+		t.Errorf("LastSucceeded skipping #2 = %+v, %v, want #1", r, err)
+	}
+	if _, err := s.LastSucceeded("dev", 1, 2); err == nil {
+		t.Error("LastSucceeded with every success skipped should fail")
+	}
+
+	if r, err := s.Get("dev", 3); err != nil || r.Error != "rollout timed out" {
+		t.Errorf("Get(3) = %+v, %v", r, err)
+	}
+	if _, err := s.Get("dev", 7); err == nil || !strings.Contains(err.Error(), "no deployment 7 of dev") {
+		t.Errorf("Get(7) error = %v", err)
+	}
+}
+
+func TestStoreCorrupt(t *testing.T) {
+	s := &Store{Dir: t.TempDir()}
+	if err := os.WriteFile(filepath.Join(s.Dir, "dev.jsonl"), []byte("{\"id\":1}\n\nnot json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.List("dev"); err == nil || !strings.Contains(err.Error(), "dev.jsonl:3:") {
+		t.Errorf("List error = %v, want the line of the bad record", err)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	for in, want := range map[string]int{"4": 4, "#12": 12} {
+		if got, err := ParseID(in); err != nil || got != want {
+			t.Errorf("ParseID(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "#", "0", "-1", "four"} {
+		if _, err := ParseID(in); err == nil {
+			t.Errorf("ParseID(%q) should fail", in)
+		}
+	}
+}
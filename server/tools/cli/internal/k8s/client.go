@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/runner"
+)
+
+// Client talks to a Kubernetes cluster.
+type Client interface {
+	// Diff returns a unified diff between objs and the live objects. It is
+	// empty when the cluster already matches.
+	Diff(ctx context.Context, objs []Object) (string, error)
+	// Apply creates or updates obj.
+	Apply(ctx context.Context, obj Object) error
+	// RolloutStatus waits until the rollout of a workload has finished.
+	RolloutStatus(ctx context.Context, obj Object, timeout time.Duration) error
+	// RolloutUndo rolls a workload back to its previous revision.
+	RolloutUndo(ctx context.Context, obj Object) error
+}
+
+// Kubectl is a Client that runs kubectl.
+type Kubectl struct {
+	// Runner runs the kubectl commands.
+	Runner runner.Runner
+	// Context is the kubeconfig context; empty means the current one.
+	Context string
+}
+
+func (k *Kubectl) command(args ...string) runner.Command {
+	if k.Context != "" {
+		args = append([]string{"--context", k.Context}, args...)
+	}
+	return runner.Command{Name: "kubectl", Args: args}
+}
+
+// Diff implements Client with kubectl diff, which exits with status 1 when
+// there are differences.
+func (k *Kubectl) Diff(ctx context.Context, objs []Object) (string, error) {
+	manifest, err := Encode(objs)
+	if err != nil {
+		return "", err
+	}
+
+	var out, stderr bytes.Buffer
+	cmd := k.command("diff", "-f", "-")
+	cmd.Stdin = bytes.NewReader(manifest)
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	err = k.Runner.Run(ctx, cmd)
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && exit.ExitCode() == 1 {
+		return out.String(), nil
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Apply implements Client.
+func (k *Kubectl) Apply(ctx context.Context, obj Object) error {
+	manifest, err := Encode([]Object{obj})
+	if err != nil {
+		return err
+	}
+	cmd := k.command("apply", "-f", "-")
+	cmd.Stdin = bytes.NewReader(manifest)
+	return runner.Quiet(ctx, k.Runner, cmd)
+}
+
+// RolloutStatus implements Client.
+func (k *Kubectl) RolloutStatus(ctx context.Context, obj Object, timeout time.Duration) error {
+	return runner.Quiet(ctx, k.Runner, k.command("rollout", "status", obj.Ref(), "-n", obj.Namespace(), "--timeout", timeout.String()))
+}
+
+// RolloutUndo implements Client.
+func (k *Kubectl) RolloutUndo(ctx context.Context, obj Object) error {
+	return runner.Quiet(ctx, k.Runner, k.command("rollout", "undo", obj.Ref(), "-n", obj.Namespace()))
+}
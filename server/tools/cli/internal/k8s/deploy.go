@@ -0,0 +1,100 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Deployer applies rendered manifests phase by phase.
+type Deployer struct {
+	Client Client
+	// Out receives progress lines and the diff.
+	Out io.Writer
+	// Timeout bounds the wait for each rollout.
+	Timeout time.Duration
+}
+
+// Diff prints the difference between objs and the cluster and reports
+// whether there is any. A cluster that cannot be diffed, for example
+// because the namespace does not exist yet, counts as changed.
+func (d *Deployer) Diff(ctx context.Context, objs []Object) bool {
+	diff, err := d.Client.Diff(ctx, objs)
+	switch {
+	case err != nil:
+		fmt.Fprintf(d.Out, "⚠️  Could not diff against the cluster: %v\n", err)
+		return true
+	case strings.TrimSpace(diff) == "":
+		fmt.Fprintln(d.Out, "✓ The cluster is up to date")
+		return false
+	default:
+		fmt.Fprintln(d.Out, "📝 Changes:")
+		fmt.Fprint(d.Out, diff)
+		if !strings.HasSuffix(diff, "\n") {
+			fmt.Fprintln(d.Out)
+		}
+		return true
+	}
+}
+
+// Apply applies objs in phase order and waits for the workloads of each
+// phase to roll out before starting the next.
+func (d *Deployer) Apply(ctx context.Context, objs []Object) error {
+	objs = append([]Object(nil), objs...)
+	Sort(objs)
+
+	phaseOf := phaseFunc(objs)
+	for _, phase := range Phases(objs) {
+		fmt.Fprintf(d.Out, "☸️  Applying %s...\n", phaseOf(phase[0]))
+		for _, obj := range phase {
+			if err := d.Client.Apply(ctx, obj); err != nil {
+				return fmt.Errorf("apply %s: %w", obj.Ref(), err)
+			}
+			fmt.Fprintf(d.Out, "   ✓ %s\n", obj.Ref())
+		}
+		if err := d.wait(ctx, phase); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback rolls every workload in objs back to its previous revision, in
+// reverse phase order, and waits for the rollouts.
+func (d *Deployer) Rollback(ctx context.Context, objs []Object) error {
+	objs = append([]Object(nil), objs...)
+	Sort(objs)
+
+	var workloads []Object
+	for i := len(objs) - 1; i >= 0; i-- {
+		if IsWorkload(objs[i]) {
+			workloads = append(workloads, objs[i])
+		}
+	}
+	if len(workloads) == 0 {
+		return fmt.Errorf("no workloads to roll back")
+	}
+
+	for _, obj := range workloads {
+		if err := d.Client.RolloutUndo(ctx, obj); err != nil {
+			return fmt.Errorf("roll back %s: %w", obj.Ref(), err)
+		}
+		fmt.Fprintf(d.Out, "   ↩️  %s\n", obj.Ref())
+	}
+	return d.wait(ctx, workloads)
+}
+
+func (d *Deployer) wait(ctx context.Context, objs []Object) error {
+	for _, obj := range objs {
+		if !IsWorkload(obj) {
+			continue
+		}
+		if err := d.Client.RolloutStatus(ctx, obj, d.Timeout); err != nil {
+			return fmt.Errorf("rollout of %s did not finish: %w", obj.Ref(), err)
+		}
+		fmt.Fprintf(d.Out, "   ✓ %s rolled out\n", obj.Ref())
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fake is an in-memory Client. It keeps the applied objects and the
+// revisions of every workload so rollbacks can be tested. It is meant for
+// tests.
+type Fake struct {
+	// Fail, when set, is called before every operation with the operation
+	// name (diff, apply, rollout-status or rollout-undo) and the object,
+	// which is nil for diff. A non-nil result fails the operation.
+	Fail func(op string, obj Object) error
+
+	mu        sync.Mutex
+	objects   map[string]Object
+	revisions map[string][]Object
+	log       []string
+}
+
+func key(obj Object) string {
+	return obj.Namespace() + "/" + obj.Ref()
+}
+
+func (f *Fake) record(op string, obj Object) error {
+	f.mu.Lock()
+	entry := op
+	if obj != nil {
+		entry += " " + obj.Ref()
+	}
+	f.log = append(f.log, entry)
+	f.mu.Unlock()
+
+	if f.Fail != nil {
+		return f.Fail(op, obj)
+	}
+	return nil
+}
+
+// Diff implements Client. Each changed object is listed as a line starting
+// with "+" when it is new and "~" when it differs from the live object.
+func (f *Fake) Diff(_ context.Context, objs []Object) (string, error) {
+	if err := f.record("diff", nil); err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var b strings.Builder
+	for _, obj := range objs {
+		live, ok := f.objects[key(obj)]
+		switch {
+		case !ok:
+			fmt.Fprintf(&b, "+ %s\n", obj.Ref())
+		case !reflect.DeepEqual(live, obj):
+			fmt.Fprintf(&b, "~ %s\n", obj.Ref())
+		}
+	}
+	return b.String(), nil
+}
+
+// Apply implements Client.
+func (f *Fake) Apply(_ context.Context, obj Object) error {
+	if err := f.record("apply", obj); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.objects == nil {
+		f.objects = map[string]Object{}
+		f.revisions = map[string][]Object{}
+	}
+	k := key(obj)
+	if live, ok := f.objects[k]; ok && reflect.DeepEqual(live, obj) {
+		return nil
+	}
+	f.objects[k] = obj.Copy()
+	if IsWorkload(obj) {
+		f.revisions[k] = append(f.revisions[k], obj.Copy())
+	}
+	return nil
+}
+
+// RolloutStatus implements Client.
+func (f *Fake) RolloutStatus(_ context.Context, obj Object, _ time.Duration) error {
+	if err := f.record("rollout-status", obj); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.objects[key(obj)]; !ok {
+		return fmt.Errorf("%s not found", obj.Ref())
+	}
+	return nil
+}
+
+// RolloutUndo implements Client. Like kubectl, it makes the previous
+// revision the newest one.
+func (f *Fake) RolloutUndo(_ context.Context, obj Object) error {
+	if err := f.record("rollout-undo", obj); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	k := key(obj)
+	revs := f.revisions[k]
+	if len(revs) < 2 {
+		return fmt.Errorf("%s has no previous revision", obj.Ref())
+	}
+	prev := revs[len(revs)-2]
+	f.revisions[k] = append(revs, prev)
+	f.objects[k] = prev.Copy()
+	return nil
+}
+
+// Get returns the live object, if any.
+func (f *Fake) Get(kind, namespace, name string) (Object, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[namespace+"/"+strings.ToLower(kind)+"/"+name]
+	return obj, ok
+}
+
+// Objects returns the keys (namespace/kind/name) of the live objects,
+// sorted.
+func (f *Fake) Objects() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.objects))
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Log returns the operations in the order they were called, e.g.
+// "apply deployment/api-gateway".
+func (f *Fake) Log() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.log...)
+}
+
+// ResetLog forgets the recorded operations but keeps the cluster state.
+func (f *Fake) ResetLog() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.log = nil
+}
@@ -0,0 +1,281 @@
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workload describes an application service to generate manifests for.
+type Workload struct {
+	// Name is used for the Deployment, Service and app label.
+	Name      string
+	Namespace string
+	// Component is the component label: service or gateway.
+	Component string
+	Image     string
+	Replicas  int
+	// Ports are the container ports; HTTPPort is the one probed.
+	Ports    []Port
+	HTTPPort int
+	// HealthPath is probed for liveness and readiness.
+	HealthPath string
+	// ConfigMaps and Secrets are loaded with envFrom, in order.
+	ConfigMaps []string
+	Secrets    []string
+	// Env holds the variables of the workload's own ConfigMap, named
+	// <Name>-config. It is only generated when Env is not empty.
+	Env map[string]string
+}
+
+// Port is a named container port.
+type Port struct {
+	Name string
+	Port int
+}
+
+// ConfigMapName returns the name of the workload's own ConfigMap.
+func (w Workload) ConfigMapName() string {
+	return w.Name + "-config"
+}
+
+// Generate returns the manifests of w: its ConfigMap, when it has its own
+// environment, the Deployment and the Service.
+func Generate(w Workload) ([]byte, error) {
+	if w.HTTPPort == 0 {
+		return nil, fmt.Errorf("%s: no HTTP port to probe", w.Name)
+	}
+
+	var docs []any
+	configMaps := w.ConfigMaps
+	if len(w.Env) > 0 {
+		meta := w.metadata()
+		meta.Name = w.ConfigMapName()
+		docs = append(docs, configMapDoc{APIVersion: "v1", Kind: "ConfigMap", Metadata: meta, Data: w.Env})
+		configMaps = append(append([]string{}, configMaps...), w.ConfigMapName())
+	}
+
+	container := containerDoc{
+		Name:            w.Name,
+		Image:           w.Image,
+		ImagePullPolicy: "Always",
+		Resources: resourcesDoc{
+			Requests: map[string]string{"memory": "128Mi", "cpu": "100m"},
+			Limits:   map[string]string{"memory": "512Mi", "cpu": "500m"},
+		},
+		LivenessProbe: probeDoc{
+			HTTPGet:             httpGetDoc{Path: w.HealthPath, Port: w.HTTPPort},
+			InitialDelaySeconds: 30,
+			PeriodSeconds:       10,
+			TimeoutSeconds:      5,
+			FailureThreshold:    3,
+		},
+		ReadinessProbe: probeDoc{
+			HTTPGet:             httpGetDoc{Path: w.HealthPath, Port: w.HTTPPort},
+			InitialDelaySeconds: 10,
+			PeriodSeconds:       5,
+			TimeoutSeconds:      3,
+			FailureThreshold:    3,
+		},
+	}
+	for _, p := range w.Ports {
+		container.Ports = append(container.Ports, containerPortDoc{ContainerPort: p.Port, Name: p.Name, Protocol: "TCP"})
+	}
+	for _, name := range configMaps {
+		container.EnvFrom = append(container.EnvFrom, envFromDoc{ConfigMapRef: &refDoc{Name: name}})
+	}
+	for _, name := range w.Secrets {
+		container.EnvFrom = append(container.EnvFrom, envFromDoc{SecretRef: &refDoc{Name: name}})
+	}
+
+	selector := map[string]string{"app": w.Name}
+	docs = append(docs, deploymentDoc{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Metadata:   w.metadata(),
+		Spec: deploymentSpecDoc{
+			Replicas: w.Replicas,
+			Selector: selectorDoc{MatchLabels: selector},
+			Strategy: strategyDoc{
+				Type:          "RollingUpdate",
+				RollingUpdate: map[string]int{"maxSurge": 1, "maxUnavailable": 0},
+			},
+			Template: templateDoc{
+				Metadata: templateMetaDoc{Labels: selector},
+				Spec:     podSpecDoc{Containers: []containerDoc{container}},
+			},
+		},
+	})
+
+	svc := serviceDoc{
+		APIVersion: "v1",
+		Kind:       "Service",
+		Metadata:   w.metadata(),
+		Spec:       serviceSpecDoc{Selector: selector, Type: "ClusterIP"},
+	}
+	for _, p := range w.Ports {
+		svc.Spec.Ports = append(svc.Spec.Ports, servicePortDoc{Name: p.Name, Port: p.Port, TargetPort: p.Port, Protocol: "TCP"})
+	}
+	docs = append(docs, svc)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (w Workload) metadata() metadataDoc {
+	labels := map[string]string{"app": w.Name}
+	if w.Component != "" {
+		labels["component"] = w.Component
+	}
+	return metadataDoc{Name: w.Name, Namespace: w.Namespace, Labels: labels}
+}
+
+// The *Doc types fix the field order of generated manifests, which a
+// map[string]any would sort alphabetically.
+
+type metadataDoc struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type configMapDoc struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   metadataDoc `yaml:"metadata"`
+	Data       sortedMap   `yaml:"data"`
+}
+
+type deploymentDoc struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   metadataDoc       `yaml:"metadata"`
+	Spec       deploymentSpecDoc `yaml:"spec"`
+}
+
+type deploymentSpecDoc struct {
+	Replicas int         `yaml:"replicas"`
+	Selector selectorDoc `yaml:"selector"`
+	Strategy strategyDoc `yaml:"strategy"`
+	Template templateDoc `yaml:"template"`
+}
+
+type selectorDoc struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type strategyDoc struct {
+	Type          string         `yaml:"type"`
+	RollingUpdate map[string]int `yaml:"rollingUpdate"`
+}
+
+type templateDoc struct {
+	Metadata templateMetaDoc `yaml:"metadata"`
+	Spec     podSpecDoc      `yaml:"spec"`
+}
+
+type templateMetaDoc struct {
+	Labels map[string]string `yaml:"labels"`
+}
+
+type podSpecDoc struct {
+	Containers []containerDoc `yaml:"containers"`
+}
+
+type containerDoc struct {
+	Name            string             `yaml:"name"`
+	Image           string             `yaml:"image"`
+	ImagePullPolicy string             `yaml:"imagePullPolicy"`
+	Ports           []containerPortDoc `yaml:"ports,omitempty"`
+	EnvFrom         []envFromDoc       `yaml:"envFrom,omitempty"`
+	Resources       resourcesDoc       `yaml:"resources"`
+	LivenessProbe   probeDoc           `yaml:"livenessProbe"`
+	ReadinessProbe  probeDoc           `yaml:"readinessProbe"`
+}
+
+type containerPortDoc struct {
+	ContainerPort int    `yaml:"containerPort"`
+	Name          string `yaml:"name"`
+	Protocol      string `yaml:"protocol"`
+}
+
+type envFromDoc struct {
+	ConfigMapRef *refDoc `yaml:"configMapRef,omitempty"`
+	SecretRef    *refDoc `yaml:"secretRef,omitempty"`
+}
+
+type refDoc struct {
+	Name string `yaml:"name"`
+}
+
+type resourcesDoc struct {
+	Requests map[string]string `yaml:"requests"`
+	Limits   map[string]string `yaml:"limits"`
+}
+
+type probeDoc struct {
+	HTTPGet             httpGetDoc `yaml:"httpGet"`
+	InitialDelaySeconds int        `yaml:"initialDelaySeconds"`
+	PeriodSeconds       int        `yaml:"periodSeconds"`
+	TimeoutSeconds      int        `yaml:"timeoutSeconds"`
+	FailureThreshold    int        `yaml:"failureThreshold"`
+}
+
+type httpGetDoc struct {
+	Path string `yaml:"path"`
+	Port int    `yaml:"port"`
+}
+
+type serviceDoc struct {
+	APIVersion string         `yaml:"apiVersion"`
+	Kind       string         `yaml:"kind"`
+	Metadata   metadataDoc    `yaml:"metadata"`
+	Spec       serviceSpecDoc `yaml:"spec"`
+}
+
+type serviceSpecDoc struct {
+	Selector map[string]string `yaml:"selector"`
+	Ports    []servicePortDoc  `yaml:"ports"`
+	Type     string            `yaml:"type"`
+}
+
+type servicePortDoc struct {
+	Name       string `yaml:"name"`
+	Port       int    `yaml:"port"`
+	TargetPort int    `yaml:"targetPort"`
+	Protocol   string `yaml:"protocol"`
+}
+
+// sortedMap encodes string values quoted, so numbers such as ports stay
+// strings as ConfigMap data requires.
+type sortedMap map[string]string
+
+// MarshalYAML implements yaml.Marshaler.
+func (m sortedMap) MarshalYAML() (any, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, k := range keys {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: k},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: m[k], Style: yaml.DoubleQuotedStyle},
+		)
+	}
+	return node, nil
+}
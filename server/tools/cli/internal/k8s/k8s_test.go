@@ -0,0 +1,476 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/runner"
+)
+
+const testManifests = `# comment-only documents are skipped
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api-gateway
+  namespace: go-platform
+  labels: {app: api-gateway, component: gateway}
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: api-gateway
+        image: <your-registry>/go-api-gateway:latest
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: auth-service
+  namespace: go-platform
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: auth-service
+        image: localhost:5000/go-auth-service
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api-gateway
+  namespace: go-platform
+spec:
+  type: LoadBalancer
+  ports:
+  - port: 8080
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: go-platform-config
+  namespace: go-platform
+data:
+  LOG_LEVEL: info
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: go-platform
+  labels: {name: go-platform, environment: production}
+`
+
+func decode(t *testing.T) []Object {
+	t.Helper()
+	objs, err := Decode(strings.NewReader(testManifests))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	return objs
+}
+
+func find(objs []Object, ref string) Object {
+	for _, obj := range objs {
+		if obj.Ref() == ref {
+			return obj
+		}
+	}
+	return nil
+}
+
+func lookup(obj Object, path ...string) any {
+	var cur any = map[string]any(obj)
+	for _, key := range path {
+		switch node := cur.(type) {
+		case map[string]any:
+			cur = node[key]
+		case []any:
+			cur = node[0]
+			if key != "0" {
+				cur = cur.(map[string]any)[key]
+			}
+		default:
+			return nil
+		}
+	}
+	return cur
+}
+
+func refs(objs []Object) []string {
+	out := make([]string, len(objs))
+	for i, obj := range objs {
+		out[i] = obj.Ref()
+	}
+	return out
+}
+
+func TestSortByPhase(t *testing.T) {
+	objs := decode(t)
+	Sort(objs)
+	want := []string{"namespace/go-platform", "configmap/go-platform-config", "deployment/auth-service", "service/api-gateway", "deployment/api-gateway"}
+	if got := refs(objs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort() = %v, want %v", got, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	base := decode(t)
+	patches := []Patch{}
+	for _, s := range []string{"LOG_LEVEL=debug", "service/api-gateway:spec.type=NodePort", "deployment/api-gateway:spec.template.spec.containers.0.imagePullPolicy=IfNotPresent"} {
+		p, err := ParsePatch(s)
+		if err != nil {
+			t.Fatalf("ParsePatch(%q) error = %v", s, err)
+		}
+		patches = append(patches, p)
+	}
+
+	objs, err := Render(base, Overlay{
+		Namespace:     "go-dev",
+		Labels:        map[string]string{"environment": "dev"},
+		ImageRegistry: "ghcr.io/acme/",
+		ImageTags:     map[string]string{"": "v2", "auth-service": "sha-1"},
+		Replicas:      map[string]int{"api-gateway": 1},
+		ConfigMap:     "go-platform-config",
+		Config:        map[string]string{"ENVIRONMENT": "development"},
+		Patches:       patches,
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if err := CheckImages(objs); err != nil {
+		t.Errorf("CheckImages() error = %v", err)
+	}
+
+	ns := find(objs, "namespace/go-dev")
+	if ns == nil || ns.Labels()["name"] != "go-dev" || ns.Labels()["environment"] != "dev" {
+		t.Errorf("namespace not renamed and relabelled: %v", ns)
+	}
+	for _, obj := range objs {
+		if obj.Kind() != "Namespace" && obj.Namespace() != "go-dev" {
+			t.Errorf("%s namespace = %q, want go-dev", obj.Ref(), obj.Namespace())
+		}
+	}
+
+	want := map[string]string{"api-gateway": "ghcr.io/acme/go-api-gateway:v2", "auth-service": "ghcr.io/acme/go-auth-service:sha-1"}
+	if got := Images(objs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Images() = %v, want %v", got, want)
+	}
+
+	gateway := find(objs, "deployment/api-gateway")
+	if got := lookup(gateway, "spec", "replicas"); got != 1 {
+		t.Errorf("replicas = %v, want 1", got)
+	}
+	if got := lookup(gateway, "spec", "template", "spec", "containers", "0", "imagePullPolicy"); got != "IfNotPresent" {
+		t.Errorf("imagePullPolicy = %v", got)
+	}
+	if got := lookup(find(objs, "deployment/auth-service"), "spec", "replicas"); got != 2 {
+		t.Errorf("auth-service replicas = %v, want the base value 2", got)
+	}
+	if got := lookup(find(objs, "service/api-gateway"), "spec", "type"); got != "NodePort" {
+		t.Errorf("service type = %v", got)
+	}
+	data := lookup(find(objs, "configmap/go-platform-config"), "data")
+	if want := map[string]any{"LOG_LEVEL": "debug", "ENVIRONMENT": "development"}; !reflect.DeepEqual(data, want) {
+		t.Errorf("config data = %v, want %v", data, want)
+	}
+
+	if got := lookup(find(base, "deployment/api-gateway"), "spec", "replicas"); got != 3 {
+		t.Errorf("Render() modified the base objects")
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	base := decode(t)
+
+	if err := CheckImages(base); err == nil || !strings.Contains(err.Error(), "placeholder") {
+		t.Errorf("CheckImages() error = %v", err)
+	}
+	if _, err := Render(base, Overlay{ConfigMap: "missing", Config: map[string]string{"A": "b"}}); err == nil {
+		t.Error("expected an error for a missing ConfigMap")
+	}
+	p, _ := ParsePatch("deployment/nope:spec.replicas=1")
+	if _, err := Render(base, Overlay{Patches: []Patch{p}}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("unknown object error = %v", err)
+	}
+	p, _ = ParsePatch("deployment/api-gateway:spec.template.spec.containers.4.image=x")
+	if _, err := Render(base, Overlay{Patches: []Patch{p}}); err == nil {
+		t.Error("expected an error for an out of range list index")
+	}
+
+	for _, s := range []string{"novalue", "=x", "deployment:spec=1", "deployment/x:=1"} {
+		if _, err := ParsePatch(s); err == nil {
+			t.Errorf("ParsePatch(%q) should fail", s)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := decode(t)
+	extra := []Object{
+		{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]any{"name": "go-platform-config"}, "data": map[string]any{"A": "1"}},
+		{"apiVersion": "policy/v1", "kind": "PodDisruptionBudget", "metadata": map[string]any{"name": "api-gateway"}},
+	}
+
+	merged := Merge(base, extra)
+	if len(merged) != len(base)+1 {
+		t.Fatalf("Merge() = %v, want one object added", refs(merged))
+	}
+	if cm := find(merged, "configmap/go-platform-config"); !reflect.DeepEqual(cm["data"], map[string]any{"A": "1"}) {
+		t.Errorf("configmap not replaced: %v", cm)
+	}
+	if find(merged, "poddisruptionbudget/api-gateway") == nil {
+		t.Errorf("Merge() = %v, want the PodDisruptionBudget added", refs(merged))
+	}
+	if find(base, "configmap/go-platform-config")["data"] == nil {
+		t.Error("Merge() modified base")
+	}
+}
+
+func TestSplitImage(t *testing.T) {
+	tests := []struct{ image, name, tag string }{
+		{"nginx", "nginx", ""},
+		{"nginx:1.25", "nginx", "1.25"},
+		{"localhost:5000/app", "localhost:5000/app", ""},
+		{"localhost:5000/app:v1", "localhost:5000/app", "v1"},
+	}
+	for _, tt := range tests {
+		if name, tag := SplitImage(tt.image); name != tt.name || tag != tt.tag {
+			t.Errorf("SplitImage(%q) = %q, %q", tt.image, name, tag)
+		}
+	}
+}
+
+func TestConfigHashAndAnnotate(t *testing.T) {
+	v1 := render(t, "v1")
+	hash := ConfigHash(v1)
+	if len(hash) != 12 {
+		t.Fatalf("ConfigHash() = %q, want 12 hex digits", hash)
+	}
+
+	// Images and annotations are not configuration.
+	v2 := render(t, "v2")
+	Annotate(v2, map[string]string{"saas.vhvplatform.io/deployment-id": "7"})
+	if got := ConfigHash(v2); got != hash {
+		t.Errorf("ConfigHash() changed with the image tag: %s != %s", got, hash)
+	}
+	if got := find(v2, "deployment/api-gateway").metadata()["annotations"]; !reflect.DeepEqual(got, map[string]any{"saas.vhvplatform.io/deployment-id": "7"}) {
+		t.Errorf("annotations = %v", got)
+	}
+
+	cm := find(v2, "configmap/go-platform-config")
+	cm["data"].(map[string]any)["LOG_LEVEL"] = "warn"
+	if got := ConfigHash(v2); got == hash {
+		t.Error("ConfigHash() did not change with the ConfigMap data")
+	}
+}
+
+func render(t *testing.T, tag string) []Object {
+	t.Helper()
+	objs, err := Render(decode(t), Overlay{Namespace: "go-dev", ImageRegistry: "ghcr.io/acme", ImageTags: map[string]string{"": tag}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return objs
+}
+
+func TestDeployerApplyInPhases(t *testing.T) {
+	fake := &Fake{}
+	var out bytes.Buffer
+	d := &Deployer{Client: fake, Out: &out, Timeout: time.Minute}
+	ctx := context.Background()
+
+	objs := render(t, "v1")
+	d.Diff(ctx, objs)
+	if err := d.Apply(ctx, objs); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	want := []string{
+		"diff",
+		"apply namespace/go-dev",
+		"apply configmap/go-platform-config",
+		"apply deployment/auth-service",
+		"rollout-status deployment/auth-service",
+		"apply service/api-gateway",
+		"apply deployment/api-gateway",
+		"rollout-status deployment/api-gateway",
+	}
+	if got := fake.Log(); !reflect.DeepEqual(got, want) {
+		t.Errorf("operations:\n  got  %q\n  want %q", got, want)
+	}
+	if !strings.Contains(out.String(), "+ deployment/api-gateway\n") {
+		t.Errorf("diff should list new objects, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	d.Diff(ctx, objs)
+	if !strings.Contains(out.String(), "up to date") {
+		t.Errorf("unchanged objects should not be reported, got:\n%s", out.String())
+	}
+	out.Reset()
+	d.Diff(ctx, render(t, "v2"))
+	if got := out.String(); !strings.Contains(got, "~ deployment/api-gateway\n") || strings.Contains(got, "configmap") {
+		t.Errorf("diff should only list changed objects, got:\n%s", got)
+	}
+}
+
+func TestDeployerApplyStopsOnFailure(t *testing.T) {
+	fake := &Fake{Fail: func(op string, obj Object) error {
+		if op == "rollout-status" && obj.Name() == "auth-service" {
+			return errors.New("progress deadline exceeded")
+		}
+		return nil
+	}}
+	d := &Deployer{Client: fake, Out: &bytes.Buffer{}}
+
+	err := d.Apply(context.Background(), render(t, "v1"))
+	if err == nil || !strings.Contains(err.Error(), "rollout of deployment/auth-service") {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if _, ok := fake.Get("Deployment", "go-dev", "api-gateway"); ok {
+		t.Error("the gateway must not be applied after the services failed")
+	}
+}
+
+func TestDeployerRollback(t *testing.T) {
+	fake := &Fake{}
+	d := &Deployer{Client: fake, Out: &bytes.Buffer{}}
+	ctx := context.Background()
+
+	if err := d.Rollback(ctx, render(t, "v1")); err == nil {
+		t.Error("rollback without a previous revision should fail")
+	}
+	for _, tag := range []string{"v1", "v2"} {
+		if err := d.Apply(ctx, render(t, tag)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fake.ResetLog()
+	if err := d.Rollback(ctx, render(t, "v2")); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	want := []string{
+		"rollout-undo deployment/api-gateway",
+		"rollout-undo deployment/auth-service",
+		"rollout-status deployment/api-gateway",
+		"rollout-status deployment/auth-service",
+	}
+	if got := fake.Log(); !reflect.DeepEqual(got, want) {
+		t.Errorf("operations:\n  got  %q\n  want %q", got, want)
+	}
+	live, _ := fake.Get("Deployment", "go-dev", "api-gateway")
+	if got := Images([]Object{live})["api-gateway"]; got != "ghcr.io/acme/go-api-gateway:v1" {
+		t.Errorf("image after rollback = %s, want v1", got)
+	}
+}
+
+func TestKubectl(t *testing.T) {
+	var stdin []string
+	fake := &runner.Fake{Respond: func(cmd runner.Command) error {
+		if cmd.Stdin != nil {
+			var b bytes.Buffer
+			b.ReadFrom(cmd.Stdin)
+			stdin = append(stdin, b.String())
+		}
+		if cmd.Args[2] == "diff" {
+			cmd.Stdout.Write([]byte("-replicas: 3\n+replicas: 1\n"))
+			return exec.Command("sh", "-c", "exit 1").Run()
+		}
+		return nil
+	}}
+	k := &Kubectl{Runner: fake, Context: "kind-saas"}
+	ctx := context.Background()
+	objs := render(t, "v1")
+	gateway := find(objs, "deployment/api-gateway")
+
+	diff, err := k.Diff(ctx, objs)
+	if err != nil || diff != "-replicas: 3\n+replicas: 1\n" {
+		t.Errorf("Diff() = %q, %v; exit status 1 means differences", diff, err)
+	}
+	if err := k.Apply(ctx, gateway); err != nil {
+		t.Errorf("Apply() error = %v", err)
+	}
+	k.RolloutStatus(ctx, gateway, 90*time.Second)
+	k.RolloutUndo(ctx, gateway)
+
+	want := []string{
+		"kubectl --context kind-saas diff -f -",
+		"kubectl --context kind-saas apply -f -",
+		"kubectl --context kind-saas rollout status deployment/api-gateway -n go-dev --timeout 1m30s",
+		"kubectl --context kind-saas rollout undo deployment/api-gateway -n go-dev",
+	}
+	if got := fake.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands:\n  got  %q\n  want %q", got, want)
+	}
+	if len(stdin) != 2 || strings.Count(stdin[0], "kind: ") != 5 || !strings.Contains(stdin[1], "name: api-gateway") {
+		t.Errorf("manifests passed on stdin = %q", stdin)
+	}
+
+	fake.Respond = func(runner.Command) error { return exec.Command("sh", "-c", "exit 2").Run() }
+	if _, err := k.Diff(ctx, objs); err == nil {
+		t.Error("Diff() should fail when kubectl diff exits with status 2")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	manifest, err := Generate(Workload{
+		Name:       "auth-service",
+		Namespace:  "go-platform",
+		Component:  "service",
+		Image:      "<your-registry>/go-auth-service:latest",
+		Replicas:   2,
+		Ports:      []Port{{Name: "grpc", Port: 50051}, {Name: "http", Port: 8081}},
+		HTTPPort:   8081,
+		HealthPath: "/health",
+		ConfigMaps: []string{"go-platform-config"},
+		Secrets:    []string{"go-platform-secrets"},
+		Env:        map[string]string{"REDIS_PORT": "6379"},
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	objs, err := Decode(bytes.NewReader(manifest))
+	if err != nil {
+		t.Fatalf("generated manifests do not decode: %v\n%s", err, manifest)
+	}
+	want := []string{"configmap/auth-service-config", "deployment/auth-service", "service/auth-service"}
+	if got := refs(objs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("objects = %v, want %v", got, want)
+	}
+
+	if got := lookup(objs[0], "data", "REDIS_PORT"); got != "6379" {
+		t.Errorf("ConfigMap values must stay strings, got %#v", got)
+	}
+	container := lookup(objs[1], "spec", "template", "spec", "containers", "0").(map[string]any)
+	for _, probe := range []string{"livenessProbe", "readinessProbe"} {
+		get := container[probe].(map[string]any)["httpGet"].(map[string]any)
+		if get["path"] != "/health" || get["port"] != 8081 {
+			t.Errorf("%s = %v, want /health on 8081", probe, get)
+		}
+	}
+	var envFrom []string
+	for _, item := range container["envFrom"].([]any) {
+		for kind, ref := range item.(map[string]any) {
+			envFrom = append(envFrom, kind+":"+ref.(map[string]any)["name"].(string))
+		}
+	}
+	wantEnv := []string{"configMapRef:go-platform-config", "configMapRef:auth-service-config", "secretRef:go-platform-secrets"}
+	if !reflect.DeepEqual(envFrom, wantEnv) {
+		t.Errorf("envFrom = %v, want %v", envFrom, wantEnv)
+	}
+	if PhaseOf(objs[1]) != PhaseServices {
+		t.Errorf("generated Deployment phase = %v", PhaseOf(objs[1]))
+	}
+
+	if _, err := Generate(Workload{Name: "x"}); err == nil {
+		t.Error("Generate() without an HTTP port should fail")
+	}
+}
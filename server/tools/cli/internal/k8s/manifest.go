@@ -0,0 +1,297 @@
+// Package k8s renders the Kubernetes manifests under k8s/base with
+// per-environment overlays and applies them to a cluster.
+package k8s
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Object is one Kubernetes resource as decoded from YAML.
+type Object map[string]any
+
+// Kind returns the resource kind, e.g. Deployment.
+func (o Object) Kind() string {
+	kind, _ := o["kind"].(string)
+	return kind
+}
+
+// Name returns metadata.name.
+func (o Object) Name() string {
+	name, _ := o.metadata()["name"].(string)
+	return name
+}
+
+// Namespace returns metadata.namespace.
+func (o Object) Namespace() string {
+	ns, _ := o.metadata()["namespace"].(string)
+	return ns
+}
+
+// Ref returns the kubectl reference of the object, e.g.
+// deployment/api-gateway.
+func (o Object) Ref() string {
+	return strings.ToLower(o.Kind()) + "/" + o.Name()
+}
+
+// Labels returns metadata.labels, or nil when there are none.
+func (o Object) Labels() map[string]any {
+	labels, _ := o.metadata()["labels"].(map[string]any)
+	return labels
+}
+
+func (o Object) metadata() map[string]any {
+	meta, _ := o["metadata"].(map[string]any)
+	return meta
+}
+
+// Copy returns a deep copy of o.
+func (o Object) Copy() Object {
+	return copyValue(map[string]any(o)).(map[string]any)
+}
+
+func copyValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			out[k] = copyValue(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = copyValue(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Decode reads every YAML document from r. Empty documents, such as a file
+// holding only comments, are skipped.
+func Decode(r io.Reader) ([]Object, error) {
+	dec := yaml.NewDecoder(r)
+	var objs []Object
+	for {
+		var obj map[string]any
+		err := dec.Decode(&obj)
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(obj) == 0 {
+			continue
+		}
+		o := Object(obj)
+		if o.Kind() == "" || o.Name() == "" {
+			return nil, fmt.Errorf("document %d has no kind or metadata.name", len(objs)+1)
+		}
+		objs = append(objs, o)
+	}
+}
+
+// LoadDir reads the manifests in the *.yaml and *.yml files of dir, in
+// file name order.
+func LoadDir(dir string) ([]Object, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var objs []Object
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		docs, err := Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		objs = append(objs, docs...)
+	}
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("no manifests found in %s", dir)
+	}
+	return objs, nil
+}
+
+// Encode writes objs as a multi-document YAML stream.
+func Encode(objs []Object) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, obj := range objs {
+		if err := enc.Encode(map[string]any(obj)); err != nil {
+			return nil, fmt.Errorf("encode %s: %w", obj.Ref(), err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ConfigHash returns a short hash of the ConfigMaps and Secrets in objs,
+// ignoring their metadata, to tell whether two deployments ran with the
+// same configuration.
+func ConfigHash(objs []Object) string {
+	var config []Object
+	for _, obj := range objs {
+		if obj.Kind() != "ConfigMap" && obj.Kind() != "Secret" {
+			continue
+		}
+		c := Object{"kind": obj.Kind(), "name": obj.Name()}
+		for _, field := range []string{"data", "stringData", "binaryData"} {
+			if v, ok := obj[field]; ok {
+				c[field] = v
+			}
+		}
+		config = append(config, c)
+	}
+	sort.SliceStable(config, func(i, j int) bool {
+		return config[i].Kind()+"/"+config[i]["name"].(string) < config[j].Kind()+"/"+config[j]["name"].(string)
+	})
+
+	// Encode sorts map keys, so equal content hashes equally.
+	data, _ := Encode(config)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// Phase is a step of a deployment. Every object of a phase is applied, and
+// its workloads rolled out, before the next phase starts.
+type Phase int
+
+// Deployment phases, in the order they are applied.
+const (
+	PhaseNamespace Phase = iota
+	PhaseConfig
+	PhaseInfra
+	PhaseServices
+	PhaseGateway
+)
+
+var phaseNames = [...]string{"namespace", "configuration", "infrastructure", "services", "gateway"}
+
+func (p Phase) String() string {
+	return phaseNames[p]
+}
+
+// PhaseOf returns the phase obj is applied in. Infrastructure and gateway
+// objects are recognised by their component label.
+func PhaseOf(obj Object) Phase {
+	switch obj.Kind() {
+	case "Namespace":
+		return PhaseNamespace
+	case "ConfigMap", "Secret", "ServiceAccount", "Role", "RoleBinding", "ClusterRole", "ClusterRoleBinding", "PersistentVolumeClaim":
+		return PhaseConfig
+	case "Ingress":
+		return PhaseGateway
+	}
+	switch obj.Labels()["component"] {
+	case "infra":
+		return PhaseInfra
+	case "gateway":
+		return PhaseGateway
+	}
+	return PhaseServices
+}
+
+// phaseFunc returns the phase of each of objs. A Service is applied in the
+// phase of the workload with the same name, so it need not repeat the
+// component label.
+func phaseFunc(objs []Object) func(Object) Phase {
+	workloads := map[string]Phase{}
+	for _, obj := range objs {
+		if IsWorkload(obj) {
+			workloads[obj.Namespace()+"/"+obj.Name()] = PhaseOf(obj)
+		}
+	}
+	return func(obj Object) Phase {
+		if obj.Kind() == "Service" {
+			if p, ok := workloads[obj.Namespace()+"/"+obj.Name()]; ok {
+				return p
+			}
+		}
+		return PhaseOf(obj)
+	}
+}
+
+// kindOrder orders the kinds within a phase; unknown kinds go last.
+var kindOrder = map[string]int{
+	"Namespace":             0,
+	"ServiceAccount":        1,
+	"Secret":                2,
+	"ConfigMap":             3,
+	"PersistentVolumeClaim": 4,
+	"ClusterRole":           5,
+	"ClusterRoleBinding":    6,
+	"Role":                  7,
+	"RoleBinding":           8,
+	"Service":               9,
+	"StatefulSet":           10,
+	"Deployment":            11,
+	"DaemonSet":             12,
+	"Ingress":               13,
+}
+
+// Sort orders objs by phase and kind, keeping the file order otherwise.
+func Sort(objs []Object) {
+	rank := func(o Object) int {
+		if r, ok := kindOrder[o.Kind()]; ok {
+			return r
+		}
+		return len(kindOrder)
+	}
+	phase := phaseFunc(objs)
+	sort.SliceStable(objs, func(i, j int) bool {
+		pi, pj := phase(objs[i]), phase(objs[j])
+		if pi != pj {
+			return pi < pj
+		}
+		return rank(objs[i]) < rank(objs[j])
+	})
+}
+
+// Phases splits objs, sorted by Sort, into the runs applied together.
+func Phases(objs []Object) [][]Object {
+	phase := phaseFunc(objs)
+	var out [][]Object
+	for i, obj := range objs {
+		if i == 0 || phase(obj) != phase(objs[i-1]) {
+			out = append(out, nil)
+		}
+		out[len(out)-1] = append(out[len(out)-1], obj)
+	}
+	return out
+}
+
+// IsWorkload reports whether obj has a rollout to wait for.
+func IsWorkload(obj Object) bool {
+	switch obj.Kind() {
+	case "Deployment", "StatefulSet", "DaemonSet":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,354 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Overlay describes how an environment differs from the base manifests.
+type Overlay struct {
+	// Namespace replaces the namespace of every namespaced object and the
+	// name of the Namespace object.
+	Namespace string
+	// Labels are added to the metadata of every object.
+	Labels map[string]string
+	// ImageRegistry replaces the registry part of every container image,
+	// e.g. ghcr.io/vhvplatform.
+	ImageRegistry string
+	// ImageTags maps container names to image tags. The "" key applies to
+	// every container without its own entry.
+	ImageTags map[string]string
+	// Images maps container names to complete image references. They take
+	// precedence over ImageRegistry and ImageTags.
+	Images map[string]string
+	// Replicas maps workload names to their replica count.
+	Replicas map[string]int
+	// ConfigMap names the ConfigMap that Config is merged into.
+	ConfigMap string
+	// Config holds entries merged into the data of ConfigMap.
+	Config map[string]string
+	// Patches are applied last, in order.
+	Patches []Patch
+}
+
+// Patch sets a single value. With a Kind it sets the field at Path of the
+// matching object; without one it sets the ConfigMap entry named Path[0].
+type Patch struct {
+	Kind  string
+	Name  string
+	Path  []string
+	Value string
+}
+
+// ParsePatch parses a --set expression. Two forms are accepted:
+//
+//	KEY=value                                  entry of the overlay ConfigMap
+//	kind/name:path.to.field=value              field of any object
+//
+// List elements are addressed by index, e.g.
+// deployment/api-gateway:spec.template.spec.containers.0.imagePullPolicy.
+func ParsePatch(s string) (Patch, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return Patch{}, fmt.Errorf("invalid --set %q: expected key=value", s)
+	}
+
+	ref, path, ok := strings.Cut(key, ":")
+	if !ok {
+		return Patch{Path: []string{key}, Value: value}, nil
+	}
+	kind, name, ok := strings.Cut(ref, "/")
+	if !ok || kind == "" || name == "" || path == "" {
+		return Patch{}, fmt.Errorf("invalid --set %q: expected kind/name:path=value", s)
+	}
+	return Patch{Kind: kind, Name: name, Path: strings.Split(path, "."), Value: value}, nil
+}
+
+// Render returns copies of objs with the overlay applied.
+func Render(objs []Object, o Overlay) ([]Object, error) {
+	out := make([]Object, len(objs))
+	for i, obj := range objs {
+		obj = obj.Copy()
+		o.apply(obj)
+		out[i] = obj
+	}
+
+	if len(o.Config) > 0 && !hasConfigMap(out, o.ConfigMap) {
+		return nil, fmt.Errorf("ConfigMap %q not found for the overlay config", o.ConfigMap)
+	}
+	for _, p := range o.Patches {
+		if err := o.patch(out, p); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Merge returns base with the objects of extra added. An object of extra
+// replaces the base object of the same kind and name.
+func Merge(base, extra []Object) []Object {
+	out := append([]Object(nil), base...)
+	index := map[string]int{}
+	for i, obj := range out {
+		index[obj.Ref()] = i
+	}
+	for _, obj := range extra {
+		if i, ok := index[obj.Ref()]; ok {
+			out[i] = obj
+			continue
+		}
+		index[obj.Ref()] = len(out)
+		out = append(out, obj)
+	}
+	return out
+}
+
+// Annotate adds annotations to the metadata of every object. Pod templates
+// are left alone, so changing them restarts nothing.
+func Annotate(objs []Object, annotations map[string]string) {
+	for _, obj := range objs {
+		meta := obj.metadata()
+		if meta == nil {
+			meta = map[string]any{}
+			obj["metadata"] = meta
+		}
+		existing, _ := meta["annotations"].(map[string]any)
+		if existing == nil {
+			existing = map[string]any{}
+			meta["annotations"] = existing
+		}
+		for k, v := range annotations {
+			existing[k] = v
+		}
+	}
+}
+
+// CheckImages returns an error when an image still holds a placeholder
+// such as <your-registry>, which the base manifests use.
+func CheckImages(objs []Object) error {
+	for _, obj := range objs {
+		for _, c := range containers(obj) {
+			if image, _ := c["image"].(string); strings.Contains(image, "<") {
+				return fmt.Errorf("%s: image %s still contains a placeholder; set the image registry of the environment", obj.Ref(), image)
+			}
+		}
+	}
+	return nil
+}
+
+func hasConfigMap(objs []Object, name string) bool {
+	for _, obj := range objs {
+		if obj.Kind() == "ConfigMap" && obj.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (o Overlay) apply(obj Object) {
+	meta := obj.metadata()
+	if meta == nil {
+		meta = map[string]any{}
+		obj["metadata"] = meta
+	}
+
+	if o.Namespace != "" {
+		switch {
+		case obj.Kind() == "Namespace":
+			meta["name"] = o.Namespace
+			if labels := obj.Labels(); labels != nil && labels["name"] != nil {
+				labels["name"] = o.Namespace
+			}
+		case !clusterScoped[obj.Kind()]:
+			meta["namespace"] = o.Namespace
+		}
+	}
+
+	if len(o.Labels) > 0 {
+		labels := obj.Labels()
+		if labels == nil {
+			labels = map[string]any{}
+			meta["labels"] = labels
+		}
+		for k, v := range o.Labels {
+			labels[k] = v
+		}
+	}
+
+	if n, ok := o.Replicas[obj.Name()]; ok && IsWorkload(obj) && obj.Kind() != "DaemonSet" {
+		spec, _ := obj["spec"].(map[string]any)
+		if spec != nil {
+			spec["replicas"] = n
+		}
+	}
+
+	for _, c := range containers(obj) {
+		name, _ := c["name"].(string)
+		tag, ok := o.ImageTags[name]
+		if !ok {
+			tag = o.ImageTags[""]
+		}
+		if image, ok := o.Images[name]; ok {
+			c["image"] = image
+		} else if image, ok := c["image"].(string); ok {
+			c["image"] = rewriteImage(image, o.ImageRegistry, tag)
+		}
+	}
+
+	if obj.Kind() == "ConfigMap" && obj.Name() == o.ConfigMap && len(o.Config) > 0 {
+		setConfig(obj, o.Config)
+	}
+}
+
+func (o Overlay) patch(objs []Object, p Patch) error {
+	if p.Kind == "" {
+		for _, obj := range objs {
+			if obj.Kind() == "ConfigMap" && obj.Name() == o.ConfigMap {
+				setConfig(obj, map[string]string{p.Path[0]: p.Value})
+				return nil
+			}
+		}
+		return fmt.Errorf("--set %s: ConfigMap %q not found", p.Path[0], o.ConfigMap)
+	}
+
+	for _, obj := range objs {
+		if strings.EqualFold(obj.Kind(), p.Kind) && obj.Name() == p.Name {
+			var value any
+			if err := yaml.Unmarshal([]byte(p.Value), &value); err != nil {
+				value = p.Value
+			}
+			if err := setPath(obj, p.Path, value); err != nil {
+				return fmt.Errorf("--set %s/%s:%s: %w", p.Kind, p.Name, strings.Join(p.Path, "."), err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("--set %s/%s: object not found", p.Kind, p.Name)
+}
+
+// clusterScoped lists the kinds that have no namespace.
+var clusterScoped = map[string]bool{
+	"Namespace":                true,
+	"ClusterRole":              true,
+	"ClusterRoleBinding":       true,
+	"PersistentVolume":         true,
+	"StorageClass":             true,
+	"CustomResourceDefinition": true,
+}
+
+func setConfig(obj Object, entries map[string]string) {
+	data, _ := obj["data"].(map[string]any)
+	if data == nil {
+		data = map[string]any{}
+		obj["data"] = data
+	}
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		data[k] = entries[k]
+	}
+}
+
+// setPath sets the field at path, creating missing maps on the way.
+func setPath(obj Object, path []string, value any) error {
+	var cur any = map[string]any(obj)
+	for i, key := range path {
+		last := i == len(path)-1
+		switch node := cur.(type) {
+		case map[string]any:
+			if last {
+				node[key] = value
+				return nil
+			}
+			next, ok := node[key]
+			if !ok || next == nil {
+				next = map[string]any{}
+				node[key] = next
+			}
+			cur = next
+		case []any:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return fmt.Errorf("no list element %q", key)
+			}
+			if last {
+				node[idx] = value
+				return nil
+			}
+			cur = node[idx]
+		default:
+			return fmt.Errorf("%s is not an object or a list", strings.Join(path[:i], "."))
+		}
+	}
+	return nil
+}
+
+// containers returns the containers and init containers of a workload's
+// pod template.
+func containers(obj Object) []map[string]any {
+	if !IsWorkload(obj) && obj.Kind() != "Job" {
+		return nil
+	}
+	spec, _ := obj["spec"].(map[string]any)
+	template, _ := spec["template"].(map[string]any)
+	podSpec, _ := template["spec"].(map[string]any)
+
+	var out []map[string]any
+	for _, key := range []string{"initContainers", "containers"} {
+		list, _ := podSpec[key].([]any)
+		for _, item := range list {
+			if c, ok := item.(map[string]any); ok {
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// rewriteImage replaces the registry and tag of image. Empty values keep
+// the current ones.
+func rewriteImage(image, registry, tag string) string {
+	name, current := SplitImage(image)
+	if registry != "" {
+		name = strings.TrimSuffix(registry, "/") + "/" + name[strings.LastIndex(name, "/")+1:]
+	}
+	if tag != "" {
+		current = tag
+	}
+	if current == "" {
+		return name
+	}
+	return name + ":" + current
+}
+
+// SplitImage splits an image reference into its name and tag. A registry
+// port, as in localhost:5000/app, is not mistaken for a tag.
+func SplitImage(image string) (name, tag string) {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return image, ""
+	}
+	return image[:i], image[i+1:]
+}
+
+// Images returns the container images of objs by container name.
+func Images(objs []Object) map[string]string {
+	images := map[string]string{}
+	for _, obj := range objs {
+		for _, c := range containers(obj) {
+			name, _ := c["name"].(string)
+			image, _ := c["image"].(string)
+			if name != "" && image != "" {
+				images[name] = image
+			}
+		}
+	}
+	return images
+}
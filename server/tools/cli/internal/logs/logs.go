@@ -0,0 +1,182 @@
+// Package logs multiplexes container log streams, filters them by pattern
+// and level, and prints each line prefixed with its service name.
+package logs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Level is a log severity. Higher values are more severe.
+type Level int
+
+const (
+	// LevelUnknown is used for lines without a recognizable level.
+	LevelUnknown Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+var levelNames = map[string]Level{
+	"trace":    LevelDebug,
+	"debug":    LevelDebug,
+	"info":     LevelInfo,
+	"notice":   LevelInfo,
+	"warn":     LevelWarn,
+	"warning":  LevelWarn,
+	"error":    LevelError,
+	"err":      LevelError,
+	"fatal":    LevelFatal,
+	"panic":    LevelFatal,
+	"critical": LevelFatal,
+}
+
+// ParseLevel parses a level name such as "info" or "ERROR".
+func ParseLevel(s string) (Level, error) {
+	if l, ok := levelNames[strings.ToLower(s)]; ok {
+		return l, nil
+	}
+	return LevelUnknown, fmt.Errorf("unknown log level %q (use debug, info, warn, error or fatal)", s)
+}
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelFatal:
+		return "fatal"
+	default:
+		return ""
+	}
+}
+
+// levelKeys are the JSON fields a log level is read from, in order.
+var levelKeys = []string{"level", "lvl", "severity", "log.level"}
+
+// plainLevel finds a level in lines that aren't JSON, e.g. "level=error" or
+// "[ERROR]".
+var plainLevel = regexp.MustCompile(`(?i)\b(debug|info|warn|warning|error|fatal|panic)\b`)
+
+// Entry is one log line.
+type Entry struct {
+	// Service is the compose service that produced the line.
+	Service string
+	// Raw is the line as read from the container, without the newline.
+	Raw string
+	// Fields holds the decoded object for JSON lines and is nil otherwise.
+	Fields map[string]any
+	// Level is the severity read from the line, if any.
+	Level Level
+}
+
+// Parse decodes a log line. JSON objects are decoded into Fields; for other
+// lines the level is guessed from the first level-like word.
+func Parse(service, raw string) Entry {
+	e := Entry{Service: service, Raw: raw}
+
+	trimmed := strings.TrimSpace(raw)
+	if strings.HasPrefix(trimmed, "{") {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(trimmed), &fields); err == nil {
+			e.Fields = fields
+			for _, key := range levelKeys {
+				if s, ok := fields[key].(string); ok {
+					e.Level, _ = ParseLevel(s)
+					break
+				}
+			}
+			return e
+		}
+	}
+
+	if m := plainLevel.FindString(raw); m != "" {
+		e.Level, _ = ParseLevel(m)
+	}
+	return e
+}
+
+// Filter selects which entries are printed. The zero value matches
+// everything.
+type Filter struct {
+	// Pattern, when set, must match the raw line.
+	Pattern *regexp.Regexp
+	// MinLevel, when set, drops entries below it, including entries
+	// without a level.
+	MinLevel Level
+}
+
+// Match reports whether e passes the filter.
+func (f Filter) Match(e Entry) bool {
+	if f.Pattern != nil && !f.Pattern.MatchString(e.Raw) {
+		return false
+	}
+	if f.MinLevel != LevelUnknown && e.Level < f.MinLevel {
+		return false
+	}
+	return true
+}
+
+// OpenFunc opens the log stream of one service. The stream ends when the
+// service's logs are exhausted or ctx is cancelled; a non-nil error from
+// Read other than io.EOF is reported by Stream.
+type OpenFunc func(ctx context.Context, service string) (io.ReadCloser, error)
+
+// maxLineSize bounds a single log line.
+const maxLineSize = 1 << 20
+
+// Stream reads the logs of all services concurrently and prints the entries
+// that pass filter until every stream ends or ctx is cancelled. The
+// returned error joins the failures of individual streams; streams cut
+// short by cancellation are not failures.
+func Stream(ctx context.Context, services []string, open OpenFunc, filter Filter, p *Printer) error {
+	errs := make([]error, len(services))
+	var wg sync.WaitGroup
+	for i, service := range services {
+		wg.Add(1)
+		go func(i int, service string) {
+			defer wg.Done()
+			if err := stream(ctx, service, open, filter, p); err != nil && ctx.Err() == nil {
+				errs[i] = fmt.Errorf("%s: %w", service, err)
+			}
+		}(i, service)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func stream(ctx context.Context, service string, open OpenFunc, filter Filter, p *Printer) error {
+	r, err := open(ctx, service)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	for scanner.Scan() {
+		e := Parse(service, scanner.Text())
+		if !filter.Match(e) {
+			continue
+		}
+		if err := p.Print(e); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
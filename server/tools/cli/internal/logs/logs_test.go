@@ -0,0 +1,188 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw       string
+		wantLevel Level
+		wantJSON  bool
+	}{
+		{`{"level":"error","msg":"boom"}`, LevelError, true},
+		{`{"severity":"WARNING","message":"slow"}`, LevelWarn, true},
+		{`{"msg":"no level"}`, LevelUnknown, true},
+		{`2024/01/02 [INFO] listening on :8080`, LevelInfo, false},
+		{`level=debug msg="cache miss"`, LevelDebug, false},
+		{`{not json`, LevelUnknown, false},
+		{`plain line`, LevelUnknown, false},
+	}
+
+	for _, tt := range tests {
+		e := Parse("auth", tt.raw)
+		if e.Level != tt.wantLevel {
+			t.Errorf("Parse(%q).Level = %v, want %v", tt.raw, e.Level, tt.wantLevel)
+		}
+		if (e.Fields != nil) != tt.wantJSON {
+			t.Errorf("Parse(%q) JSON = %v, want %v", tt.raw, e.Fields != nil, tt.wantJSON)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := Filter{Pattern: regexp.MustCompile(`tenant=\d+`), MinLevel: LevelWarn}
+
+	tests := []struct {
+		raw  string
+		want bool
+	}{
+		{`{"level":"error","msg":"tenant=1 failed"}`, true},
+		{`{"level":"info","msg":"tenant=1 ok"}`, false},
+		{`{"level":"error","msg":"no tenant"}`, false},
+		{`tenant=7 without level`, false},
+	}
+	for _, tt := range tests {
+		if got := f.Match(Parse("svc", tt.raw)); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+
+	if !(Filter{}).Match(Parse("svc", "anything")) {
+		t.Error("zero Filter should match everything")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if l, err := ParseLevel("ERROR"); err != nil || l != LevelError {
+		t.Errorf("ParseLevel(ERROR) = %v, %v", l, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(loud) should fail")
+	}
+}
+
+func TestPrinterPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinter(&buf, []string{"api-gateway", "auth"}, false, false)
+
+	p.Print(Parse("auth", "hello"))
+	if got, want := buf.String(), "auth        | hello\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	p.Color = true
+	p.Print(Parse("auth", "hello"))
+	if !strings.HasPrefix(buf.String(), "\x1b[32m") {
+		t.Errorf("expected colored prefix, got %q", buf.String())
+	}
+}
+
+func TestPrinterJSON(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{`{"level":"info","msg":"hi"}`, `{"service":"auth","level":"info","msg":"hi"}`},
+		{`{"service":"custom","msg":"hi"}`, `{"service":"custom","msg":"hi"}`},
+		{`{}`, `{"service":"auth"}`},
+		{`plain "text"`, `{"message":"plain \"text\"","service":"auth"}`},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		p := NewPrinter(&buf, []string{"auth"}, false, true)
+		p.Print(Parse("auth", tt.raw))
+		if got := strings.TrimSpace(buf.String()); got != tt.want {
+			t.Errorf("JSON output for %q = %s, want %s", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	streams := map[string]string{
+		"auth": "{\"level\":\"info\",\"msg\":\"started\"}\n{\"level\":\"error\",\"msg\":\"db down\"}\n",
+		"user": "[ERROR] cannot connect\nready\n",
+	}
+	open := func(_ context.Context, service string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(streams[service])), nil
+	}
+
+	var buf bytes.Buffer
+	p := NewPrinter(&buf, []string{"auth", "user"}, false, false)
+	if err := Stream(context.Background(), []string{"auth", "user"}, open, Filter{MinLevel: LevelError}, p); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	sort.Strings(lines)
+	want := []string{
+		`auth | {"level":"error","msg":"db down"}`,
+		`user | [ERROR] cannot connect`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+}
+
+func TestStreamReportsFailures(t *testing.T) {
+	open := func(_ context.Context, service string) (io.ReadCloser, error) {
+		if service == "broken" {
+			return nil, errors.New("no such service")
+		}
+		return io.NopCloser(strings.NewReader("ok\n")), nil
+	}
+
+	var buf bytes.Buffer
+	p := NewPrinter(&buf, nil, false, false)
+	err := Stream(context.Background(), []string{"auth", "broken"}, open, Filter{}, p)
+	if err == nil || !strings.Contains(err.Error(), "broken: no such service") {
+		t.Errorf("Stream() error = %v, want failure for broken", err)
+	}
+	if !strings.Contains(buf.String(), "ok") {
+		t.Errorf("healthy stream should still be printed, got %q", buf.String())
+	}
+}
+
+func TestPrinterPretty(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPrinter(&buf, []string{"auth"}, false, false)
+	p.Pretty = true
+	p.TraceURL = "http://localhost:16686/trace/"
+
+	raw := `{"time":"2024-01-02T13:23:37.5Z","level":"error","msg":"login failed","trace_id":"abc123","user":"jane doe","error":"bad password","stack":"main.login\n\tauth.go:42"}`
+	p.Print(Parse("auth", raw))
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected entry plus three detail lines, got %q", lines)
+	}
+	wantTime := time.Date(2024, 1, 2, 13, 23, 37, 5e8, time.UTC).Local().Format("15:04:05.000")
+	for _, want := range []string{wantTime, "ERROR", "auth |", "login failed", "trace_id=abc123", `user="jane doe"`} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("entry %q missing %q", lines[0], want)
+		}
+	}
+	if strings.Contains(lines[0], "\x1b]8;;") {
+		t.Errorf("hyperlinks need color output, got %q", lines[0])
+	}
+	if lines[1] != "    error: bad password" || lines[2] != "    stack: main.login" || lines[3] != "    \tauth.go:42" {
+		t.Errorf("details = %q", lines[1:])
+	}
+
+	buf.Reset()
+	p.Color = true
+	p.Print(Parse("auth", `{"level":"info","msg":"hi","trace_id":"abc123"}`))
+	if !strings.Contains(buf.String(), "\x1b]8;;http://localhost:16686/trace/abc123\x1b\\abc123\x1b]8;;\x1b\\") {
+		t.Errorf("expected OSC 8 link to Jaeger, got %q", buf.String())
+	}
+}
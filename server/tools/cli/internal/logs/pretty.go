@@ -0,0 +1,165 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Well-known JSON log fields, in order of preference.
+var (
+	timeKeys    = []string{"time", "ts", "timestamp", "@timestamp"}
+	messageKeys = []string{"msg", "message"}
+	traceKeys   = []string{"trace_id", "traceId", "traceID", "trace.id"}
+	// detailKeys are printed on their own indented lines below the entry.
+	detailKeys = []string{"error", "err", "stack", "stacktrace", "error.stack"}
+)
+
+// messageWidth is the column the trace ID is aligned to. Longer messages
+// push it further right.
+const messageWidth = 48
+
+var levelColors = map[Level]string{
+	LevelDebug: "90",
+	LevelInfo:  "32",
+	LevelWarn:  "33",
+	LevelError: "31",
+	LevelFatal: "1;31",
+}
+
+// pretty renders an entry as aligned columns: time, level, service,
+// message and trace ID, followed by the remaining fields as key=value and
+// any error or stack fields expanded below.
+func (p *Printer) pretty(e Entry) string {
+	if e.Fields == nil {
+		return fmt.Sprintf("%-12s %-5s %s %s", "", p.colorLevel(e.Level), p.prefix(e.Service), e.Raw)
+	}
+
+	used := map[string]bool{}
+	take := func(keys []string) string {
+		for _, k := range keys {
+			if v, ok := e.Fields[k]; ok {
+				used[k] = true
+				return fieldString(v)
+			}
+		}
+		return ""
+	}
+	for _, k := range levelKeys {
+		if _, ok := e.Fields[k]; ok {
+			used[k] = true
+			break
+		}
+	}
+
+	ts := formatTime(take(timeKeys))
+	msg := take(messageKeys)
+	trace := take(traceKeys)
+	if _, ok := e.Fields["service"]; ok {
+		used["service"] = true
+	}
+
+	var details []string
+	for _, k := range detailKeys {
+		if v, ok := e.Fields[k]; ok {
+			used[k] = true
+			details = append(details, k+": "+fieldString(v))
+		}
+	}
+
+	var extra []string
+	for _, k := range sortedKeys(e.Fields) {
+		if !used[k] {
+			extra = append(extra, k+"="+quoteIfNeeded(fieldString(e.Fields[k])))
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s %s %s%-*s", ts, p.colorLevel(e.Level), p.prefix(e.Service), messageWidth, msg)
+	if trace != "" {
+		b.WriteString(" " + p.traceLink(trace))
+	}
+	if len(extra) > 0 {
+		b.WriteString(" " + p.dim(strings.Join(extra, " ")))
+	}
+	for _, d := range details {
+		for _, line := range strings.Split(strings.TrimRight(d, "\n"), "\n") {
+			b.WriteString("\n    " + p.dim(line))
+		}
+	}
+	return b.String()
+}
+
+// colorLevel returns the level padded to five characters.
+func (p *Printer) colorLevel(l Level) string {
+	name := fmt.Sprintf("%-5s", strings.ToUpper(l.String()))
+	if c, ok := levelColors[l]; ok && p.Color {
+		return "\x1b[" + c + "m" + name + reset
+	}
+	return name
+}
+
+// traceLink renders a trace ID as an OSC 8 terminal hyperlink to TraceURL.
+// Terminals without hyperlink support show the ID as plain text.
+func (p *Printer) traceLink(id string) string {
+	if !p.Color || p.TraceURL == "" {
+		return "trace_id=" + id
+	}
+	return "trace_id=\x1b]8;;" + p.TraceURL + id + "\x1b\\" + id + "\x1b]8;;\x1b\\"
+}
+
+func (p *Printer) dim(s string) string {
+	if !p.Color {
+		return s
+	}
+	return "\x1b[2m" + s + reset
+}
+
+// formatTime shortens RFC 3339 and Unix timestamps to the local time of
+// day. Anything else is returned unchanged.
+func formatTime(s string) string {
+	if s == "" {
+		return ""
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.Local().Format("15:04:05.000")
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec := int64(f)
+		return time.Unix(sec, int64((f-float64(sec))*1e9)).Local().Format("15:04:05.000")
+	}
+	return s
+}
+
+func fieldString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case map[string]any, []any:
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,96 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// palette holds the ANSI colors assigned to services in turn. Red is left
+// out so it stays reserved for errors.
+var palette = []string{"36", "32", "33", "34", "35", "96", "92", "93", "94", "95"}
+
+const reset = "\x1b[0m"
+
+// Printer writes entries to Out. It is safe for concurrent use, so the
+// streams of several services can share one Printer without interleaving
+// partial lines.
+type Printer struct {
+	// Out receives the formatted lines.
+	Out io.Writer
+	// Color enables ANSI colors for service prefixes.
+	Color bool
+	// JSON writes one JSON object per line instead of prefixed text: JSON
+	// lines are passed through with a "service" field added when missing,
+	// other lines are wrapped as {"service": ..., "message": ...}.
+	JSON bool
+	// Pretty renders JSON lines as aligned columns; see pretty.go.
+	Pretty bool
+	// TraceURL is the prefix trace IDs are linked to in pretty mode, e.g.
+	// http://localhost:16686/trace/ for Jaeger.
+	TraceURL string
+
+	mu     sync.Mutex
+	width  int
+	colors map[string]string
+}
+
+// NewPrinter returns a Printer whose prefixes are aligned and colored for
+// the given services.
+func NewPrinter(out io.Writer, services []string, color, jsonOut bool) *Printer {
+	p := &Printer{Out: out, Color: color, JSON: jsonOut, colors: map[string]string{}}
+	for i, s := range services {
+		p.width = max(p.width, len(s))
+		p.colors[s] = palette[i%len(palette)]
+	}
+	return p
+}
+
+// Print writes one entry.
+func (p *Printer) Print(e Entry) error {
+	var line string
+	switch {
+	case p.JSON:
+		line = p.jsonLine(e)
+	case p.Pretty:
+		line = p.pretty(e)
+	default:
+		line = p.prefix(e.Service) + e.Raw
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err := io.WriteString(p.Out, line+"\n")
+	return err
+}
+
+func (p *Printer) prefix(service string) string {
+	name := fmt.Sprintf("%-*s |", p.width, service)
+	if p.Color {
+		if c, ok := p.colors[service]; ok {
+			return "\x1b[" + c + "m" + name + reset + " "
+		}
+	}
+	return name + " "
+}
+
+func (p *Printer) jsonLine(e Entry) string {
+	if e.Fields == nil {
+		b, _ := json.Marshal(map[string]string{"service": e.Service, "message": e.Raw})
+		return string(b)
+	}
+
+	raw := strings.TrimSpace(e.Raw)
+	if _, ok := e.Fields["service"]; ok {
+		return raw
+	}
+	svc, _ := json.Marshal(e.Service)
+	if len(e.Fields) == 0 {
+		return `{"service":` + string(svc) + `}`
+	}
+	// Splice the field in rather than re-encoding, so the original line
+	// keeps its key order and number formatting.
+	return `{"service":` + string(svc) + "," + strings.TrimPrefix(raw, "{")
+}
@@ -0,0 +1,364 @@
+// Package orchestrator starts and stops docker-compose services in
+// dependency order, reporting progress for every service it touches.
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/compose"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/runner"
+)
+
+// Orchestrator drives `docker compose` for the services of a project.
+type Orchestrator struct {
+	// Project is the parsed compose project.
+	Project *compose.Project
+	// Runner runs the docker commands.
+	Runner runner.Runner
+	// Out receives one status line per service.
+	Out io.Writer
+
+	mu sync.Mutex
+}
+
+// New creates an Orchestrator for the given project.
+func New(project *compose.Project, r runner.Runner, out io.Writer) *Orchestrator {
+	if out == nil {
+		out = io.Discard
+	}
+	return &Orchestrator{Project: project, Runner: r, Out: out}
+}
+
+// ServiceError is returned when a docker operation fails for one service.
+type ServiceError struct {
+	// Service is the compose service name.
+	Service string
+	// Op is the operation that failed, e.g. "start" or "stop".
+	Op string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ServiceError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Service, e.Err)
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// Status is the outcome of an operation on one service.
+type Status string
+
+const (
+	// StatusOK means the operation succeeded.
+	StatusOK Status = "ok"
+	// StatusFailed means the docker command failed.
+	StatusFailed Status = "failed"
+	// StatusSkipped means the service was not touched because one of its
+	// dependencies failed.
+	StatusSkipped Status = "skipped"
+)
+
+// Result reports what happened to one service.
+type Result struct {
+	Service  string
+	Op       string
+	Status   Status
+	Duration time.Duration
+	Err      error
+}
+
+// Start starts the named services and their dependencies. Dependency levels
+// run in order and the services within a level run concurrently. A service
+// that another one depends on with condition service_healthy is started
+// with --wait, so the next level only starts once it is healthy. When a
+// service fails, or never becomes healthy, the services depending on it are
+// skipped while unrelated services still start. With no names, every
+// service without a compose profile is started.
+//
+// The returned error joins a *ServiceError for every failed service.
+func (o *Orchestrator) Start(ctx context.Context, services ...string) ([]Result, error) {
+	if len(services) == 0 {
+		services = o.defaultServices()
+	}
+
+	levels, err := o.Project.Levels(services...)
+	if err != nil {
+		return nil, err
+	}
+
+	awaited := o.awaitedServices(levels)
+	failed := map[string]bool{}
+	return o.runLevels(ctx, levels, "start", func(name string) (bool, [][]string) {
+		for _, dep := range o.Project.Service(name).Dependencies() {
+			if failed[dep] {
+				failed[name] = true
+				return false, nil
+			}
+		}
+		args := []string{"up", "-d", "--no-deps"}
+		if awaited[name] {
+			args = append(args, "--wait")
+		}
+		return true, [][]string{append(args, name)}
+	}, failed)
+}
+
+// awaitedServices returns the services of levels that another service in
+// levels depends on with condition service_healthy.
+func (o *Orchestrator) awaitedServices(levels [][]string) map[string]bool {
+	awaited := map[string]bool{}
+	for _, level := range levels {
+		for _, name := range level {
+			for dep, d := range o.Project.Service(name).DependsOn {
+				if d.Condition == "service_healthy" {
+					awaited[dep] = true
+				}
+			}
+		}
+	}
+	return awaited
+}
+
+// Stop stops the named services in reverse dependency order, so that a
+// service is always stopped before the services it depends on. Services
+// within a level are stopped concurrently. With no names, every service is
+// stopped.
+func (o *Orchestrator) Stop(ctx context.Context, services ...string) ([]Result, error) {
+	levels, err := o.selectedLevels(services)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(levels)
+
+	return o.runLevels(ctx, levels, "stop", func(name string) (bool, [][]string) {
+		return true, [][]string{{"stop", name}}
+	}, map[string]bool{})
+}
+
+// Restart restarts the named services in dependency order without
+// recreating their containers. With no names, every service is restarted.
+func (o *Orchestrator) Restart(ctx context.Context, services ...string) ([]Result, error) {
+	levels, err := o.selectedLevels(services)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.runLevels(ctx, levels, "restart", func(name string) (bool, [][]string) {
+		return true, [][]string{{"restart", name}}
+	}, map[string]bool{})
+}
+
+// BuildOptions controls how Rebuild builds images.
+type BuildOptions struct {
+	// NoCache builds without the Docker layer cache.
+	NoCache bool
+	// Pull always pulls newer base images.
+	Pull bool
+	// Sources maps service names to a local source checkout to build from
+	// instead of the build context in the compose file.
+	Sources map[string]string
+}
+
+// Rebuild builds fresh images for the named services and recreates their
+// containers, in dependency order. Services without a build section (or
+// source checkout) are only recreated, pulling their image when opts.Pull
+// is set. Dependencies are left untouched.
+func (o *Orchestrator) Rebuild(ctx context.Context, opts BuildOptions, services ...string) ([]Result, error) {
+	levels, err := o.selectedLevels(services)
+	if err != nil {
+		return nil, err
+	}
+
+	var override []string
+	if len(opts.Sources) > 0 {
+		path, err := o.writeBuildOverride(opts.Sources)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(path)
+		override = []string{"-f", path}
+	}
+
+	return o.runLevels(ctx, levels, "rebuild", func(name string) (bool, [][]string) {
+		var steps [][]string
+		_, fromSource := opts.Sources[name]
+		if o.Project.Service(name).Build != nil || fromSource {
+			build := append(slices.Clone(override), "build")
+			if opts.NoCache {
+				build = append(build, "--no-cache")
+			}
+			if opts.Pull {
+				build = append(build, "--pull")
+			}
+			steps = append(steps, append(build, name))
+		} else if opts.Pull {
+			steps = append(steps, []string{"pull", name})
+		}
+		steps = append(steps, []string{"up", "-d", "--no-deps", "--force-recreate", name})
+		return true, steps
+	}, map[string]bool{})
+}
+
+// writeBuildOverride writes a temporary compose file pointing the build
+// context of each service at its source checkout. The dockerfile is reset
+// too, as the mocks are built from a Dockerfile below a shared context.
+func (o *Orchestrator) writeBuildOverride(sources map[string]string) (string, error) {
+	var b strings.Builder
+	b.WriteString("services:\n")
+	for _, name := range slices.Sorted(maps.Keys(sources)) {
+		fmt.Fprintf(&b, "  %s:\n    build:\n      context: %q\n      dockerfile: Dockerfile\n", name, sources[name])
+	}
+
+	f, err := os.CreateTemp("", "saas-build-*.yml")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(b.String()); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// selectedLevels returns the dependency levels of the project restricted to
+// the named services, without pulling in their dependencies. With no names,
+// every service is included.
+func (o *Orchestrator) selectedLevels(services []string) ([][]string, error) {
+	all, err := o.Project.Levels()
+	if err != nil {
+		return nil, err
+	}
+
+	selected := map[string]bool{}
+	for _, name := range services {
+		if o.Project.Service(name) == nil {
+			return nil, &compose.UnknownServiceError{Name: name}
+		}
+		selected[name] = true
+	}
+
+	var levels [][]string
+	for _, level := range all {
+		var keep []string
+		for _, name := range level {
+			if len(selected) == 0 || selected[name] {
+				keep = append(keep, name)
+			}
+		}
+		if len(keep) > 0 {
+			levels = append(levels, keep)
+		}
+	}
+	return levels, nil
+}
+
+// Down removes the containers and networks of the project, keeping volumes.
+func (o *Orchestrator) Down(ctx context.Context) error {
+	if err := o.docker(ctx, "down"); err != nil {
+		return fmt.Errorf("docker compose down: %w", err)
+	}
+	return nil
+}
+
+// ComposeArgs prefixes args with `compose -f <file>...` for this project.
+func (o *Orchestrator) ComposeArgs(args ...string) []string {
+	out := []string{"compose"}
+	for _, file := range o.Project.Files {
+		out = append(out, "-f", file)
+	}
+	return append(out, args...)
+}
+
+// docker runs `docker compose` with args, capturing its output.
+func (o *Orchestrator) docker(ctx context.Context, args ...string) error {
+	return runner.Quiet(ctx, o.Runner, runner.Command{Name: "docker", Args: o.ComposeArgs(args...)})
+}
+
+// runLevels runs op for every service level by level. plan decides, for
+// each service, whether to run it and which compose commands make up the
+// operation; failed collects the services whose operation failed or was
+// skipped.
+func (o *Orchestrator) runLevels(ctx context.Context, levels [][]string, op string, plan func(string) (bool, [][]string), failed map[string]bool) ([]Result, error) {
+	var results []Result
+	var errs []error
+
+	for _, level := range levels {
+		levelResults := make([]Result, len(level))
+		var wg sync.WaitGroup
+		for i, name := range level {
+			run, steps := plan(name)
+			if !run {
+				levelResults[i] = Result{Service: name, Op: op, Status: StatusSkipped}
+				o.printf("   %-24s %s... ⏭️  skipped (dependency failed)\n", name, op)
+				continue
+			}
+
+			wg.Add(1)
+			go func(i int, name string, steps [][]string) {
+				defer wg.Done()
+				levelResults[i] = o.run(ctx, op, name, steps)
+			}(i, name, steps)
+		}
+		wg.Wait()
+
+		for _, r := range levelResults {
+			if r.Status != StatusOK {
+				failed[r.Service] = true
+			}
+			if r.Err != nil {
+				errs = append(errs, &ServiceError{Service: r.Service, Op: r.Op, Err: r.Err})
+			}
+		}
+		results = append(results, levelResults...)
+	}
+	return results, errors.Join(errs...)
+}
+
+func (o *Orchestrator) run(ctx context.Context, op, service string, steps [][]string) Result {
+	started := time.Now()
+	var err error
+	for _, args := range steps {
+		if err = o.docker(ctx, args...); err != nil {
+			break
+		}
+	}
+	result := Result{Service: service, Op: op, Status: StatusOK, Duration: time.Since(started), Err: err}
+
+	if err != nil {
+		result.Status = StatusFailed
+		o.printf("   %-24s %s... ❌ (%s)\n", service, op, result.Duration.Round(100*time.Millisecond))
+	} else {
+		o.printf("   %-24s %s... ✅ (%s)\n", service, op, result.Duration.Round(100*time.Millisecond))
+	}
+	return result
+}
+
+func (o *Orchestrator) printf(format string, args ...any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Fprintf(o.Out, format, args...)
+}
+
+// defaultServices returns the services started when none are named: all
+// services without a compose profile.
+func (o *Orchestrator) defaultServices() []string {
+	var names []string
+	for _, name := range o.Project.Names() {
+		if len(o.Project.Service(name).Profiles) == 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,292 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/compose"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/runner"
+)
+
+const testCompose = `
+services:
+  db: {}
+  cache: {}
+  api:
+    build: ./api
+    depends_on: [db, cache]
+  worker:
+    depends_on: [cache]
+  gateway:
+    depends_on: [api]
+`
+
+func loadProject(t *testing.T) *compose.Project {
+	t.Helper()
+	return loadCompose(t, testCompose)
+}
+
+func loadCompose(t *testing.T, content string) *compose.Project {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "docker-compose.yml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write compose file: %v", err)
+	}
+	project, err := compose.Load(path)
+	if err != nil {
+		t.Fatalf("load compose file: %v", err)
+	}
+	return project
+}
+
+type recorder struct {
+	runner.Fake
+	failOn string
+}
+
+func newRecorder(failOn string) *recorder {
+	r := &recorder{failOn: failOn}
+	r.Respond = func(cmd runner.Command) error {
+		if r.failOn != "" && cmd.Args[len(cmd.Args)-1] == r.failOn {
+			return errors.New("boom")
+		}
+		return nil
+	}
+	return r
+}
+
+// calls returns the recorded commands without "compose -f <file>", so
+// assertions don't depend on the temp path.
+func (r *recorder) calls() []string {
+	var out []string
+	for _, cmd := range r.Calls() {
+		out = append(out, strings.Join(append([]string{cmd.Name}, cmd.Args[3:]...), " "))
+	}
+	return out
+}
+
+// position returns the index of the call ending in service.
+func (r *recorder) position(t *testing.T, service string) int {
+	t.Helper()
+	for i, call := range r.calls() {
+		if strings.HasSuffix(call, " "+service) {
+			return i
+		}
+	}
+	t.Fatalf("no call for %s in %v", service, r.calls())
+	return -1
+}
+
+func TestStartInDependencyOrder(t *testing.T) {
+	rec := newRecorder("")
+	orch := New(loadProject(t), rec, nil)
+
+	results, err := orch.Start(context.Background(), "gateway")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %v", results)
+	}
+
+	got := rec.calls()
+	sort.Strings(got[:2])
+	want := []string{
+		"docker up -d --no-deps cache",
+		"docker up -d --no-deps db",
+		"docker up -d --no-deps api",
+		"docker up -d --no-deps gateway",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
+
+func TestStopInReverseOrder(t *testing.T) {
+	rec := newRecorder("")
+	orch := New(loadProject(t), rec, nil)
+
+	if _, err := orch.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	if rec.position(t, "gateway") > rec.position(t, "api") {
+		t.Errorf("gateway must stop before api: %v", rec.calls())
+	}
+	for _, dep := range []string{"db", "cache"} {
+		if rec.position(t, "api") > rec.position(t, dep) {
+			t.Errorf("api must stop before %s: %v", dep, rec.calls())
+		}
+	}
+}
+
+func TestStopOnlySelected(t *testing.T) {
+	rec := newRecorder("")
+	orch := New(loadProject(t), rec, nil)
+
+	if _, err := orch.Stop(context.Background(), "db", "gateway"); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	want := []string{"docker stop gateway", "docker stop db"}
+	if !reflect.DeepEqual(rec.calls(), want) {
+		t.Errorf("calls = %v, want %v", rec.calls(), want)
+	}
+}
+
+func TestStartSkipsDependentsOfFailedService(t *testing.T) {
+	rec := newRecorder("db")
+	orch := New(loadProject(t), rec, nil)
+
+	results, err := orch.Start(context.Background())
+	var svcErr *ServiceError
+	if !errors.As(err, &svcErr) {
+		t.Fatalf("expected ServiceError, got %v", err)
+	}
+	if svcErr.Service != "db" || svcErr.Op != "start" {
+		t.Errorf("ServiceError = %+v, want db/start", svcErr)
+	}
+
+	status := map[string]Status{}
+	for _, r := range results {
+		status[r.Service] = r.Status
+	}
+	want := map[string]Status{
+		"cache":   StatusOK,
+		"db":      StatusFailed,
+		"worker":  StatusOK,
+		"api":     StatusSkipped,
+		"gateway": StatusSkipped,
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("statuses = %v, want %v", status, want)
+	}
+}
+
+func TestStartWaitsForHealthyDependencies(t *testing.T) {
+	project := loadCompose(t, `
+services:
+  mongodb: {}
+  redis: {}
+  rabbitmq: {}
+  auth:
+    depends_on:
+      mongodb: {condition: service_healthy}
+      redis: {condition: service_started}
+  notification:
+    depends_on:
+      rabbitmq: {condition: service_healthy}
+  gateway:
+    depends_on:
+      auth: {condition: service_started}
+      notification: {condition: service_started}
+`)
+
+	rec := newRecorder("")
+	if _, err := New(project, rec, nil).Start(context.Background(), "gateway"); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	got := rec.calls()
+	sort.Strings(got[:3])
+	sort.Strings(got[3:5])
+	want := []string{
+		"docker up -d --no-deps --wait mongodb",
+		"docker up -d --no-deps --wait rabbitmq",
+		"docker up -d --no-deps redis",
+		"docker up -d --no-deps auth",
+		"docker up -d --no-deps notification",
+		"docker up -d --no-deps gateway",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+
+	// A dependency that never becomes healthy fails its --wait, and the
+	// services waiting for it are skipped.
+	rec = newRecorder("mongodb")
+	results, err := New(project, rec, nil).Start(context.Background(), "gateway")
+	if err == nil {
+		t.Fatal("Start() succeeded with an unhealthy mongodb")
+	}
+	status := map[string]Status{}
+	for _, r := range results {
+		status[r.Service] = r.Status
+	}
+	if status["auth"] != StatusSkipped || status["gateway"] != StatusSkipped || status["notification"] != StatusOK {
+		t.Errorf("statuses = %v", status)
+	}
+	if slices.ContainsFunc(rec.calls(), func(c string) bool { return strings.HasSuffix(c, " auth") }) {
+		t.Errorf("auth was started before mongodb was healthy: %v", rec.calls())
+	}
+}
+
+func TestRestartOnlySelectedInOrder(t *testing.T) {
+	rec := newRecorder("")
+	orch := New(loadProject(t), rec, nil)
+
+	if _, err := orch.Restart(context.Background(), "gateway", "db"); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	want := []string{"docker restart db", "docker restart gateway"}
+	if !reflect.DeepEqual(rec.calls(), want) {
+		t.Errorf("calls = %v, want %v", rec.calls(), want)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	rec := newRecorder("")
+	orch := New(loadProject(t), rec, nil)
+
+	opts := BuildOptions{NoCache: true, Pull: true}
+	if _, err := orch.Rebuild(context.Background(), opts, "api", "db"); err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	want := []string{
+		"docker pull db",
+		"docker up -d --no-deps --force-recreate db",
+		"docker build --no-cache --pull api",
+		"docker up -d --no-deps --force-recreate api",
+	}
+	if !reflect.DeepEqual(rec.calls(), want) {
+		t.Errorf("calls = %v, want %v", rec.calls(), want)
+	}
+}
+
+func TestRebuildFromSource(t *testing.T) {
+	var builds [][]string
+	var override string
+	fake := &runner.Fake{Respond: func(cmd runner.Command) error {
+		if i := slices.Index(cmd.Args, "build"); i >= 0 {
+			builds = append(builds, cmd.Args)
+			data, err := os.ReadFile(cmd.Args[i-1])
+			if err != nil {
+				return err
+			}
+			override = string(data)
+		}
+		return nil
+	}}
+	orch := New(loadProject(t), fake, nil)
+
+	opts := BuildOptions{Sources: map[string]string{"worker": "/src/go-worker"}}
+	results, err := orch.Rebuild(context.Background(), opts, "worker")
+	if err != nil {
+		t.Fatalf("Rebuild() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Status != StatusOK {
+		t.Fatalf("results = %+v", results)
+	}
+	if len(builds) != 1 {
+		t.Fatalf("expected one build, got %v", builds)
+	}
+	if !strings.Contains(override, "worker:") || !strings.Contains(override, `context: "/src/go-worker"`) ||
+		!strings.Contains(override, "dockerfile: Dockerfile") {
+		t.Errorf("override file = %q", override)
+	}
+}
@@ -0,0 +1,227 @@
+// Package runner abstracts running external commands so the CLI can run
+// them for real, print them without running them, or record them in tests.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command is an external command to run.
+type Command struct {
+	// Name is the program, e.g. "docker" or "make".
+	Name string
+	// Args are the arguments, not including Name.
+	Args []string
+	// Dir is the working directory; empty means the current directory.
+	Dir string
+	// Env holds extra KEY=value variables added to the environment.
+	Env []string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// String returns the command line, quoting arguments where the shell would
+// need it.
+func (c Command) String() string {
+	parts := make([]string, 0, len(c.Env)+len(c.Args)+1)
+	for _, kv := range c.Env {
+		parts = append(parts, quote(kv))
+	}
+	parts = append(parts, quote(c.Name))
+	for _, arg := range c.Args {
+		parts = append(parts, quote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+func quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if strings.ContainsAny(s, " \t\n\"'`$\\|&;<>(){}*?[]#~") {
+		return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	}
+	return s
+}
+
+// Runner runs commands.
+type Runner interface {
+	// Run runs cmd to completion and returns an error if it could not be
+	// started or exited unsuccessfully.
+	Run(ctx context.Context, cmd Command) error
+}
+
+// Output runs cmd with r and returns its standard output.
+func Output(ctx context.Context, r Runner, cmd Command) ([]byte, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := r.Run(ctx, cmd)
+	return out.Bytes(), err
+}
+
+// Quiet runs cmd with r and captures its output. The output is only
+// surfaced, as part of the returned error, when the command fails.
+func Quiet(ctx context.Context, r Runner, cmd Command) error {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := r.Run(ctx, cmd); err != nil {
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// Exec runs commands as child processes.
+type Exec struct{}
+
+// Run implements Runner.
+func (Exec) Run(ctx context.Context, cmd Command) error {
+	c := exec.CommandContext(ctx, cmd.Name, cmd.Args...)
+	c.Dir = cmd.Dir
+	if len(cmd.Env) > 0 {
+		c.Env = append(c.Environ(), cmd.Env...)
+	}
+	c.Stdin = cmd.Stdin
+	c.Stdout = cmd.Stdout
+	c.Stderr = cmd.Stderr
+	return c.Run()
+}
+
+// DryRun prints commands instead of running them.
+type DryRun struct {
+	// Out receives one line per command, followed by its input as a here
+	// document when the input is held in memory, such as a rendered manifest.
+	Out io.Writer
+
+	mu sync.Mutex
+}
+
+// Run implements Runner. It always succeeds.
+func (d *DryRun) Run(_ context.Context, cmd Command) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	line := "+ " + cmd.String()
+	input, ok := bufferedInput(cmd.Stdin)
+	if ok {
+		line += " <<'EOF'"
+	}
+	if cmd.Dir != "" {
+		line += "  (in " + cmd.Dir + ")"
+	}
+	if ok {
+		if !strings.HasSuffix(input, "\n") {
+			input += "\n"
+		}
+		line += "\n" + input + "EOF"
+	}
+	_, err := fmt.Fprintln(d.Out, line)
+	return err
+}
+
+// bufferedInput returns the contents of stdin when it is an in-memory
+// reader. Other readers, such as a terminal, are left alone since reading
+// them would block.
+func bufferedInput(stdin io.Reader) (string, bool) {
+	switch r := stdin.(type) {
+	case *bytes.Reader:
+		data, _ := io.ReadAll(r)
+		return string(data), true
+	case *strings.Reader:
+		data, _ := io.ReadAll(r)
+		return string(data), true
+	case *bytes.Buffer:
+		return r.String(), true
+	}
+	return "", false
+}
+
+// Timed wraps a Runner and reports every command as it starts and when it
+// finishes, with its duration.
+type Timed struct {
+	// Runner runs the commands.
+	Runner Runner
+	// Out receives the report lines.
+	Out io.Writer
+
+	mu sync.Mutex
+}
+
+// Run implements Runner.
+func (t *Timed) Run(ctx context.Context, cmd Command) error {
+	line := cmd.String()
+	t.printf("▶ %s\n", line)
+
+	started := time.Now()
+	err := t.Runner.Run(ctx, cmd)
+	elapsed := time.Since(started).Round(time.Millisecond)
+	if err != nil {
+		t.printf("✘ %s (%s): %v\n", line, elapsed, err)
+	} else {
+		t.printf("✔ %s (%s)\n", line, elapsed)
+	}
+	return err
+}
+
+func (t *Timed) printf(format string, args ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.Out, format, args...)
+}
+
+// Fake records commands instead of running them. It is meant for tests.
+type Fake struct {
+	// Respond, when set, is called for every command. It can write output
+	// to cmd.Stdout and return the error the command should fail with.
+	Respond func(cmd Command) error
+
+	mu    sync.Mutex
+	calls []Command
+}
+
+// Run implements Runner.
+func (f *Fake) Run(_ context.Context, cmd Command) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, cmd)
+	f.mu.Unlock()
+
+	if f.Respond != nil {
+		return f.Respond(cmd)
+	}
+	return nil
+}
+
+// Calls returns the recorded commands in the order they were run.
+func (f *Fake) Calls() []Command {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Command(nil), f.calls...)
+}
+
+// Commands returns the recorded command lines.
+func (f *Fake) Commands() []string {
+	calls := f.Calls()
+	out := make([]string, len(calls))
+	for i, c := range calls {
+		out[i] = c.String()
+	}
+	return out
+}
+
+// Reset forgets the recorded commands.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
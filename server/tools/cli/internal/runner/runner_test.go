@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommandString(t *testing.T) {
+	cmd := Command{
+		Name: "kubectl",
+		Args: []string{"apply", "-f", "my file.yaml", "--selector", "app=api", "it's"},
+		Env:  []string{"KUBECONFIG=/tmp/kube"},
+	}
+	want := `KUBECONFIG=/tmp/kube kubectl apply -f 'my file.yaml' --selector app=api 'it'\''s'`
+	if got := cmd.String(); got != want {
+		t.Errorf("String() = %s, want %s", got, want)
+	}
+}
+
+func TestExec(t *testing.T) {
+	var out bytes.Buffer
+	err := Exec{}.Run(context.Background(), Command{
+		Name:   "sh",
+		Args:   []string{"-c", "echo $GREETING; pwd"},
+		Dir:    "/",
+		Env:    []string{"GREETING=hello"},
+		Stdout: &out,
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := out.String(); got != "hello\n/\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestQuietIncludesOutputInError(t *testing.T) {
+	err := Quiet(context.Background(), Exec{}, Command{Name: "sh", Args: []string{"-c", "echo broken >&2; exit 3"}})
+	if err == nil || !strings.Contains(err.Error(), "exit status 3: broken") {
+		t.Errorf("Quiet() error = %v", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var out bytes.Buffer
+	d := &DryRun{Out: &out}
+	if err := d.Run(context.Background(), Command{Name: "make", Args: []string{"deploy-dev"}, Dir: "/srv"}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got, want := out.String(), "+ make deploy-dev  (in /srv)\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	// Input held in memory is shown; a terminal is never read.
+	out.Reset()
+	cmd := Command{Name: "kubectl", Args: []string{"apply", "-f", "-"}, Stdin: strings.NewReader("kind: Service\nmetadata:\n  name: api\n")}
+	if err := d.Run(context.Background(), cmd); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got, want := out.String(), "+ kubectl apply -f - <<'EOF'\nkind: Service\nmetadata:\n  name: api\nEOF\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	out.Reset()
+	if err := d.Run(context.Background(), Command{Name: "cat", Stdin: os.Stdin}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got, want := out.String(), "+ cat\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestFake(t *testing.T) {
+	f := &Fake{Respond: func(cmd Command) error {
+		if cmd.Args[0] == "fail" {
+			return errors.New("boom")
+		}
+		if cmd.Stdout != nil {
+			io.WriteString(cmd.Stdout, "out")
+		}
+		return nil
+	}}
+
+	got, err := Output(context.Background(), f, Command{Name: "docker", Args: []string{"ps"}})
+	if err != nil || string(got) != "out" {
+		t.Errorf("Output() = %q, %v", got, err)
+	}
+	if err := f.Run(context.Background(), Command{Name: "docker", Args: []string{"fail"}}); err == nil {
+		t.Error("expected error from Respond")
+	}
+
+	want := []string{"docker ps", "docker fail"}
+	if !reflect.DeepEqual(f.Commands(), want) {
+		t.Errorf("Commands() = %v, want %v", f.Commands(), want)
+	}
+	f.Reset()
+	if len(f.Calls()) != 0 {
+		t.Error("Reset() should forget calls")
+	}
+}
+
+func TestTimed(t *testing.T) {
+	var out bytes.Buffer
+	fake := &Fake{Respond: func(cmd Command) error {
+		if cmd.Name == "false" {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}}
+	timed := &Timed{Runner: fake, Out: &out}
+
+	timed.Run(context.Background(), Command{Name: "true"})
+	if err := timed.Run(context.Background(), Command{Name: "false"}); err == nil {
+		t.Error("Timed must pass errors through")
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected start and end lines per command, got %q", lines)
+	}
+	if lines[0] != "▶ true" || !strings.HasPrefix(lines[1], "✔ true (") {
+		t.Errorf("unexpected lines for success: %q", lines[:2])
+	}
+	if !strings.HasPrefix(lines[3], "✘ false (") || !strings.HasSuffix(lines[3], "): exit status 1") {
+		t.Errorf("unexpected line for failure: %q", lines[3])
+	}
+	if len(fake.Calls()) != 2 {
+		t.Errorf("expected the wrapped runner to run both commands, got %v", fake.Commands())
+	}
+}
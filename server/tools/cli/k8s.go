@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/compose"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/k8s"
+)
+
+var (
+	k8sOut      string
+	k8sForce    bool
+	k8sStdout   bool
+	k8sReplicas int
+)
+
+var k8sCmd = &cobra.Command{
+	Use:   "k8s",
+	Short: "Manage the Kubernetes manifests",
+	Long: `Work with the Kubernetes manifests 'saas deploy' renders.
+
+Examples:
+  saas k8s generate              # Write manifests for every service
+  saas k8s generate auth --force # Regenerate one service`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var k8sGenerateCmd = &cobra.Command{
+	Use:   "generate [service...]",
+	Short: "Generate Deployment and Service manifests from docker-compose",
+	Long: `Generate a manifest per application service (every compose service with
+a build) into k8s_base, where 'saas deploy' picks it up.
+
+Each file holds a Deployment, a ClusterIP Service and, when the service has
+environment variables of its own in docker-compose.yml, a <service>-config
+ConfigMap. Variables already in the shared <project>-config ConfigMap or
+<project>-secrets Secret, those listed in k8s_exclude_env in saas.yaml and
+anything that looks like a credential are left out. The port and health path come from saas.yaml and are used for the
+liveness and readiness probes.
+
+Existing files are kept unless --force is given, so hand-written manifests
+such as api-gateway.yaml are not overwritten.
+
+Examples:
+  saas k8s generate                 # All application services
+  saas k8s generate auth user       # Selected services
+  saas k8s generate --stdout auth   # Print instead of writing
+  saas k8s generate --force         # Overwrite existing files`,
+	ValidArgsFunction: completeServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		project, err := loadProject(c, false)
+		if err != nil {
+			return err
+		}
+
+		names, err := generateSelection(c, project, args)
+		if err != nil {
+			return err
+		}
+
+		dir := c.Resolve(c.K8sBase)
+		if k8sOut != "" {
+			dir = k8sOut
+		}
+		shared := sharedEnv(c, dir)
+
+		for _, name := range names {
+			w, err := workloadFor(c, project, project.Service(name), shared)
+			if err != nil {
+				return err
+			}
+			manifest, err := k8s.Generate(w)
+			if err != nil {
+				return err
+			}
+			manifest = append([]byte(generatedHeader(name)), manifest...)
+
+			if k8sStdout {
+				fmt.Fprint(cmd.OutOrStdout(), "---\n"+string(manifest))
+				continue
+			}
+			if err := writeManifest(filepath.Join(dir, name+".yaml"), manifest); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func generatedHeader(name string) string {
+	return "# " + name + " - generated by `saas k8s generate` from docker-compose.yml\n" +
+		"# and saas.yaml. Edit as needed; regenerate with --force.\n"
+}
+
+// generateSelection returns the compose names of the services to generate:
+// the arguments, or every service with a build.
+func generateSelection(c *config.Config, project *compose.Project, args []string) ([]string, error) {
+	if len(args) == 0 {
+		var names []string
+		for _, name := range project.Names() {
+			if project.Service(name).Build != nil {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+
+	names, err := resolveComposeNames(c, args)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		svc := project.Service(name)
+		if svc == nil {
+			return nil, fmt.Errorf("%s is not defined in docker-compose.yml", name)
+		}
+		if svc.Build == nil {
+			return nil, withExitCode(exitInvalidArgs, fmt.Errorf("%s has no build in docker-compose.yml; only application services are generated", name))
+		}
+	}
+	return names, nil
+}
+
+func writeManifest(path string, manifest []byte) error {
+	if _, err := os.Stat(path); err == nil && !k8sForce {
+		fmt.Printf("⏭️  %s exists, skipping (use --force to overwrite)\n", path)
+		return nil
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if dryRun {
+		fmt.Printf("Would write %s\n", path)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, manifest, 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	fmt.Printf("📝 Wrote %s\n", path)
+	return nil
+}
+
+// sharedEnv returns the keys of the shared ConfigMap and Secret in dir.
+func sharedEnv(c *config.Config, dir string) map[string]bool {
+	keys := map[string]bool{}
+	objs, err := k8s.LoadDir(dir)
+	if err != nil {
+		return keys
+	}
+	for _, obj := range objs {
+		if (obj.Kind() == "ConfigMap" && obj.Name() == c.Project+"-config") || (obj.Kind() == "Secret" && obj.Name() == c.Project+"-secrets") {
+			for _, field := range []string{"data", "stringData"} {
+				data, _ := obj[field].(map[string]any)
+				for k := range data {
+					keys[k] = true
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// credentialKey matches variables that belong in a Secret.
+var credentialKey = regexp.MustCompile(`(?i)(SECRET|PASSWORD|PASSWD|TOKEN|API_KEY|PRIVATE_KEY)`)
+
+// composeDefault matches ${VAR}, ${VAR:-default} and ${VAR-default}.
+var composeDefault = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*(?::?-([^}]*))?\}`)
+
+// workloadFor derives the manifest description of a compose service from
+// its definition and its registry entry. Variables naming an infrastructure
+// service, such as REDIS_HOST=redis, are pointed at its cluster Service.
+func workloadFor(c *config.Config, project *compose.Project, svc *compose.Service, shared map[string]bool) (k8s.Workload, error) {
+	w := k8s.Workload{
+		Name:       svc.Name,
+		Namespace:  c.Project,
+		Component:  "service",
+		Image:      "<your-registry>/go-" + svc.Name + ":latest",
+		Replicas:   k8sReplicas,
+		HealthPath: "/health",
+		ConfigMaps: []string{c.Project + "-config"},
+		Secrets:    []string{c.Project + "-secrets"},
+	}
+
+	entry := c.Service(svc.Name)
+	if entry != nil {
+		if entry.Repo != "" {
+			w.Image = "<your-registry>/" + strings.TrimSuffix(path.Base(entry.Repo), ".git") + ":latest"
+		}
+		if entry.HealthPath != "" {
+			w.HealthPath = entry.HealthPath
+		}
+		if entry.Name == "gateway" || strings.HasSuffix(entry.Name, "-gateway") {
+			w.Component = "gateway"
+		}
+	}
+
+	for _, spec := range svc.Ports {
+		port, err := containerPort(spec)
+		if err != nil {
+			return w, fmt.Errorf("%s: %w", svc.Name, err)
+		}
+		w.Ports = append(w.Ports, k8s.Port{Port: port})
+	}
+	if len(w.Ports) == 0 {
+		return w, fmt.Errorf("%s publishes no ports in docker-compose.yml", svc.Name)
+	}
+	w.HTTPPort = w.Ports[0].Port
+	if entry != nil && entry.Port != 0 {
+		w.HTTPPort = entry.Port
+	}
+	grpc := false
+	for i := range w.Ports {
+		switch {
+		case w.Ports[i].Port == w.HTTPPort:
+			w.Ports[i].Name = "http"
+		case !grpc:
+			w.Ports[i].Name, grpc = "grpc", true
+		default:
+			w.Ports[i].Name = "port-" + strconv.Itoa(w.Ports[i].Port)
+		}
+	}
+
+	for k, v := range svc.Environment {
+		if shared[k] || credentialKey.MatchString(k) || slices.Contains(c.K8sExcludeEnv, k) {
+			continue
+		}
+		if w.Env == nil {
+			w.Env = map[string]string{}
+		}
+		v = composeDefault.ReplaceAllString(v, "$1")
+		if dep := project.Service(v); dep != nil && dep.Build == nil {
+			// Infrastructure is reached through <name>-service in the
+			// cluster, as in the shared ConfigMap.
+			v += "-service"
+		}
+		w.Env[k] = v
+	}
+	return w, nil
+}
+
+// containerPort returns the container side of a compose port mapping such
+// as "8080", "8080:8080", "127.0.0.1:8080:8080" or "8080:8080/tcp".
+func containerPort(spec string) (int, error) {
+	spec, _, _ = strings.Cut(spec, "/")
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		spec = spec[i+1:]
+	}
+	port, err := strconv.Atoi(spec)
+	if err != nil {
+		return 0, fmt.Errorf("unsupported port mapping %q", spec)
+	}
+	return port, nil
+}
+
+func init() {
+	k8sGenerateCmd.Flags().StringVar(&k8sOut, "out", "", "Directory to write to (default: k8s_base from saas.yaml)")
+	k8sGenerateCmd.Flags().BoolVar(&k8sForce, "force", false, "Overwrite existing manifests")
+	k8sGenerateCmd.Flags().BoolVar(&k8sStdout, "stdout", false, "Print the manifests instead of writing them")
+	k8sGenerateCmd.Flags().IntVar(&k8sReplicas, "replicas", 2, "Replica count of every Deployment")
+	k8sCmd.AddCommand(k8sGenerateCmd)
+}
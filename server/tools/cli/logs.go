@@ -1,36 +1,168 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"regexp"
+	"syscall"
 
 	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/logs"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/runner"
+)
+
+var (
+	logsFollow    bool
+	logsSince     string
+	logsTail      string
+	logsGrep      string
+	logsLevel     string
+	logsJSON      bool
+	logsPretty    bool
+	logsNoColor   bool
+	logsSelection serviceSelection
 )
 
 var logsCmd = &cobra.Command{
-	Use:   "logs [service]",
+	Use:   "logs [service...]",
 	Short: "View service logs",
-	Long: `View logs from all services or a specific service.
+	Long: `View logs from all services or a selection of services.
+
+The logs of every service are streamed concurrently and each line is
+prefixed with its service name. JSON log lines are parsed so they can be
+filtered by level; --grep matches the raw line.
+
+--pretty renders JSON lines as aligned columns (time, level, service,
+message, trace ID), expands error and stack fields below the line, and
+links trace IDs to the local Jaeger UI in terminals that support
+hyperlinks.
 
 Examples:
-  saas logs              # View all logs
-  saas logs auth         # View auth service logs`,
-	Run: func(cmd *cobra.Command, args []string) {
-		target := "logs"
+  saas logs                         # View all logs
+  saas logs auth                    # View auth service logs
+  saas logs -f auth user            # Follow several services
+  saas logs --since 10m --tail 100  # Recent lines only
+  saas logs --grep 'tenant_id=42'   # Lines matching a regular expression
+  saas logs --level error           # Errors and worse
+  saas logs --json auth | jq .msg   # One JSON object per line for jq
+  saas logs -f --pretty             # Readable columns for JSON logs`,
+	ValidArgsFunction: completeServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if logsJSON && logsPretty {
+			return withExitCode(exitInvalidArgs, errors.New("--json and --pretty can't be combined"))
+		}
+
+		filter := logs.Filter{}
+		if logsGrep != "" {
+			re, err := regexp.Compile(logsGrep)
+			if err != nil {
+				return withExitCode(exitInvalidArgs, fmt.Errorf("invalid --grep pattern: %w", err))
+			}
+			filter.Pattern = re
+		}
+		if logsLevel != "" {
+			level, err := logs.ParseLevel(logsLevel)
+			if err != nil {
+				return withExitCode(exitInvalidArgs, err)
+			}
+			filter.MinLevel = level
+		}
+
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		project, err := loadProject(c, false)
+		if err != nil {
+			return err
+		}
+
+		services, err := logsSelection.resolve(c, project, args)
+		if err != nil {
+			return err
+		}
+		if len(services) == 0 {
+			services = project.Names()
+		}
 
-		if len(args) > 0 {
-			// Specific service
-			service := args[0]
-			fmt.Printf("📋 Viewing %s logs...\n", service)
-			target = "logs-service SERVICE=" + service + "-service"
-		} else {
-			// All logs
-			fmt.Println("📋 Viewing all service logs...")
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		orch := newOrchestrator(project)
+		open := func(ctx context.Context, service string) (io.ReadCloser, error) {
+			return openLogs(ctx, orch.ComposeArgs(logsArgs(service)...))
 		}
 
-		if err := runCommand("make", target); err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Failed to view logs: %v\n", err)
-			os.Exit(1)
+		out := cmd.OutOrStdout()
+		printer := logs.NewPrinter(out, services, !logsJSON && !logsNoColor && colorEnabled(out), logsJSON)
+		printer.Pretty = logsPretty
+		printer.TraceURL = jaegerTraceURL(c)
+		if err := logs.Stream(ctx, services, open, filter, printer); err != nil {
+			return fmt.Errorf("failed to view logs: %w", err)
 		}
+		return nil
 	},
 }
+
+// logsArgs returns the `docker compose logs` arguments for one service.
+func logsArgs(service string) []string {
+	args := []string{"logs", "--no-color", "--no-log-prefix"}
+	if logsFollow {
+		args = append(args, "--follow")
+	}
+	if logsSince != "" {
+		args = append(args, "--since", logsSince)
+	}
+	if logsTail != "" {
+		args = append(args, "--tail", logsTail)
+	}
+	return append(args, service)
+}
+
+// openLogs runs `docker <args>` in the background and returns its combined
+// output. Reading the stream returns the command's error once it has
+// exited.
+func openLogs(ctx context.Context, args []string) (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(cmdRunner.Run(ctx, runner.Command{Name: "docker", Args: args, Stdout: pw, Stderr: pw}))
+	}()
+	return pr, nil
+}
+
+// jaegerTraceURL returns the prefix of trace pages in the local Jaeger UI.
+func jaegerTraceURL(c *config.Config) string {
+	port := 16686
+	if svc := c.Service("jaeger"); svc != nil && svc.Port != 0 {
+		port = svc.Port
+	}
+	return fmt.Sprintf("http://%s:%d/trace/", c.Host, port)
+}
+
+// colorEnabled reports whether w is a terminal and NO_COLOR is unset.
+func colorEnabled(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow log output")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Show logs since a timestamp (2024-01-02T13:23:37Z) or relative duration (42m)")
+	logsCmd.Flags().StringVarP(&logsTail, "tail", "n", "", "Number of lines to show from the end of each service's logs")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "Only show lines matching this regular expression")
+	logsCmd.Flags().StringVar(&logsLevel, "level", "", "Only show lines at this level or above: debug, info, warn, error, fatal")
+	logsCmd.Flags().BoolVar(&logsJSON, "json", false, "Print one JSON object per line, e.g. for piping to jq")
+	logsCmd.Flags().BoolVar(&logsPretty, "pretty", false, "Render JSON log lines as aligned, readable columns")
+	logsCmd.Flags().BoolVar(&logsNoColor, "no-color", false, "Disable colored service prefixes")
+	logsSelection.register(logsCmd)
+}
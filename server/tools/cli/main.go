@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/compose"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/orchestrator"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/runner"
 )
 
 const (
@@ -13,13 +20,148 @@ const (
 	version = "1.0.0"
 )
 
+var (
+	// viaMake makes start/stop delegate to the Makefile targets instead of
+	// orchestrating docker compose natively.
+	viaMake bool
+
+	// configPath overrides saas.yaml discovery.
+	configPath string
+
+	// cfg is the loaded project configuration; use loadConfig to access it.
+	cfg *config.Config
+
+	// dryRun prints commands instead of running them.
+	dryRun bool
+
+	// verbose reports every step and command with its duration.
+	verbose bool
+)
+
+// loadConfig loads the project configuration once. The file is taken from
+// --config, then $SAAS_CONFIG, then discovered by walking up from the
+// working directory; without one the built-in defaults are used.
+func loadConfig() (*config.Config, error) {
+	if cfg != nil {
+		return cfg, nil
+	}
+
+	path := configPath
+	if path == "" {
+		path = os.Getenv("SAAS_CONFIG")
+	}
+
+	done := step("load configuration")
+	defer done()
+
+	var err error
+	if path != "" {
+		cfg, err = config.Load(path)
+	} else {
+		var wd string
+		if wd, err = os.Getwd(); err == nil {
+			cfg, err = config.LoadFrom(wd)
+		}
+	}
+	if err != nil {
+		cfg = nil
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// execRunner runs external commands for real. Tests replace it with a
+// runner.Fake.
+var execRunner runner.Runner = runner.Exec{}
+
+// cmdRunner runs every external command: execRunner, or a dry run, wrapped
+// for --verbose. configureRunner sets it before each command.
+var cmdRunner runner.Runner = execRunner
+
+// configureRunner applies --dry-run and --verbose.
+func configureRunner(cmd *cobra.Command, args []string) error {
+	cmdRunner = execRunner
+	if dryRun {
+		fmt.Fprintln(cmd.ErrOrStderr(), "🔍 Dry run: commands are printed, not executed")
+		cmdRunner = &runner.DryRun{Out: cmd.OutOrStdout()}
+	}
+	if verbose {
+		cmdRunner = &runner.Timed{Runner: cmdRunner, Out: cmd.ErrOrStderr()}
+	}
+	return nil
+}
+
+// step reports the start of a step with --verbose and returns a function
+// that reports its duration. Without --verbose it does nothing.
+func step(name string) func() {
+	if !verbose {
+		return func() {}
+	}
+	started := time.Now()
+	fmt.Fprintf(os.Stderr, "▶ %s\n", name)
+	return func() {
+		fmt.Fprintf(os.Stderr, "✔ %s (%s)\n", name, time.Since(started).Round(time.Millisecond))
+	}
+}
+
+// registerGlobalFlags adds the flags shared by every command to root.
+func registerGlobalFlags(root *cobra.Command) {
+	root.PersistentFlags().StringVar(&configPath, "config", "", "Path to saas.yaml (default: discovered from the working directory)")
+	root.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Print the commands that would run without running them")
+	root.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log each step and command with its duration")
+	root.PersistentPreRunE = configureRunner
+}
+
+// exitError carries the process exit code for an error returned by a
+// command. A nil err exits silently, for commands that already reported
+// the problem.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode makes a command exit with code instead of 1.
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
 // runCommand executes a command with the given arguments and pipes output to stdout/stderr.
-// Returns an error if the command fails.
+// Commands run from the project directory. Returns an error if the command fails.
 func runCommand(name string, args ...string) error {
-	cmd := exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	return cmdRunner.Run(context.Background(), runner.Command{
+		Name:   name,
+		Args:   args,
+		Dir:    c.Dir,
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+}
+
+// runOutput executes a command and returns its standard output.
+func runOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return runner.Output(ctx, cmdRunner, runner.Command{Name: name, Args: args})
+}
+
+// newOrchestrator returns an orchestrator for the compose project that
+// reports per-service progress on stdout.
+func newOrchestrator(project *compose.Project) *orchestrator.Orchestrator {
+	return orchestrator.New(project, cmdRunner, os.Stdout)
 }
 
 var rootCmd = &cobra.Command{
@@ -41,8 +183,11 @@ Examples:
   saas logs auth      # View auth service logs
   saas test           # Run all tests
   saas deploy local   # Deploy to local Kubernetes
+  saas stop --dry-run # Show what stop would run
 
 For more information, visit: https://github.com/vhvplatform/go-framework`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
@@ -61,15 +206,42 @@ func init() {
 	rootCmd.AddCommand(setupCmd)
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(restartCmd)
+	rootCmd.AddCommand(rebuildCmd)
 	rootCmd.AddCommand(logsCmd)
 	rootCmd.AddCommand(testCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(deployCmd)
+	rootCmd.AddCommand(waitCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(shellCmd)
+	rootCmd.AddCommand(k8sCmd)
+	rootCmd.AddCommand(promoteCmd)
+	rootCmd.AddCommand(mockCmd)
+
+	registerGlobalFlags(rootCmd)
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	os.Exit(execute(rootCmd))
+}
+
+// execute runs the command tree and returns the process exit code.
+// Errors are reported here, once, instead of by each command.
+func execute(root *cobra.Command) int {
+	err := root.Execute()
+	if err == nil {
+		return 0
+	}
+
+	code := 1
+	var exit *exitError
+	if errors.As(err, &exit) {
+		code = exit.code
+		if exit.err == nil {
+			return code
+		}
 	}
+	fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+	return code
 }
@@ -2,10 +2,15 @@ package main
 
 import (
 	"bytes"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/compose"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
 )
 
 // resetRootCmd resets the rootCmd for each test to avoid state pollution
@@ -29,8 +34,11 @@ Examples:
   saas logs auth      # View auth service logs
   saas test           # Run all tests
   saas deploy local   # Deploy to local Kubernetes
+  saas stop --dry-run # Show what stop would run
 
 For more information, visit: https://github.com/vhvplatform/go-framework`,
+		SilenceErrors: true,
+		SilenceUsage:  true,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
 		},
@@ -40,11 +48,20 @@ For more information, visit: https://github.com/vhvplatform/go-framework`,
 	cmd.AddCommand(setupCmd)
 	cmd.AddCommand(startCmd)
 	cmd.AddCommand(stopCmd)
+	cmd.AddCommand(restartCmd)
+	cmd.AddCommand(rebuildCmd)
 	cmd.AddCommand(logsCmd)
 	cmd.AddCommand(testCmd)
 	cmd.AddCommand(statusCmd)
 	cmd.AddCommand(deployCmd)
-
+	cmd.AddCommand(waitCmd)
+	cmd.AddCommand(configCmd)
+	cmd.AddCommand(shellCmd)
+	cmd.AddCommand(k8sCmd)
+	cmd.AddCommand(promoteCmd)
+	cmd.AddCommand(mockCmd)
+
+	registerGlobalFlags(cmd)
 	return cmd
 }
 
@@ -109,11 +126,18 @@ func TestCommandExists(t *testing.T) {
 		{"Setup command", "setup"},
 		{"Start command", "start"},
 		{"Stop command", "stop"},
+		{"Restart command", "restart"},
+		{"Rebuild command", "rebuild"},
 		{"Logs command", "logs"},
 		{"Test command", "test"},
 		{"Status command", "status"},
 		{"Deploy command", "deploy"},
 		{"Version command", "version"},
+		{"Wait command", "wait"},
+		{"Config command", "config"},
+		{"Shell command", "shell"},
+		{"K8s command", "k8s"},
+		{"Promote command", "promote"},
 	}
 
 	for _, tt := range tests {
@@ -247,3 +271,53 @@ func TestVersionValue(t *testing.T) {
 		t.Errorf("Version '%s' should contain dots for semantic versioning", version)
 	}
 }
+
+func TestServiceSelection(t *testing.T) {
+	c := config.Default()
+	c.Dir = "../.."
+	project, err := compose.Load(c.ComposePaths(false)...)
+	if err != nil {
+		t.Fatalf("load compose: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		sel  serviceSelection
+		args []string
+		want []string
+	}{
+		{"aliases", serviceSelection{}, []string{"auth", "user", "tenant"}, []string{"auth-service", "user-service", "tenant-service"}},
+		{"group", serviceSelection{groups: []string{"infra"}}, nil, []string{"mongodb", "redis", "rabbitmq"}},
+		{"args and group deduplicated", serviceSelection{groups: []string{"infra"}}, []string{"redis"}, []string{"redis", "mongodb", "rabbitmq"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.sel.resolve(c, project, tt.args)
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("all except", func(t *testing.T) {
+		sel := serviceSelection{allExcept: []string{"mongo"}}
+		got, err := sel.resolve(c, project, nil)
+		if err != nil {
+			t.Fatalf("resolve() error = %v", err)
+		}
+		if len(got) != len(project.Names())-1 || slices.Contains(got, "mongodb") {
+			t.Errorf("resolve() = %v, want every service but mongodb", got)
+		}
+	})
+
+	t.Run("all except with args", func(t *testing.T) {
+		sel := serviceSelection{allExcept: []string{"mongo"}}
+		if _, err := sel.resolve(c, project, []string{"auth"}); err == nil {
+			t.Error("expected error combining --all-except with service names")
+		}
+	})
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/faults"
+)
+
+var (
+	faultPath      string
+	faultMethod    string
+	faultTenant    string
+	faultLatency   time.Duration
+	faultStatus    int
+	faultRate      float64
+	faultDrop      bool
+	faultUnhealthy bool
+	faultTTL       time.Duration
+	faultIDs       []string
+	faultOutput    string
+	faultTimeout   time.Duration
+)
+
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "Control the mock services",
+	Long: `Control the mock services under server/mocks while they run.
+
+Examples:
+  saas mock fault add auth --status 503 --rate 0.5   # Fail half the auth requests
+  saas mock fault list                                # Show the active rules`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var mockFaultCmd = &cobra.Command{
+	Use:   "fault",
+	Short: "Inject latency, errors and outages into mock services",
+	Long: `Manage the fault rules of the mock services.
+
+A rule matches requests by path, method and X-Tenant-ID header and
+delays them, answers them with a status code or drops the connection.
+--path matches whole segments: /api/v1/user covers /api/v1/user/1 but
+not /api/v1/users. An --unhealthy rule makes /health and /ready answer
+503 instead. Rules expire after --ttl, so a test that forgets to clear
+them leaves no trace for long.
+
+Examples:
+  saas mock fault add auth --latency 2s                          # Slow down every auth request
+  saas mock fault add auth --path /api/v1/auth/login --status 500
+  saas mock fault add users --tenant tenant-2 --drop --ttl 30s   # Drop one tenant's requests
+  saas mock fault add tenant --unhealthy                         # Fail tenant-service health checks
+  saas mock fault list                                           # Rules of every mock
+  saas mock fault clear auth                                     # Remove the rules of auth-service`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var mockFaultAddCmd = &cobra.Command{
+	Use:   "add <service>",
+	Short: "Add a fault rule to a mock service",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if faultLatency == 0 && faultStatus == 0 && !faultDrop && !faultUnhealthy {
+			return withExitCode(exitInvalidArgs, errors.New("give at least one of --latency, --status, --drop or --unhealthy"))
+		}
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		svc, err := mockService(c, args[0])
+		if err != nil {
+			return withExitCode(exitInvalidArgs, err)
+		}
+
+		rule := faults.Rule{
+			Path:      faultPath,
+			Method:    strings.ToUpper(faultMethod),
+			Tenant:    faultTenant,
+			Status:    faultStatus,
+			Drop:      faultDrop,
+			Unhealthy: faultUnhealthy,
+			Rate:      faultRate,
+		}
+		if faultLatency > 0 {
+			rule.Latency = faultLatency.String()
+		}
+		if faultTTL > 0 {
+			rule.TTL = faultTTL.String()
+		}
+
+		out := cmd.OutOrStdout()
+		if dryRun {
+			fmt.Fprintf(out, "Would add to %s: %s on %s\n", svc.Name, rule.Effect(), rule.Scope())
+			return nil
+		}
+		added, err := faultClient(c, svc).Add(context.Background(), rule)
+		if err != nil {
+			return faultError(svc, err)
+		}
+		fmt.Fprintf(out, "💥 %s %s: %s on %s until %s\n", svc.Name, added.ID, added.Effect(), added.Scope(), added.ExpiresAt.Local().Format(time.TimeOnly))
+		return nil
+	},
+	ValidArgsFunction: completeServices,
+}
+
+var mockFaultListCmd = &cobra.Command{
+	Use:   "list [service...]",
+	Short: "List the active fault rules",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if faultOutput != "table" && faultOutput != "json" {
+			return withExitCode(exitInvalidArgs, fmt.Errorf("unknown output format: %s (use table or json)", faultOutput))
+		}
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		services, err := mockServices(c, args)
+		if err != nil {
+			return withExitCode(exitInvalidArgs, err)
+		}
+
+		rules := map[string][]faults.Rule{}
+		var errs []error
+		for _, svc := range services {
+			list, err := faultClient(c, &svc).List(context.Background())
+			if err != nil {
+				errs = append(errs, faultError(&svc, err))
+				continue
+			}
+			rules[svc.Name] = list
+		}
+		if err := writeFaults(cmd.OutOrStdout(), faultOutput, services, rules); err != nil {
+			return err
+		}
+		return errors.Join(errs...)
+	},
+	ValidArgsFunction: completeServices,
+}
+
+var mockFaultClearCmd = &cobra.Command{
+	Use:   "clear [service...]",
+	Short: "Remove fault rules",
+	Long: `Remove the fault rules of the given mock services, or of every mock.
+With --id only the named rules are removed, from a single service.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(faultIDs) > 0 && len(args) != 1 {
+			return withExitCode(exitInvalidArgs, errors.New("--id needs exactly one service"))
+		}
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		services, err := mockServices(c, args)
+		if err != nil {
+			return withExitCode(exitInvalidArgs, err)
+		}
+
+		out := cmd.OutOrStdout()
+		var errs []error
+		for _, svc := range services {
+			if dryRun {
+				fmt.Fprintf(out, "Would clear the fault rules of %s\n", svc.Name)
+				continue
+			}
+			client := faultClient(c, &svc)
+			if len(faultIDs) == 0 {
+				if err := client.Clear(context.Background()); err != nil {
+					errs = append(errs, faultError(&svc, err))
+					continue
+				}
+				fmt.Fprintf(out, "🧹 %s: cleared\n", svc.Name)
+				continue
+			}
+			for _, id := range faultIDs {
+				if err := client.Delete(context.Background(), id); err != nil {
+					errs = append(errs, faultError(&svc, err))
+					continue
+				}
+				fmt.Fprintf(out, "🧹 %s: removed %s\n", svc.Name, id)
+			}
+		}
+		return errors.Join(errs...)
+	},
+	ValidArgsFunction: completeServices,
+}
+
+// defaultMockGroups are the services `saas mock fault list` and `clear`
+// address when none are given.
+var defaultMockGroups = []string{"core"}
+
+// mockServices resolves service arguments, or returns the mocks of
+// defaultMockGroups when there are none.
+func mockServices(c *config.Config, names []string) ([]config.Service, error) {
+	if len(names) == 0 {
+		members, err := c.GroupMembers(defaultMockGroups...)
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range members {
+			if svc.Port != 0 && svc.CheckKind() == config.CheckHTTP {
+				names = append(names, svc.Name)
+			}
+		}
+	}
+	services := make([]config.Service, 0, len(names))
+	for _, name := range names {
+		svc, err := mockService(c, name)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, *svc)
+	}
+	return services, nil
+}
+
+// mockService resolves a service argument to a service serving HTTP.
+func mockService(c *config.Config, name string) (*config.Service, error) {
+	svc, err := resolveService(c, name)
+	if err != nil {
+		return nil, err
+	}
+	if svc.Port == 0 || svc.CheckKind() != config.CheckHTTP {
+		return nil, fmt.Errorf("service %q has no HTTP port configured in %s", svc.Name, config.FileName)
+	}
+	return svc, nil
+}
+
+func faultClient(c *config.Config, svc *config.Service) *faults.Client {
+	return faults.NewClient(fmt.Sprintf("http://%s:%d", c.Host, svc.Port), faultTimeout)
+}
+
+// faultError names the service in err. A rejected rule exits with the
+// invalid-arguments code.
+func faultError(svc *config.Service, err error) error {
+	var apiErr *faults.APIError
+	if errors.As(err, &apiErr) && apiErr.Status == 422 {
+		return withExitCode(exitInvalidArgs, fmt.Errorf("%s rejected the rule: %w", svc.Name, err))
+	}
+	return fmt.Errorf("%s: %w", svc.Name, err)
+}
+
+// writeFaults renders the rules of each service in the requested format.
+func writeFaults(w io.Writer, format string, services []config.Service, rules map[string][]faults.Rule) error {
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rules)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tID\tMATCH\tEFFECT\tRATE\tHITS\tEXPIRES")
+	for _, svc := range services {
+		for _, r := range rules[svc.Name] {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%g\t%d\t%s\n",
+				svc.Name, r.ID, r.Scope(), r.Effect(), r.Rate, r.Hits, time.Until(r.ExpiresAt).Round(time.Second))
+		}
+	}
+	return tw.Flush()
+}
+
+func init() {
+	mockCmd.AddCommand(mockFaultCmd)
+	mockFaultCmd.AddCommand(mockFaultAddCmd, mockFaultListCmd, mockFaultClearCmd)
+	mockFaultCmd.PersistentFlags().DurationVar(&faultTimeout, "timeout", 5*time.Second, "Timeout for each request to a mock")
+
+	f := mockFaultAddCmd.Flags()
+	f.StringVar(&faultPath, "path", "", "Only requests to this path or below it, e.g. /api/v1/users matches /api/v1/users/1 but not /api/v1/users-admin")
+	f.StringVar(&faultMethod, "method", "", "Only requests with this HTTP method")
+	f.StringVar(&faultTenant, "tenant", "", "Only requests with this X-Tenant-ID")
+	f.DurationVar(&faultLatency, "latency", 0, "Delay matching requests by this long")
+	f.IntVar(&faultStatus, "status", 0, "Answer matching requests with this status code")
+	f.BoolVar(&faultDrop, "drop", false, "Close the connection of matching requests without an answer")
+	f.BoolVar(&faultUnhealthy, "unhealthy", false, "Make /health and /ready answer 503")
+	f.Float64Var(&faultRate, "rate", 1, "Share of matching requests to apply the rule to, from 0 (none) to 1 (all)")
+	f.DurationVar(&faultTTL, "ttl", 5*time.Minute, "Remove the rule after this long")
+
+	mockFaultListCmd.Flags().StringVarP(&faultOutput, "output", "o", "table", "Output format: table, json")
+	mockFaultClearCmd.Flags().StringSliceVar(&faultIDs, "id", nil, "Remove only these rules")
+}
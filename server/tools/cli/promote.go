@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/history"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/k8s"
+)
+
+var promoteID string
+
+var promoteCmd = &cobra.Command{
+	Use:   "promote <from> <to>",
+	Short: "Deploy the images of one environment to another",
+	Long: `Deploy the exact images of the last successful deployment of one
+environment to another.
+
+The manifests are rendered with the overlay of the target environment, so
+only the images move; its namespace, replicas and configuration stay its
+own. The result is recorded in the history of the target environment.
+
+Protected environments need --confirm, and --approved-by when they
+require approvals.
+
+Examples:
+  saas promote dev staging --confirm --approved-by alice
+  saas promote dev staging --id 12 --confirm --approved-by alice
+  saas promote dev staging --diff    # Only show what would change`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeEnvironments(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if args[0] == args[1] {
+			return withExitCode(exitInvalidArgs, fmt.Errorf("cannot promote %s to itself", args[0]))
+		}
+		c, from, err := loadEnvironment(args[0])
+		if err != nil {
+			return err
+		}
+		_, to, err := loadEnvironment(args[1])
+		if err != nil {
+			return err
+		}
+
+		store := historyStore(c)
+		var source *history.Record
+		if promoteID != "" {
+			id, err := history.ParseID(promoteID)
+			if err != nil {
+				return withExitCode(exitInvalidArgs, err)
+			}
+			if source, err = store.Get(from.Name, id); err != nil {
+				return err
+			}
+			if source.Status != history.StatusSucceeded {
+				return fmt.Errorf("deployment #%d of %s %s; only successful deployments are promoted", source.ID, from.Name, source.Status)
+			}
+		} else if source, err = store.LastSucceeded(from.Name); err != nil {
+			return fmt.Errorf("nothing to promote: %w", err)
+		}
+
+		o, err := overlayFor(c, to, nil, nil)
+		if err != nil {
+			return err
+		}
+		o.Images = source.Images
+		objs, err := renderEnvironment(c, to, o)
+		if err != nil {
+			return err
+		}
+		if err := k8s.CheckImages(objs); err != nil {
+			return err
+		}
+
+		fmt.Printf("🚀 Promoting %s #%d (commit %s, %s) to %s\n",
+			from.Name, source.ID, source.GitSHA, source.Time.Local().Format("2006-01-02 15:04"), to.Name)
+		rec := history.Record{PromotedFrom: fmt.Sprintf("%s#%d", from.Name, source.ID)}
+		return applyDeployment(cmd.OutOrStdout(), c, to, objs, rec)
+	},
+}
+
+func init() {
+	promoteCmd.Flags().StringVar(&promoteID, "id", "", "Deployment of the source environment to promote (default: its last successful one)")
+	promoteCmd.Flags().BoolVar(&deployDiffOnly, "diff", false, "Show the diff against the cluster without applying")
+	promoteCmd.Flags().DurationVar(&deployTimeout, "timeout", 5*time.Minute, "Maximum time to wait for each rollout")
+	addProtectionFlags(promoteCmd)
+}
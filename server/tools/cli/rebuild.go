@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/compose"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/orchestrator"
+)
+
+var (
+	rebuildNoCache     bool
+	rebuildPull        bool
+	rebuildDev         bool
+	rebuildNoWait      bool
+	rebuildWaitTimeout time.Duration
+	rebuildSelection   serviceSelection
+)
+
+var rebuildCmd = &cobra.Command{
+	Use:   "rebuild [service...]",
+	Short: "Rebuild images and recreate services",
+	Long: `Build fresh images for services, recreate their containers and wait
+until they are healthy again.
+
+A service is built from its local checkout (the 'source' directory in
+saas.yaml, e.g. ../../go-auth-service) when that contains a Dockerfile, and
+from the mock under server/mocks otherwise. Services without a build, such
+as mongodb, are only recreated. Dependencies are left running.
+
+Containers running with hot-reload ('saas start --dev') are recreated with
+the development overrides, so the source mount and HOT_RELOAD survive the
+rebuild. Pass --dev to force them.
+
+With no services, every service that has a build is rebuilt.
+
+Examples:
+  saas rebuild auth               # Rebuild auth service
+  saas rebuild auth user          # Rebuild several services
+  saas rebuild --group core       # Rebuild all microservices
+  saas rebuild auth --no-cache    # Build without the layer cache
+  saas rebuild auth --pull        # Pull newer base images first
+  saas rebuild --via-make auth    # Use the Makefile target instead`,
+	ValidArgsFunction: completeServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		services := args
+		if viaMake {
+			err = rebuildViaMake(c, args)
+		} else {
+			services, err = rebuildNative(c, args)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !rebuildNoWait {
+			if err := waitForServices(c, waitableServices(c, services), rebuildWaitTimeout); err != nil {
+				return err
+			}
+		}
+
+		fmt.Println("✅ Services rebuilt!")
+		return nil
+	},
+}
+
+// rebuildNative rebuilds services with the orchestrator and returns the
+// compose names of the rebuilt services.
+func rebuildNative(c *config.Config, args []string) ([]string, error) {
+	ctx := context.Background()
+
+	project, err := loadProject(c, false)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := rebuildSelection.resolve(c, project, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		services = buildableServices(c, project)
+	}
+
+	dev := rebuildDev
+	if hot := hotReloadServices(ctx, project, services); len(hot) > 0 {
+		fmt.Printf("🔥 Hot-reload is active for %s; keeping the development overrides\n", strings.Join(hot, ", "))
+		dev = true
+	}
+	if dev {
+		if project, err = loadProject(c, true); err != nil {
+			return nil, err
+		}
+	}
+
+	fmt.Printf("🔨 Rebuilding %d service(s)...\n", len(services))
+	opts := orchestrator.BuildOptions{
+		NoCache: rebuildNoCache,
+		Pull:    rebuildPull,
+		Sources: sourceDirs(c, services),
+	}
+	for _, name := range services {
+		if dir, ok := opts.Sources[name]; ok {
+			fmt.Printf("   %-24s from %s\n", name, dir)
+		}
+	}
+
+	results, err := newOrchestrator(project).Rebuild(ctx, opts, services...)
+	printResults(os.Stdout, results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild services: %w", err)
+	}
+	return services, nil
+}
+
+// buildableServices returns the compose services built from source: those
+// with a build section or a local checkout.
+func buildableServices(c *config.Config, project *compose.Project) []string {
+	sources := sourceDirs(c, project.Names())
+	var names []string
+	for _, name := range project.Names() {
+		if _, ok := sources[name]; ok || project.Service(name).Build != nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// sourceDirs maps the compose services whose registry entry points at a
+// local checkout containing a Dockerfile to that directory.
+func sourceDirs(c *config.Config, services []string) map[string]string {
+	dirs := map[string]string{}
+	for _, name := range services {
+		svc := c.Service(name)
+		if svc == nil || svc.Source == "" {
+			continue
+		}
+		dir := c.Resolve(svc.Source)
+		if _, err := os.Stat(filepath.Join(dir, "Dockerfile")); err == nil {
+			dirs[name] = dir
+		}
+	}
+	return dirs
+}
+
+func rebuildViaMake(c *config.Config, args []string) error {
+	if rebuildSelection.any() || rebuildNoCache || rebuildPull {
+		return withExitCode(exitInvalidArgs, errors.New("--all-except, --group, --no-cache and --pull are not supported with --via-make"))
+	}
+	if len(args) == 0 {
+		return withExitCode(exitInvalidArgs, errors.New("--via-make needs at least one service"))
+	}
+
+	for _, arg := range args {
+		svc, err := resolveService(c, arg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🔨 Rebuilding %s...\n", svc.Name)
+
+		if err := runCommand("make", "rebuild", "SERVICE="+svc.ComposeName()); err != nil {
+			return fmt.Errorf("failed to rebuild %s: %w", svc.Name, err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	rebuildCmd.Flags().BoolVar(&rebuildNoCache, "no-cache", false, "Build images without the Docker layer cache")
+	rebuildCmd.Flags().BoolVar(&rebuildPull, "pull", false, "Always pull newer base images")
+	rebuildCmd.Flags().BoolVar(&rebuildDev, "dev", false, "Recreate containers with the development (hot-reload) overrides")
+	rebuildCmd.Flags().BoolVar(&rebuildNoWait, "no-wait", false, "Don't wait for services to become healthy")
+	rebuildCmd.Flags().DurationVar(&rebuildWaitTimeout, "wait-timeout", 5*time.Minute, "Maximum time to wait for services to become healthy")
+	rebuildCmd.Flags().BoolVar(&viaMake, "via-make", false, "Delegate to the Makefile target instead of docker compose")
+	rebuildSelection.register(rebuildCmd)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+)
+
+var (
+	restartNoWait      bool
+	restartWaitTimeout time.Duration
+	restartSelection   serviceSelection
+)
+
+var restartCmd = &cobra.Command{
+	Use:   "restart [service...]",
+	Short: "Restart services",
+	Long: `Restart all services or a selection of services and wait until they
+are healthy again.
+
+Containers are restarted in place, keeping their configuration, so this is
+the quick way to pick up changed environment files or recover a crashed
+service. Use 'saas rebuild' after code changes. Services started with
+'saas start --dev' reload code changes by themselves and rarely need either.
+
+Examples:
+  saas restart                    # Restart all services
+  saas restart auth               # Restart auth service
+  saas restart auth user          # Restart several services
+  saas restart --group core       # Restart a group from saas.yaml
+  saas restart auth --no-wait     # Don't wait for health checks
+  saas restart --via-make         # Use the Makefile targets instead`,
+	ValidArgsFunction: completeServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		services := args
+		if viaMake {
+			err = restartViaMake(c, args)
+		} else {
+			services, err = restartNative(c, args)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !restartNoWait {
+			if err := waitForServices(c, waitableServices(c, services), restartWaitTimeout); err != nil {
+				return err
+			}
+		}
+
+		fmt.Println("✅ Services restarted!")
+		return nil
+	},
+}
+
+// restartNative restarts services with the orchestrator and returns the
+// compose names of the selected services, or nil when all were restarted.
+func restartNative(c *config.Config, args []string) ([]string, error) {
+	project, err := loadProject(c, false)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := restartSelection.resolve(c, project, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(services) == 0 {
+		fmt.Println("🔄 Restarting all services...")
+	} else {
+		fmt.Printf("🔄 Restarting %d service(s)...\n", len(services))
+	}
+
+	ctx := context.Background()
+	if hot := hotReloadServices(ctx, project, services); len(hot) > 0 {
+		fmt.Printf("   🔥 Hot-reload is active for %s; code changes are picked up without a restart\n", strings.Join(hot, ", "))
+	}
+
+	results, err := newOrchestrator(project).Restart(ctx, services...)
+	printResults(os.Stdout, results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restart services: %w", err)
+	}
+	return services, nil
+}
+
+func restartViaMake(c *config.Config, args []string) error {
+	if restartSelection.any() {
+		return withExitCode(exitInvalidArgs, errors.New("--all-except and --group are not supported with --via-make"))
+	}
+
+	if len(args) == 0 {
+		fmt.Println("🔄 Restarting all services...")
+
+		if err := runCommand("make", "restart"); err != nil {
+			return fmt.Errorf("failed to restart services: %w", err)
+		}
+		return nil
+	}
+
+	for _, arg := range args {
+		svc, err := resolveService(c, arg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🔄 Restarting %s...\n", svc.Name)
+
+		if err := runCommand("make", "restart-service", "SERVICE="+svc.ComposeName()); err != nil {
+			return fmt.Errorf("failed to restart %s: %w", svc.Name, err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	restartCmd.Flags().BoolVar(&restartNoWait, "no-wait", false, "Don't wait for services to become healthy")
+	restartCmd.Flags().DurationVar(&restartWaitTimeout, "wait-timeout", 2*time.Minute, "Maximum time to wait for services to become healthy")
+	restartCmd.Flags().BoolVar(&viaMake, "via-make", false, "Delegate to the Makefile targets instead of docker compose")
+	restartSelection.register(restartCmd)
+}
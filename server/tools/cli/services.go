@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/compose"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/health"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/orchestrator"
+)
+
+// composeServicesAdded records whether the compose services have been merged
+// into the registry.
+var composeServicesAdded bool
+
+// serviceRegistry returns the configuration with every docker-compose
+// service registered, so services missing from saas.yaml can still be
+// addressed by their compose name.
+func serviceRegistry(c *config.Config) *config.Config {
+	if !composeServicesAdded {
+		if project, err := compose.Load(c.ComposePaths(false)...); err == nil {
+			c.AddComposeServices(project.Names())
+		}
+		composeServicesAdded = true
+	}
+	return c
+}
+
+// loadProject loads the compose project, including the development
+// overrides when dev is true.
+func loadProject(c *config.Config, dev bool) (*compose.Project, error) {
+	done := step("load compose project")
+	defer done()
+
+	project, err := compose.Load(c.ComposePaths(dev)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load compose project: %w", err)
+	}
+	return project, nil
+}
+
+// resolveService resolves a command-line service argument against the
+// service registry. Full names, aliases, compose names and unique prefixes
+// are accepted; unknown names come back with "did you mean" suggestions.
+func resolveService(c *config.Config, name string) (*config.Service, error) {
+	return serviceRegistry(c).Match(name)
+}
+
+// completeServices offers service names and aliases for shell completion,
+// skipping services that are already on the command line.
+func completeServices(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	c, err := loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	c = serviceRegistry(c)
+
+	used := map[string]bool{}
+	for _, arg := range args {
+		if svc := c.Service(arg); svc != nil {
+			used[svc.Name] = true
+		}
+	}
+
+	var out []string
+	for _, svc := range c.Services {
+		if used[svc.Name] {
+			continue
+		}
+		for _, name := range append([]string{svc.Name}, svc.Aliases...) {
+			if strings.HasPrefix(name, toComplete) {
+				out = append(out, name)
+			}
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// resolveComposeNames maps service arguments to docker-compose service names.
+func resolveComposeNames(c *config.Config, names []string) ([]string, error) {
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		svc, err := resolveService(c, name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, svc.ComposeName())
+	}
+	return out, nil
+}
+
+// healthTarget builds the health probe for a registry service.
+func healthTarget(c *config.Config, svc config.Service) health.Target {
+	addr := fmt.Sprintf("%s:%d", c.Host, svc.Port)
+	switch svc.CheckKind() {
+	case config.CheckHTTP:
+		return health.Target{Name: svc.Name, Kind: health.KindHTTP, Address: "http://" + addr + svc.HealthPath}
+	case config.CheckRedis:
+		return health.Target{Name: svc.Name, Kind: health.KindRedis, Address: addr}
+	default:
+		return health.Target{Name: svc.Name, Kind: health.KindTCP, Address: addr}
+	}
+}
+
+// healthTargets returns the probes for every registry service that exposes a port.
+func healthTargets(c *config.Config) []health.Target {
+	var targets []health.Target
+	for _, svc := range c.Services {
+		if svc.Port != 0 {
+			targets = append(targets, healthTarget(c, svc))
+		}
+	}
+	return targets
+}
+
+// waitableServices returns the services among names that have a port to
+// probe. With no names, it returns the default set `saas wait` checks.
+func waitableServices(c *config.Config, names []string) []string {
+	if len(names) == 0 {
+		return defaultWaitServices(c)
+	}
+
+	var out []string
+	for _, name := range names {
+		if svc, err := resolveService(c, name); err == nil && svc.Port != 0 {
+			out = append(out, svc.Name)
+		}
+	}
+	return out
+}
+
+// hotReloadServices returns the services among names whose running
+// container was started with the development overrides (HOT_RELOAD=true).
+// Services without a running container are left out.
+func hotReloadServices(ctx context.Context, project *compose.Project, names []string) []string {
+	if len(names) == 0 {
+		names = project.Names()
+	}
+
+	var out []string
+	for _, name := range names {
+		svc := project.Service(name)
+		if svc == nil || svc.ContainerName == "" {
+			continue
+		}
+		env, err := runOutput(ctx, "docker", "inspect", "--format", "{{range .Config.Env}}{{println .}}{{end}}", svc.ContainerName)
+		if err != nil {
+			continue
+		}
+		if slices.Contains(strings.Split(string(env), "\n"), "HOT_RELOAD=true") {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// serviceSelection holds the flags shared by commands that act on several
+// services at once.
+type serviceSelection struct {
+	allExcept []string
+	groups    []string
+}
+
+// register adds --all-except and --group to cmd.
+func (s *serviceSelection) register(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&s.allExcept, "all-except", nil, "Select every service except these (comma-separated or repeated)")
+	cmd.Flags().StringSliceVarP(&s.groups, "group", "g", nil, "Select services by group from saas.yaml, e.g. infra, core, observability")
+
+	cmd.RegisterFlagCompletionFunc("all-except", completeServices)
+	cmd.RegisterFlagCompletionFunc("group", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		c, err := loadConfig()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return c.GroupNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// any reports whether a selection flag was given.
+func (s *serviceSelection) any() bool {
+	return len(s.allExcept) > 0 || len(s.groups) > 0
+}
+
+// resolve turns service arguments and selection flags into compose service
+// names. A nil result with no error means "all services"; a selection flag
+// that matches no service is an error, so that it never means all.
+func (s *serviceSelection) resolve(c *config.Config, project *compose.Project, args []string) ([]string, error) {
+	if len(s.allExcept) > 0 && (len(args) > 0 || len(s.groups) > 0) {
+		return nil, fmt.Errorf("--all-except can't be combined with service names or --group")
+	}
+
+	if len(s.allExcept) > 0 {
+		excluded, err := resolveComposeNames(c, s.allExcept)
+		if err != nil {
+			return nil, err
+		}
+		var out []string
+		for _, name := range project.Names() {
+			if len(project.Service(name).Profiles) == 0 && !slices.Contains(excluded, name) {
+				out = append(out, name)
+			}
+		}
+		if len(out) == 0 {
+			return nil, withExitCode(exitInvalidArgs, errors.New("--all-except leaves no services to select"))
+		}
+		return out, nil
+	}
+
+	names, err := resolveComposeNames(c, args)
+	if err != nil {
+		return nil, err
+	}
+	members, err := c.GroupMembers(s.groups...)
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range members {
+		names = append(names, svc.ComposeName())
+	}
+
+	var out []string
+	for _, name := range names {
+		if !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	if len(out) == 0 && len(s.groups) > 0 {
+		return nil, withExitCode(exitInvalidArgs, fmt.Errorf("--group %s has no services", strings.Join(s.groups, ",")))
+	}
+	return out, nil
+}
+
+// printResults prints a per-service summary of an orchestrator run.
+func printResults(w io.Writer, results []orchestrator.Result) {
+	if len(results) == 0 {
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nSERVICE\tACTION\tRESULT\tDURATION\tERROR")
+	for _, r := range results {
+		errMsg := ""
+		if r.Err != nil {
+			errMsg = firstLine(r.Err.Error())
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Service, r.Op, r.Status, r.Duration.Round(100*time.Millisecond), errMsg)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
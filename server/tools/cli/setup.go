@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -17,17 +16,17 @@ This command will:
   2. Clone all service repositories
   3. Install Go development tools
   4. Initialize workspace configuration`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		fmt.Println("🚀 Setting up development environment...")
 
 		if err := runCommand("make", "setup"); err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Setup failed: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("setup failed: %w", err)
 		}
 
 		fmt.Println("✅ Setup complete!")
 		fmt.Println("\nNext steps:")
 		fmt.Println("  saas start    # Start all services")
 		fmt.Println("  saas status   # Check service status")
+		return nil
 	},
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/runner"
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell <service>",
+	Short: "Open a shell in a service container",
+	Long: `Open an interactive shell inside a running service container.
+
+Uses sh and falls back to bash when the container has no sh. The
+command exits with the status of the last command run in the shell.
+
+Examples:
+  saas shell auth      # Shell into auth-service
+  saas shell mongo     # Shell into mongodb`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		svc, err := resolveService(c, args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("🐚 Accessing %s shell...\n", svc.Name)
+
+		compose := []string{"compose"}
+		for _, f := range c.ComposePaths(false) {
+			compose = append(compose, "-f", f)
+		}
+
+		// Ask for sh without a terminal first: the exit status of the
+		// session itself can't tell a missing sh from a failed command.
+		shell := "sh"
+		probe := append(slices.Clone(compose), "exec", "-T", svc.ComposeName(), "sh", "-c", "true")
+		var exit *exec.ExitError
+		if err := runner.Quiet(context.Background(), cmdRunner, runner.Command{Name: "docker", Args: probe, Dir: c.Dir}); err != nil {
+			if !errors.As(err, &exit) {
+				return fmt.Errorf("failed to open shell in %s: %w", svc.Name, err)
+			}
+			shell = "bash"
+		}
+
+		err = runCommand("docker", append(compose, "exec", svc.ComposeName(), shell)...)
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &exit):
+			// The session ran; pass on the status of its last command.
+			return withExitCode(exit.ExitCode(), nil)
+		}
+		return fmt.Errorf("failed to open shell in %s: %w", svc.Name, err)
+	},
+}
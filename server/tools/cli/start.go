@@ -1,56 +1,143 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
 )
 
 var (
-	devMode bool
+	devMode          bool
+	startWait        bool
+	startWaitTimeout time.Duration
+	startSelection   serviceSelection
 )
 
 var startCmd = &cobra.Command{
-	Use:   "start [service]",
+	Use:   "start [service...]",
 	Short: "Start services",
-	Long: `Start all services or a specific service.
+	Long: `Start all services or a selection of services.
+
+Services are started in dependency order (mongodb, redis and rabbitmq
+before the services that need them). Services that don't depend on each
+other start concurrently, and a result table is printed at the end. If a
+service fails to start, the services that depend on it are skipped.
 
 Examples:
-  saas start              # Start all services
-  saas start auth         # Start only auth service
-  saas start --dev        # Start with hot-reload`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) == 0 {
-			// Start all services
-			fmt.Println("🚀 Starting all services...")
-
-			target := "start"
-			if devMode {
-				target = "start-dev"
-				fmt.Println("   (development mode with hot-reload)")
-			}
+  saas start                      # Start all services
+  saas start auth                 # Start auth service and its dependencies
+  saas start auth user tenant     # Start several services
+  saas start --group infra        # Start a group from saas.yaml
+  saas start --all-except jaeger  # Start everything but jaeger
+  saas start --dev                # Start with hot-reload
+  saas start --wait               # Start and wait until services are ready
+  saas start --via-make           # Use the Makefile targets instead`,
+	ValidArgsFunction: completeServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
 
-			if err := runCommand("make", target); err != nil {
-				fmt.Fprintf(os.Stderr, "❌ Failed to start services: %v\n", err)
-				os.Exit(1)
-			}
+		var services []string
+		if viaMake {
+			err = startViaMake(c, args)
 		} else {
-			// Start specific service
-			service := args[0]
-			fmt.Printf("🚀 Starting %s...\n", service)
+			services, err = startNative(c, args)
+		}
+		if err != nil {
+			return err
+		}
 
-			if err := runCommand("make", "restart-service", "SERVICE="+service+"-service"); err != nil {
-				fmt.Fprintf(os.Stderr, "❌ Failed to start %s: %v\n", service, err)
-				os.Exit(1)
+		if startWait {
+			if err := waitForServices(c, waitableServices(c, services), startWaitTimeout); err != nil {
+				return err
 			}
 		}
 
 		fmt.Println("✅ Services started!")
 		fmt.Println("\nCheck status with: saas status")
+		return nil
 	},
 }
 
+// startNative starts services with the orchestrator and returns the compose
+// names of the selected services, or nil when all services were started.
+func startNative(c *config.Config, args []string) ([]string, error) {
+	project, err := loadProject(c, devMode)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := startSelection.resolve(c, project, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(services) == 0 {
+		fmt.Println("🚀 Starting all services...")
+	} else {
+		fmt.Printf("🚀 Starting %d service(s) and their dependencies...\n", len(services))
+	}
+	if devMode {
+		fmt.Println("   (development mode with hot-reload)")
+	}
+
+	orch := newOrchestrator(project)
+	results, err := orch.Start(context.Background(), services...)
+	printResults(os.Stdout, results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start services: %w", err)
+	}
+	return services, nil
+}
+
+func startViaMake(c *config.Config, args []string) error {
+	if startSelection.any() {
+		return withExitCode(exitInvalidArgs, errors.New("--all-except and --group are not supported with --via-make"))
+	}
+
+	if len(args) == 0 {
+		// Start all services
+		fmt.Println("🚀 Starting all services...")
+
+		target := "start"
+		if devMode {
+			target = "start-dev"
+			fmt.Println("   (development mode with hot-reload)")
+		}
+
+		if err := runCommand("make", target); err != nil {
+			return fmt.Errorf("failed to start services: %w", err)
+		}
+		return nil
+	}
+
+	// Start specific services
+	for _, arg := range args {
+		svc, err := resolveService(c, arg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🚀 Starting %s...\n", svc.Name)
+
+		if err := runCommand("make", "restart-service", "SERVICE="+svc.ComposeName()); err != nil {
+			return fmt.Errorf("failed to start %s: %w", svc.Name, err)
+		}
+	}
+	return nil
+}
+
 func init() {
 	startCmd.Flags().BoolVar(&devMode, "dev", false, "Start in development mode with hot-reload")
+	startCmd.Flags().BoolVar(&startWait, "wait", false, "Wait until services are ready after starting them")
+	startCmd.Flags().DurationVar(&startWaitTimeout, "wait-timeout", 5*time.Minute, "Maximum time to wait with --wait")
+	startCmd.Flags().BoolVar(&viaMake, "via-make", false, "Delegate to the Makefile targets instead of docker compose")
+	startSelection.register(startCmd)
 }
@@ -1,10 +1,31 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/health"
+)
+
+// Exit codes of `saas status`, matching scripts/utilities/check-health.sh.
+const (
+	exitHealthy     = 0
+	exitUnhealthy   = 1
+	exitInvalidArgs = 2
+)
+
+var (
+	statusOutput  string
+	statusTimeout time.Duration
+	statusSlow    time.Duration
 )
 
 var statusCmd = &cobra.Command{
@@ -13,20 +34,101 @@ var statusCmd = &cobra.Command{
 	Long: `Check the health and status of all services.
 
 This command will:
-  - Check if services are running
-  - Verify health endpoints
-  - Show service URLs`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("🏥 Checking service status...")
-		fmt.Println()
-
-		if err := runCommand("make", "status"); err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Status check failed: %v\n", err)
-			os.Exit(1)
+  - Probe the health endpoint of every service in saas.yaml
+  - Ping MongoDB, Redis and RabbitMQ
+  - Measure response latency
+  - Classify each target as healthy, degraded (slow or non-healthy status)
+    or down (unreachable or HTTP error)
+
+Exit codes:
+  0  All services healthy or degraded
+  1  One or more services down
+  2  Invalid arguments
+
+Examples:
+  saas status                # Table output
+  saas status -o json        # Machine-readable output for CI
+  saas status --slow 500ms   # Flag responses slower than 500ms as degraded`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if statusOutput != "table" && statusOutput != "json" && statusOutput != "yaml" {
+			return withExitCode(exitInvalidArgs, fmt.Errorf("unknown output format: %s (use table, json or yaml)", statusOutput))
 		}
 
-		fmt.Println()
-		fmt.Println("💡 View service URLs: saas info")
-		fmt.Println("💡 View logs: saas logs [service]")
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		if statusOutput == "table" {
+			fmt.Println("🏥 Checking service status...")
+			fmt.Println()
+		}
+
+		targets := healthTargets(c)
+		done := step(fmt.Sprintf("check %d target(s)", len(targets)))
+		checker := health.NewChecker(statusTimeout, statusSlow)
+		results := checker.CheckAll(context.Background(), targets)
+		done()
+
+		if err := writeStatus(os.Stdout, statusOutput, results); err != nil {
+			return withExitCode(exitInvalidArgs, fmt.Errorf("failed to write status: %w", err))
+		}
+
+		counts := health.Summary(results)
+		if statusOutput == "table" {
+			fmt.Println()
+			fmt.Printf("%d healthy, %d degraded, %d down\n",
+				counts[health.StateHealthy], counts[health.StateDegraded], counts[health.StateDown])
+			fmt.Println()
+			fmt.Println("💡 View service URLs: saas info")
+			fmt.Println("💡 View logs: saas logs [service]")
+		}
+
+		if counts[health.StateDown] > 0 {
+			return withExitCode(exitUnhealthy, nil)
+		}
+		return nil
 	},
 }
+
+// writeStatus renders health results in the requested format.
+func writeStatus(w io.Writer, format string, results []health.Result) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tSTATE\tLATENCY\tADDRESS\tDETAILS")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s %s\t%dms\t%s\t%s\n",
+			r.Name, stateIcon(r.State), r.State, r.LatencyMS, r.Address, r.Error)
+	}
+	return tw.Flush()
+}
+
+func stateIcon(state health.State) string {
+	switch state {
+	case health.StateHealthy:
+		return "✅"
+	case health.StateDegraded:
+		return "⚠️ "
+	default:
+		return "❌"
+	}
+}
+
+func init() {
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "table", "Output format: table, json, yaml")
+	statusCmd.Flags().DurationVar(&statusTimeout, "timeout", 5*time.Second, "Timeout for each check")
+	statusCmd.Flags().DurationVar(&statusSlow, "slow", time.Second, "Latency above which a service is reported as degraded")
+}
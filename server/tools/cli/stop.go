@@ -1,40 +1,119 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+)
+
+var (
+	stopSelection serviceSelection
 )
 
 var stopCmd = &cobra.Command{
-	Use:   "stop [service]",
+	Use:   "stop [service...]",
 	Short: "Stop services",
-	Long: `Stop all services or a specific service.
+	Long: `Stop all services or a selection of services.
+
+Services are stopped in reverse dependency order, so api-gateway stops
+before the services it calls and infrastructure stops last. Services that
+don't depend on each other stop concurrently.
 
 Examples:
-  saas stop           # Stop all services
-  saas stop auth      # Stop only auth service`,
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) == 0 {
-			// Stop all services
-			fmt.Println("⏸️  Stopping all services...")
-
-			if err := runCommand("make", "stop"); err != nil {
-				fmt.Fprintf(os.Stderr, "❌ Failed to stop services: %v\n", err)
-				os.Exit(1)
-			}
+  saas stop                        # Stop all services
+  saas stop auth                   # Stop only auth service
+  saas stop auth user              # Stop several services
+  saas stop --group observability  # Stop a group from saas.yaml
+  saas stop --all-except mongodb   # Stop everything but mongodb
+  saas stop --via-make             # Use the Makefile targets instead`,
+	ValidArgsFunction: completeServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		if viaMake {
+			err = stopViaMake(c, args)
 		} else {
-			// Stop specific service
-			service := args[0]
-			fmt.Printf("⏸️  Stopping %s...\n", service)
-
-			if err := runCommand("docker-compose", "-f", "docker/docker-compose.yml", "stop", service+"-service"); err != nil {
-				fmt.Fprintf(os.Stderr, "❌ Failed to stop %s: %v\n", service, err)
-				os.Exit(1)
-			}
+			err = stopNative(c, args)
+		}
+		if err != nil {
+			return err
 		}
 
 		fmt.Println("✅ Services stopped!")
+		return nil
 	},
 }
+
+func stopNative(c *config.Config, args []string) error {
+	project, err := loadProject(c, false)
+	if err != nil {
+		return err
+	}
+
+	services, err := stopSelection.resolve(c, project, args)
+	if err != nil {
+		return err
+	}
+
+	stopAll := len(services) == 0 && !stopSelection.any()
+	if stopAll {
+		fmt.Println("⏸️  Stopping all services...")
+	} else {
+		fmt.Printf("⏸️  Stopping %d service(s)...\n", len(services))
+	}
+
+	ctx := context.Background()
+	orch := newOrchestrator(project)
+	results, err := orch.Stop(ctx, services...)
+	printResults(os.Stdout, results)
+	if err == nil && stopAll {
+		err = orch.Down(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stop services: %w", err)
+	}
+	return nil
+}
+
+func stopViaMake(c *config.Config, args []string) error {
+	if stopSelection.any() {
+		return withExitCode(exitInvalidArgs, errors.New("--all-except and --group are not supported with --via-make"))
+	}
+
+	if len(args) == 0 {
+		// Stop all services
+		fmt.Println("⏸️  Stopping all services...")
+
+		if err := runCommand("make", "stop"); err != nil {
+			return fmt.Errorf("failed to stop services: %w", err)
+		}
+		return nil
+	}
+
+	// Stop specific services
+	for _, arg := range args {
+		svc, err := resolveService(c, arg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("⏸️  Stopping %s...\n", svc.Name)
+
+		if err := runCommand("docker-compose", "-f", c.ComposePaths(false)[0], "stop", svc.ComposeName()); err != nil {
+			return fmt.Errorf("failed to stop %s: %w", svc.Name, err)
+		}
+	}
+	return nil
+}
+
+func init() {
+	stopCmd.Flags().BoolVar(&viaMake, "via-make", false, "Delegate to the Makefile targets instead of docker compose")
+	stopSelection.register(stopCmd)
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -22,7 +21,7 @@ Examples:
   saas test --type=integration  # Run integration tests
   saas test --type=e2e    # Run end-to-end tests
   saas test --type=load   # Run load tests`,
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		target := "test"
 
 		switch testType {
@@ -43,11 +42,11 @@ Examples:
 		}
 
 		if err := runCommand("make", target); err != nil {
-			fmt.Fprintf(os.Stderr, "❌ Tests failed: %v\n", err)
-			os.Exit(1)
+			return fmt.Errorf("tests failed: %w", err)
 		}
 
 		fmt.Println("✅ Tests complete!")
+		return nil
 	},
 }
 
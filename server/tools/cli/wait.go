@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/vhvplatform/go-framework/tools/cli/internal/config"
+	"github.com/vhvplatform/go-framework/tools/cli/internal/health"
+)
+
+var (
+	waitTimeout     time.Duration
+	waitInterval    time.Duration
+	waitMaxInterval time.Duration
+)
+
+// defaultWaitGroups are the groups `saas wait` checks when no services are
+// given: infrastructure plus every microservice.
+var defaultWaitGroups = []string{"infra", "core"}
+
+// defaultWaitServices returns the services in defaultWaitGroups, or every
+// service with a port when saas.yaml doesn't define those groups.
+func defaultWaitServices(c *config.Config) []string {
+	members, err := c.GroupMembers(defaultWaitGroups...)
+	if err != nil || len(members) == 0 {
+		members = nil
+		for _, svc := range c.Services {
+			if svc.Port != 0 {
+				members = append(members, svc)
+			}
+		}
+	}
+
+	names := make([]string, len(members))
+	for i, svc := range members {
+		names[i] = svc.Name
+	}
+	return names
+}
+
+var waitCmd = &cobra.Command{
+	Use:   "wait [service...]",
+	Short: "Wait for services to become ready",
+	Long: `Wait until services are ready to accept traffic.
+
+Microservices are ready once their /health endpoint reports "healthy";
+MongoDB and RabbitMQ once their port accepts connections and Redis once it
+answers PING. Checks back off exponentially from --interval up to
+--max-interval until --timeout expires.
+
+Examples:
+  saas wait                       # Wait for infrastructure and all services
+  saas wait mongodb auth-service  # Wait for specific services
+  saas wait --timeout 10m         # Allow slow machines more time`,
+	ValidArgsFunction: completeServices,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		names := args
+		if len(names) == 0 {
+			names = defaultWaitServices(c)
+		}
+		return waitForServices(c, names, waitTimeout)
+	},
+}
+
+// waitForServices waits for the named services and prints progress and a
+// summary. It returns an error with exit code 1 when any service did not
+// become ready in time.
+func waitForServices(c *config.Config, names []string, timeout time.Duration) error {
+	// The backoff doubles the interval, so zero would probe without pause.
+	if waitInterval <= 0 {
+		return withExitCode(exitInvalidArgs, fmt.Errorf("--interval must be greater than zero, got %s", waitInterval))
+	}
+	targets, err := lookupTargets(c, names)
+	if err != nil {
+		return withExitCode(exitInvalidArgs, err)
+	}
+
+	if dryRun {
+		fmt.Printf("⏳ Would wait for %d service(s) (timeout %s)\n", len(targets), timeout)
+		return nil
+	}
+
+	done := step(fmt.Sprintf("wait for %d service(s)", len(targets)))
+	defer done()
+
+	fmt.Printf("⏳ Waiting for %d service(s) (timeout %s)...\n", len(targets), timeout)
+
+	checker := health.NewChecker(statusTimeout, 0)
+	results := checker.Wait(context.Background(), targets, health.WaitOptions{
+		Timeout:     timeout,
+		Interval:    waitInterval,
+		MaxInterval: waitMaxInterval,
+		OnReady: func(r health.WaitResult) {
+			fmt.Printf("   %-24s ✅ (%s, %d attempt(s))\n", r.Target.Name, r.Elapsed.Round(100*time.Millisecond), r.Attempts)
+		},
+	})
+
+	var failed []health.WaitResult
+	for _, r := range results {
+		if !r.Ready {
+			failed = append(failed, r)
+		}
+	}
+
+	if len(failed) == 0 {
+		fmt.Println("✅ All services are ready!")
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "\n❌ %d service(s) never became ready:\n", len(failed))
+	for _, r := range failed {
+		reason := r.Last.Error
+		if reason == "" {
+			reason = "status " + r.Last.Status
+		}
+		fmt.Fprintf(os.Stderr, "   - %s (%s): %s\n", r.Target.Name, r.Target.Address, reason)
+	}
+	fmt.Fprintln(os.Stderr, "\n💡 View logs: saas logs [service]")
+	return withExitCode(exitUnhealthy, nil)
+}
+
+// lookupTargets maps service arguments to their health targets.
+func lookupTargets(c *config.Config, names []string) ([]health.Target, error) {
+	targets := make([]health.Target, 0, len(names))
+	for _, name := range names {
+		svc, err := resolveService(c, name)
+		if err != nil {
+			return nil, err
+		}
+		if svc.Port == 0 {
+			return nil, fmt.Errorf("service %q has no port configured in %s", svc.Name, config.FileName)
+		}
+		targets = append(targets, healthTarget(c, *svc))
+	}
+	return targets, nil
+}
+
+func init() {
+	waitCmd.Flags().DurationVar(&waitTimeout, "timeout", 5*time.Minute, "Maximum time to wait for all services")
+	waitCmd.Flags().DurationVar(&waitInterval, "interval", 2*time.Second, "Initial delay between checks")
+	waitCmd.Flags().DurationVar(&waitMaxInterval, "max-interval", 15*time.Second, "Maximum delay between checks")
+}